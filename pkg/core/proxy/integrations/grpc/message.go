@@ -0,0 +1,97 @@
+//go:build linux
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+//ref: https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md#requests
+
+// Message is a single length-prefixed message of a gRPC stream.
+type Message struct {
+	// Compressed is the compressed flag of the message prefix.
+	Compressed bool
+	// Data is the message payload, decompressed with the codec of the stream when Compressed is set.
+	Data []byte
+}
+
+// MessageDecoder splits the payloads of the DATA frames of a gRPC stream into the messages
+// they carry. Each message has a 5 byte prefix, a compressed flag followed by the big-endian
+// length of the payload, and may span several DATA frames while a DATA frame may hold
+// several messages, so payloads are buffered until a message is complete.
+type MessageDecoder struct {
+	encoding string
+	buf      []byte
+}
+
+// NewMessageDecoder returns a decoder for the messages of a stream whose grpc-encoding
+// header is encoding, it is used to decompress the messages with the compressed flag set.
+// Only gzip is supported, an empty encoding or "identity" means that no compression was
+// negotiated.
+func NewMessageDecoder(encoding string) *MessageDecoder {
+	return &MessageDecoder{encoding: encoding}
+}
+
+// Write appends the payload of a DATA frame to the stream.
+func (d *MessageDecoder) Write(payload []byte) {
+	d.buf = append(d.buf, payload...)
+}
+
+// Next returns the next complete message of the stream, ok is false when more DATA frames
+// are needed to complete it.
+func (d *MessageDecoder) Next() (msg *Message, ok bool, err error) {
+	if len(d.buf) < 5 {
+		return nil, false, nil
+	}
+	flag := d.buf[0]
+	if flag > 1 {
+		return nil, false, fmt.Errorf("invalid compressed flag %d in grpc message prefix", flag)
+	}
+	length := binary.BigEndian.Uint32(d.buf[1:5])
+	if uint64(len(d.buf)-5) < uint64(length) {
+		return nil, false, nil
+	}
+
+	data := make([]byte, length)
+	copy(data, d.buf[5:5+length])
+	d.buf = d.buf[5+length:]
+
+	msg = &Message{Compressed: flag == 1, Data: data}
+	if msg.Compressed {
+		msg.Data, err = d.decompress(data)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return msg, true, nil
+}
+
+// Buffered returns the number of bytes of the incomplete message at the end of the stream.
+func (d *MessageDecoder) Buffered() int {
+	return len(d.buf)
+}
+
+func (d *MessageDecoder) decompress(data []byte) ([]byte, error) {
+	switch d.encoding {
+	case "gzip":
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress grpc message: %w", err)
+		}
+		defer r.Close()
+		decompressed, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress grpc message: %w", err)
+		}
+		return decompressed, nil
+	case "", "identity":
+		return nil, fmt.Errorf("compressed grpc message on a stream without grpc-encoding")
+	default:
+		return nil, fmt.Errorf("unsupported grpc-encoding %q", d.encoding)
+	}
+}
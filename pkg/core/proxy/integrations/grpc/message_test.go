@@ -0,0 +1,86 @@
+//go:build linux
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+// prefixed frames data as a gRPC message with the compressed flag and length prefix.
+func prefixed(compressed bool, data []byte) []byte {
+	n := len(data)
+	flag := byte(0)
+	if compressed {
+		flag = 1
+	}
+	return append([]byte{flag, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}, data...)
+}
+
+func TestMessageDecoderSplitsTwoMessageStream(t *testing.T) {
+	// two HelloRequest messages, name "alice" and name "bob"
+	first := []byte{0x0a, 0x05, 'a', 'l', 'i', 'c', 'e'}
+	second := []byte{0x0a, 0x03, 'b', 'o', 'b'}
+	stream := append(prefixed(false, first), prefixed(false, second)...)
+
+	d := NewMessageDecoder("")
+	// the first DATA frame ends in the middle of the prefix of the second message
+	split := len(first) + 7
+	d.Write(stream[:split])
+
+	msg, ok, err := d.Next()
+	if err != nil || !ok {
+		t.Fatalf("first message: ok %v, err %v", ok, err)
+	}
+	if msg.Compressed || !bytes.Equal(msg.Data, first) {
+		t.Errorf("first message %+v, want % x", msg, first)
+	}
+	if _, ok, err := d.Next(); ok || err != nil {
+		t.Fatalf("decoded the incomplete second message: ok %v, err %v", ok, err)
+	}
+	if d.Buffered() != 2 {
+		t.Errorf("%d bytes buffered, want 2", d.Buffered())
+	}
+
+	d.Write(stream[split:])
+	msg, ok, err = d.Next()
+	if err != nil || !ok {
+		t.Fatalf("second message: ok %v, err %v", ok, err)
+	}
+	if !bytes.Equal(msg.Data, second) {
+		t.Errorf("second message % x, want % x", msg.Data, second)
+	}
+	if d.Buffered() != 0 {
+		t.Errorf("%d bytes left buffered", d.Buffered())
+	}
+}
+
+func TestMessageDecoderDecompressesWithStreamEncoding(t *testing.T) {
+	data := []byte{0x0a, 0x05, 'a', 'l', 'i', 'c', 'e'}
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewMessageDecoder("gzip")
+	d.Write(prefixed(true, compressed.Bytes()))
+	msg, ok, err := d.Next()
+	if err != nil || !ok {
+		t.Fatalf("ok %v, err %v", ok, err)
+	}
+	if !msg.Compressed || !bytes.Equal(msg.Data, data) {
+		t.Errorf("decoded %+v, want % x", msg, data)
+	}
+
+	// a compressed message on a stream that didn't negotiate a codec
+	d = NewMessageDecoder("")
+	d.Write(prefixed(true, compressed.Bytes()))
+	if _, _, err := d.Next(); err == nil {
+		t.Error("expected an error without grpc-encoding")
+	}
+}
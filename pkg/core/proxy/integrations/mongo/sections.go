@@ -0,0 +1,88 @@
+//go:build linux
+
+package mongo
+
+import (
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+	"go.uber.org/zap"
+)
+
+// DecodeOpMsgSections decodes the sections of the OP_MSG body (the flags, the sections and
+// the optional checksum following the message header) into their structured form, so that
+// they can be compared document by document.
+func DecodeOpMsgSections(body []byte, logger *zap.Logger) ([]models.MongoOpMsgSection, error) {
+	msg, err := decodeMsg(0, body, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]models.MongoOpMsgSection, 0, len(msg.sections))
+	for i, section := range msg.sections {
+		var (
+			structured models.MongoOpMsgSection
+			docs       []bsoncore.Document
+		)
+		switch s := section.(type) {
+		case *opMsgSectionSingle:
+			structured.Kind = int(wiremessage.SingleDocument)
+			docs = []bsoncore.Document{s.msg}
+		case *opMsgSectionSequence:
+			structured.Kind = int(wiremessage.DocumentSequence)
+			structured.Identifier = s.identifier
+			docs = s.msgs
+		}
+		structured.Documents = make([]string, 0, len(docs))
+		for j, doc := range docs {
+			jsonBytes, err := bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				return nil, fmt.Errorf("malformed bson document %d of section %d: %v", j, i, err)
+			}
+			structured.Documents = append(structured.Documents, string(jsonBytes))
+		}
+		sections = append(sections, structured)
+	}
+	return sections, nil
+}
+
+// EncodeOpMsgSections encodes the sections as they appear in an OP_MSG body, it is the
+// inverse of DecodeOpMsgSections. The documents are written with their fields in the
+// recorded order.
+func EncodeOpMsgSections(sections []models.MongoOpMsgSection) ([]byte, error) {
+	var buffer []byte
+	for i, section := range sections {
+		docs := make([]bsoncore.Document, 0, len(section.Documents))
+		for j, doc := range section.Documents {
+			var d bson.D
+			if err := bson.UnmarshalExtJSON([]byte(doc), true, &d); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal document %d of section %d: %v", j, i, err)
+			}
+			raw, err := bson.Marshal(d)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal document %d of section %d: %v", j, i, err)
+			}
+			docs = append(docs, raw)
+		}
+
+		switch wiremessage.SectionType(section.Kind) {
+		case wiremessage.SingleDocument:
+			if len(docs) != 1 {
+				return nil, fmt.Errorf("section %d of kind 0 has %d documents, expected 1", i, len(docs))
+			}
+			buffer = (&opMsgSectionSingle{msg: docs[0]}).append(buffer)
+		case wiremessage.DocumentSequence:
+			if section.Identifier == "" {
+				return nil, errors.New("section of kind 1 without an identifier")
+			}
+			buffer = (&opMsgSectionSequence{identifier: section.Identifier, msgs: docs}).append(buffer)
+		default:
+			return nil, fmt.Errorf("unknown kind %d of section %d", section.Kind, i)
+		}
+	}
+	return buffer, nil
+}
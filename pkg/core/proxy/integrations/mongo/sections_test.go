@@ -0,0 +1,92 @@
+//go:build linux
+
+package mongo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+	"go.uber.org/zap"
+)
+
+func marshal(t *testing.T, d bson.D) []byte {
+	t.Helper()
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// insertBody is the body of the OP_MSG the driver sends for
+// db.users.insertMany([{name: "alice", age: 31}, {name: "bob", score: 4.5}]): the command in
+// a kind 0 section and the documents in a kind 1 section. The fields aren't in alphabetical
+// order and have different numeric types, which the re-encoded documents must keep.
+func insertBody(t *testing.T) []byte {
+	body := wiremessage.AppendMsgFlags(nil, 0)
+	body = wiremessage.AppendMsgSectionType(body, wiremessage.SingleDocument)
+	body = append(body, marshal(t, bson.D{{Key: "insert", Value: "users"}, {Key: "ordered", Value: true}, {Key: "$db", Value: "shop"}})...)
+
+	sequence := append([]byte("documents"), 0x00)
+	sequence = append(sequence, marshal(t, bson.D{{Key: "name", Value: "alice"}, {Key: "age", Value: int32(31)}})...)
+	sequence = append(sequence, marshal(t, bson.D{{Key: "name", Value: "bob"}, {Key: "score", Value: 4.5}, {Key: "visits", Value: int64(1 << 40)}})...)
+	body = wiremessage.AppendMsgSectionType(body, wiremessage.DocumentSequence)
+	body = binary.LittleEndian.AppendUint32(body, uint32(4+len(sequence)))
+	return append(body, sequence...)
+}
+
+func TestOpMsgSectionsRoundTrip(t *testing.T) {
+	body := insertBody(t)
+
+	sections, err := DecodeOpMsgSections(body, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("decoded %d sections, want 2", len(sections))
+	}
+	if sections[0].Kind != int(wiremessage.SingleDocument) || len(sections[0].Documents) != 1 {
+		t.Errorf("command section %+v, want a single document", sections[0])
+	}
+	if sections[1].Kind != int(wiremessage.DocumentSequence) || sections[1].Identifier != "documents" || len(sections[1].Documents) != 2 {
+		t.Errorf("document sequence %+v, want 2 documents identified as documents", sections[1])
+	}
+
+	encoded, err := EncodeOpMsgSections(sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the flags precede the sections in the body
+	if !bytes.Equal(encoded, body[4:]) {
+		t.Errorf("encoded % x, want % x", encoded, body[4:])
+	}
+}
+
+func TestEncodeOpMsgSectionsRejectsInvalidSections(t *testing.T) {
+	sections, err := DecodeOpMsgSections(insertBody(t), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	command, documents := sections[0], sections[1]
+
+	withoutIdentifier := documents
+	withoutIdentifier.Identifier = ""
+	twoBodies := documents
+	twoBodies.Kind = int(wiremessage.SingleDocument)
+	unknownKind := command
+	unknownKind.Kind = 2
+
+	for name, section := range map[string]models.MongoOpMsgSection{
+		"sequence without identifier": withoutIdentifier,
+		"kind 0 with 2 documents":     twoBodies,
+		"unknown kind":                unknownKind,
+	} {
+		if _, err := EncodeOpMsgSections([]models.MongoOpMsgSection{command, section}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -392,13 +392,14 @@ func handleAuth(ctx context.Context, logger *zap.Logger, authPkt *mysql.PacketBu
 	}
 
 	switch mysql.AuthPluginName(decodeCtx.PluginName) {
-	case mysql.Native:
+	case mysql.Native, mysql.Ed25519:
+		// the server answers the auth data of the client with an OK/ERR packet right away
 		res.resp = append(res.resp, mysql.Response{
 			PacketBundle: *authPkt,
 		})
 
 		res.responseOperation = authPkt.Header.Type
-		logger.Debug("native password authentication is handled successfully")
+		logger.Debug("password authentication is handled successfully", zap.String("plugin", decodeCtx.PluginName))
 	case mysql.CachingSha2:
 		result, err := handleCachingSha2Password(ctx, logger, authPkt, clientConn, destConn, decodeCtx)
 		if err != nil {
@@ -431,6 +432,10 @@ func handleCachingSha2Password(ctx context.Context, logger *zap.Logger, authPkt
 		return res, fmt.Errorf("invalid packet type for caching sha2 password mechanism, expected: AuthMoreDataPacket, found: %T", authPkt.Message)
 	}
 
+	if len(authMorePkt.Data) < 1 {
+		return res, fmt.Errorf("AuthMoreData packet carries no caching sha2 password mechanism")
+	}
+
 	// Getting the string value of the caching_sha2_password mechanism
 	authMechanism, err = wire.GetCachingSha2PasswordMechanism(authMorePkt.Data[0])
 	if err != nil {
@@ -534,6 +539,13 @@ func handleFullAuth(ctx context.Context, logger *zap.Logger, clientConn, destCon
 		return res, err
 	}
 
+	// The client only requests the public key when it doesn't already have it and the connection
+	// isn't secured, otherwise it directly sends the encrypted (or, over TLS, cleartext) password
+	if !mysqlUtils.IsRequestPublicKeyPacket(publicKeyRequest) {
+		logger.Debug("client sent the password without requesting the public key")
+		return recordFullAuthPassword(ctx, logger, publicKeyRequest, clientConn, destConn, decodeCtx, res)
+	}
+
 	publicKeyReqPkt, err := wire.DecodePayload(ctx, logger, publicKeyRequest, clientConn, decodeCtx)
 	if err != nil {
 		utils.LogError(logger, err, "failed to decode public key request packet")
@@ -589,6 +601,12 @@ func handleFullAuth(ctx context.Context, logger *zap.Logger, clientConn, destCon
 		return res, err
 	}
 
+	return recordFullAuthPassword(ctx, logger, encryptPass, clientConn, destConn, decodeCtx, res)
+}
+
+// recordFullAuthPassword records the password sent by the client during the full authentication,
+// which has already been forwarded to the server, and the final response (OK/ERR) of the server.
+func recordFullAuthPassword(ctx context.Context, logger *zap.Logger, encryptPass []byte, clientConn, destConn net.Conn, decodeCtx *wire.DecodeContext, res handshakeRes) (handshakeRes, error) {
 	encPass, err := mysqlUtils.BytesToMySQLPacket(encryptPass)
 	if err != nil {
 		utils.LogError(logger, err, "failed to parse MySQL packet")
@@ -4,6 +4,7 @@ package recorder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -11,6 +12,7 @@ import (
 
 	mysqlUtils "go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/rowscols"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -124,6 +126,12 @@ func handleQueryResponse(ctx context.Context, logger *zap.Logger, clientConn, de
 		return commandRespPkt, nil
 	}
 
+	// check if the server requested a local file (LOAD DATA LOCAL INFILE)
+	if commandRespPkt.Header.Type == mysql.StatusToString(mysql.LocalInFile) {
+		logger.Debug("Handling local infile request", zap.Any("packet", commandRespPkt.Header.Type))
+		return handleLocalInFile(ctx, logger, clientConn, destConn, commandRespPkt, decodeCtx)
+	}
+
 	// Get the last operation in order to handle current packet if it is not an error or ok packet
 	lastOp, ok := decodeCtx.LastOp.Load(clientConn)
 	if !ok {
@@ -297,6 +305,89 @@ func handlePreparedStmtResponse(ctx context.Context, logger *zap.Logger, clientC
 	return commandRespPkt, nil
 }
 
+//ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_local_infile_request.html
+
+func handleLocalInFile(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, localInFilePkt *mysql.PacketBundle, decodeCtx *wire.DecodeContext) (*mysql.PacketBundle, error) {
+
+	// localInFilePkt is the file request from the server, the client sends the file contents in one or more packets
+	// followed by an empty packet, and the server then responds with an OK or ERR packet
+
+	request, ok := localInFilePkt.Message.(*mysql.LocalInFileRequestPacket)
+	if !ok {
+		return nil, fmt.Errorf("expected LocalInFileRequestPacket, got %T", localInFilePkt.Message)
+	}
+
+	localInFile := &mysql.LocalInFileResponse{
+		Request: request,
+	}
+
+	// Read the file data packets from the client
+fileLoop:
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			data, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read file data packet from the client")
+				}
+				return nil, err
+			}
+
+			// Write the file data packet to the server
+			_, err = destConn.Write(data)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write file data packet to the server")
+				return nil, err
+			}
+
+			localInFile.FileData = append(localInFile.FileData, data)
+
+			// an empty packet marks the end of the file
+			if len(data) == 4 {
+				break fileLoop
+			}
+		}
+	}
+
+	logger.Debug("Read the local infile data from the client", zap.Int("packets", len(localInFile.FileData)))
+
+	// Read the final response from the server
+	finalResp, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
+	if err != nil {
+		if err != io.EOF {
+			utils.LogError(logger, err, "failed to read the final response for local infile")
+		}
+		return nil, err
+	}
+
+	// Write the final response to the client
+	_, err = clientConn.Write(finalResp)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the final response for local infile to the client")
+		return nil, err
+	}
+
+	respType, ok := mysqlUtils.IsGenericResponse(finalResp)
+	if !ok {
+		return nil, fmt.Errorf("expected OK or ERR packet after local infile data, got %v", finalResp)
+	}
+
+	localInFile.FinalResponse = &mysql.GenericResponse{
+		Data: finalResp,
+		Type: respType,
+	}
+
+	localInFilePkt.Message = localInFile
+
+	// reset the last OP
+	decodeCtx.LastOp.Store(clientConn, wire.RESET)
+
+	return localInFilePkt, nil
+}
+
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_text_resultset.html
 
 func handleTextResultSet(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, textResultSetPkt *mysql.PacketBundle, decodeCtx *wire.DecodeContext) (*mysql.PacketBundle, error) {
@@ -308,6 +399,28 @@ func handleTextResultSet(ctx context.Context, logger *zap.Logger, clientConn, de
 		return nil, fmt.Errorf("expected TextResultSet, got %T", textResultSetPkt.Message)
 	}
 
+	if err := readTextResultSet(ctx, logger, clientConn, destConn, textResultSet, decodeCtx); err != nil {
+		return nil, err
+	}
+
+	// further results follow for multi-statement queries and CALL statements
+	if textResultSet.FinalResponse != nil && mysqlUtils.HasMoreResults(textResultSet.FinalResponse.Data) {
+		moreResults, err := readMoreResults(ctx, logger, clientConn, destConn, query.Text, decodeCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the results following the resultset: %w", err)
+		}
+		textResultSet.MoreResults = moreResults
+	}
+
+	// reset the last OP
+	decodeCtx.LastOp.Store(clientConn, wire.RESET)
+
+	return textResultSetPkt, nil
+}
+
+// readTextResultSet reads the columns and rows of a text resultset whose column count has been
+// decoded into textResultSet, up to the EOF packet ending it, forwarding them to the client.
+func readTextResultSet(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, textResultSet *mysql.TextResultSet, decodeCtx *wire.DecodeContext) error {
 	// Read the column count packet
 	colCount := textResultSet.ColumnCount
 
@@ -319,20 +432,20 @@ func handleTextResultSet(ctx context.Context, logger *zap.Logger, clientConn, de
 			if err != io.EOF {
 				utils.LogError(logger, err, "failed to read column definition packet")
 			}
-			return nil, err
+			return err
 		}
 
 		// Write the column definition packet to the client
 		_, err = clientConn.Write(colData)
 		if err != nil {
 			utils.LogError(logger, err, "failed to write column definition packet")
-			return nil, err
+			return err
 		}
 
 		// Decode the column definition packet
 		column, _, err := rowscols.DecodeColumn(ctx, logger, colData)
 		if err != nil {
-			return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
+			return fmt.Errorf("failed to decode column definition packet: %w", err)
 		}
 
 		textResultSet.Columns = append(textResultSet.Columns, column)
@@ -347,19 +460,19 @@ func handleTextResultSet(ctx context.Context, logger *zap.Logger, clientConn, de
 			if err != io.EOF {
 				utils.LogError(logger, err, "failed to read EOF packet for column definition")
 			}
-			return nil, err
+			return err
 		}
 
 		// Write the EOF packet for column definition to the client
 		_, err = clientConn.Write(eofData)
 		if err != nil {
 			utils.LogError(logger, err, "failed to write EOF packet for column definition to the client")
-			return nil, err
+			return err
 		}
 
 		// Validate the EOF packet for column definition
 		if !mysqlUtils.IsEOFPacket(eofData) {
-			return nil, fmt.Errorf("expected EOF packet for column definition, got %v, while handling textResultSet", eofData)
+			return fmt.Errorf("expected EOF packet for column definition, got %v, while handling textResultSet", eofData)
 		}
 
 		textResultSet.EOFAfterColumns = eofData
@@ -370,7 +483,7 @@ rowLoop:
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 
 			// Read the packet
@@ -379,14 +492,14 @@ rowLoop:
 				if err != io.EOF {
 					utils.LogError(logger, err, "failed to read data packet while reading row data")
 				}
-				return nil, err
+				return err
 			}
 
 			// Write the packet to the client
 			_, err = clientConn.Write(data)
 			if err != nil {
 				utils.LogError(logger, err, "failed to write data packet while reading row data")
-				return nil, err
+				return err
 			}
 
 			// // Break if the data packet is a generic response
@@ -413,16 +526,13 @@ rowLoop:
 			// It must be a row data packet
 			row, _, err := rowscols.DecodeTextRow(ctx, logger, data, textResultSet.Columns)
 			if err != nil {
-				return nil, fmt.Errorf("failed to decode row data packet: %w", err)
+				return fmt.Errorf("failed to decode row data packet: %w", err)
 			}
 			textResultSet.Rows = append(textResultSet.Rows, row)
 		}
 	}
 
-	// reset the last OP
-	decodeCtx.LastOp.Store(clientConn, wire.RESET)
-
-	return textResultSetPkt, nil
+	return nil
 }
 
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html
@@ -437,6 +547,30 @@ func handleBinaryResultSet(ctx context.Context, logger *zap.Logger, clientConn,
 		return nil, fmt.Errorf("expected TextResultSet, got %T", binaryResultSetPkt.Message)
 	}
 
+	if err := readBinaryResultSet(ctx, logger, clientConn, destConn, binaryResultSet, decodeCtx); err != nil {
+		return nil, err
+	}
+
+	// further results follow for multi-statement queries and CALL statements
+	if binaryResultSet.FinalResponse != nil && mysqlUtils.HasMoreResults(binaryResultSet.FinalResponse.Data) {
+		moreResults, err := readMoreResults(ctx, logger, clientConn, destConn, query.Binary, decodeCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the results following the resultset: %w", err)
+		}
+		binaryResultSet.MoreResults = moreResults
+	}
+
+	// reset the last OP
+	decodeCtx.LastOp.Store(clientConn, wire.RESET)
+
+	return binaryResultSetPkt, nil
+
+}
+
+// readBinaryResultSet reads the columns and rows of a binary resultset whose column count has
+// been decoded into binaryResultSet, up to the EOF/OK packet ending it, forwarding them to the
+// client.
+func readBinaryResultSet(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, binaryResultSet *mysql.BinaryProtocolResultSet, decodeCtx *wire.DecodeContext) error {
 	// Read the column count packet
 	colCount := binaryResultSet.ColumnCount
 
@@ -449,20 +583,20 @@ func handleBinaryResultSet(ctx context.Context, logger *zap.Logger, clientConn,
 			if err != io.EOF {
 				utils.LogError(logger, err, "failed to read column definition packet")
 			}
-			return nil, err
+			return err
 		}
 
 		// Write the column definition packet to the client
 		_, err = clientConn.Write(colData)
 		if err != nil {
 			utils.LogError(logger, err, "failed to write column definition packet")
-			return nil, err
+			return err
 		}
 
 		// Decode the column definition packet
 		column, _, err := rowscols.DecodeColumn(ctx, logger, colData)
 		if err != nil {
-			return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
+			return fmt.Errorf("failed to decode column definition packet: %w", err)
 		}
 
 		binaryResultSet.Columns = append(binaryResultSet.Columns, column)
@@ -476,19 +610,19 @@ func handleBinaryResultSet(ctx context.Context, logger *zap.Logger, clientConn,
 		if err != io.EOF {
 			utils.LogError(logger, err, "failed to read EOF packet for column definition")
 		}
-		return nil, err
+		return err
 	}
 
 	// Write the EOF packet for column definition to the client
 	_, err = clientConn.Write(eofData)
 	if err != nil {
 		utils.LogError(logger, err, "failed to write EOF packet for column definition to the client")
-		return nil, err
+		return err
 	}
 
 	// Validate the EOF packet for column definition
 	if !mysqlUtils.IsEOFPacket(eofData) {
-		return nil, fmt.Errorf("expected EOF packet for column definition, got %v, while handling BinaryProtocolResultSet", eofData)
+		return fmt.Errorf("expected EOF packet for column definition, got %v, while handling BinaryProtocolResultSet", eofData)
 	}
 
 	binaryResultSet.EOFAfterColumns = eofData
@@ -498,7 +632,7 @@ rowLoop:
 	for {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 
 			// Read the packet
@@ -507,14 +641,14 @@ rowLoop:
 				if err != io.EOF {
 					utils.LogError(logger, err, "failed to read data packet while reading row data")
 				}
-				return nil, err
+				return err
 			}
 
 			// Write the packet to the client
 			_, err = clientConn.Write(data)
 			if err != nil {
 				utils.LogError(logger, err, "failed to write data packet while reading row data")
-				return nil, err
+				return err
 			}
 
 			// Break if the data packet is a generic response
@@ -542,8 +676,17 @@ rowLoop:
 
 			// It must be a row data packet
 			row, _, err := rowscols.DecodeBinaryRow(ctx, logger, data, binaryResultSet.Columns)
+			if errors.Is(err, rowscols.ErrResultSetEnd) {
+				// OK packet with the EOF header (CLIENT_DEPRECATE_EOF) ends the rows
+				logger.Debug("Found OK packet after row data in binary resultset")
+				binaryResultSet.FinalResponse = &mysql.GenericResponse{
+					Data: data,
+					Type: mysql.StatusToString(mysql.OK),
+				}
+				break rowLoop
+			}
 			if err != nil {
-				return nil, fmt.Errorf("failed to decode row data packet: %w", err)
+				return fmt.Errorf("failed to decode row data packet: %w", err)
 			}
 			binaryResultSet.Rows = append(binaryResultSet.Rows, row)
 		}
@@ -551,9 +694,78 @@ rowLoop:
 
 	logger.Debug("Rows: ", zap.Any("Rows", binaryResultSet.Rows))
 
-	// reset the last OP
-	decodeCtx.LastOp.Store(clientConn, wire.RESET)
+	return nil
+}
 
-	return binaryResultSetPkt, nil
+// readMoreResults reads the results following a resultset whose final EOF/OK packet has
+// SERVER_MORE_RESULTS_EXISTS set, forwarding them to the client: further resultsets of the
+// given row type and OK/ERR packets, until a result without the flag (or an ERR packet).
+func readMoreResults(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, rowType query.RowType, decodeCtx *wire.DecodeContext) ([]*mysql.MoreResult, error) {
+	var moreResults []*mysql.MoreResult
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		data, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
+		if err != nil {
+			if err != io.EOF {
+				utils.LogError(logger, err, "failed to read the next result packet")
+			}
+			return nil, err
+		}
+
+		_, err = clientConn.Write(data)
+		if err != nil {
+			utils.LogError(logger, err, "failed to write the next result packet to the client")
+			return nil, err
+		}
+
+		if mysqlUtils.IsERRPacket(data) {
+			moreResults = append(moreResults, &mysql.MoreResult{
+				Response: &mysql.GenericResponse{Data: data, Type: mysql.StatusToString(mysql.ERR)},
+			})
+			return moreResults, nil
+		}
+		if mysqlUtils.IsOKPacket(data) {
+			moreResults = append(moreResults, &mysql.MoreResult{
+				Response: &mysql.GenericResponse{Data: data, Type: mysql.StatusToString(mysql.OK)},
+			})
+			if !mysqlUtils.HasMoreResults(data) {
+				return moreResults, nil
+			}
+			continue
+		}
+
+		// anything else starts another resultset with its column count
+		if len(data) < 5 {
+			return nil, fmt.Errorf("malformed packet %v while reading the next result", data)
+		}
+		resultSet, err := query.DecodeResultSetMetadata(ctx, logger, data[4:], rowType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the column count of the next resultset: %w", err)
+		}
 
+		var finalResponse *mysql.GenericResponse
+		switch rs := resultSet.(type) {
+		case *mysql.TextResultSet:
+			if err := readTextResultSet(ctx, logger, clientConn, destConn, rs, decodeCtx); err != nil {
+				return nil, err
+			}
+			moreResults = append(moreResults, &mysql.MoreResult{TextResultSet: rs})
+			finalResponse = rs.FinalResponse
+		case *mysql.BinaryProtocolResultSet:
+			if err := readBinaryResultSet(ctx, logger, clientConn, destConn, rs, decodeCtx); err != nil {
+				return nil, err
+			}
+			moreResults = append(moreResults, &mysql.MoreResult{BinaryResultSet: rs})
+			finalResponse = rs.FinalResponse
+		default:
+			return nil, fmt.Errorf("unexpected resultset type %T", resultSet)
+		}
+
+		if finalResponse == nil || !mysqlUtils.HasMoreResults(finalResponse.Data) {
+			return moreResults, nil
+		}
+	}
 }
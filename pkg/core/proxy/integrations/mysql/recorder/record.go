@@ -50,6 +50,8 @@ func Record(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Co
 			ServerGreetings: wire.NewGreetings(),
 			// Map for storing prepared statements per connection
 			PreparedStatements: make(map[uint32]*mysql.StmtPrepareOkPacket),
+			// Map for accumulating the long data of prepared statement parameters per connection
+			LongData: make(map[uint32]map[uint16][]byte),
 		}
 		decodeCtx.LastOp.Store(clientConn, wire.RESET) //resetting last command for new loop
 
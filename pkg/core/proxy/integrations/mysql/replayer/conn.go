@@ -460,6 +460,13 @@ func simulateFullAuth(ctx context.Context, logger *zap.Logger, clientConn net.Co
 		return err
 	}
 
+	// The client directly sends the password when it already has the public key or the connection
+	// is secured, in which case no public key exchange was recorded
+	if !mysqlUtils.IsRequestPublicKeyPacket(publicKeyRequestBuf) {
+		logger.Debug("client sent the password without requesting the public key")
+		return simulateFullAuthPassword(ctx, logger, clientConn, publicKeyRequestBuf, req, resp, initialHandshakeMock, mockDb, decodeCtx)
+	}
+
 	// decode the public key request
 	pkt, err := wire.DecodePayload(ctx, logger, publicKeyRequestBuf, clientConn, decodeCtx)
 	if err != nil {
@@ -536,6 +543,12 @@ func simulateFullAuth(ctx context.Context, logger *zap.Logger, clientConn net.Co
 		return err
 	}
 
+	return simulateFullAuthPassword(ctx, logger, clientConn, encryptedPasswordBuf, req[1:], resp[1:], initialHandshakeMock, mockDb, decodeCtx)
+}
+
+// simulateFullAuthPassword checks the password sent by the client during the full authentication
+// against the recorded one (req[0]) and sends the recorded final response (resp[0]).
+func simulateFullAuthPassword(ctx context.Context, logger *zap.Logger, clientConn net.Conn, encryptedPasswordBuf []byte, req []mysql.Request, resp []mysql.Response, initialHandshakeMock *models.Mock, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) error {
 	// Get the packet from the buffer
 	encryptedPassPkt, err := mysqlUtils.BytesToMySQLPacket(encryptedPasswordBuf)
 	if err != nil {
@@ -543,13 +556,13 @@ func simulateFullAuth(ctx context.Context, logger *zap.Logger, clientConn net.Co
 		return err
 	}
 
-	if len(req) < 2 {
+	if len(req) < 1 {
 		utils.LogError(logger, nil, "no mysql mocks found for encrypted password during full auth")
 		return fmt.Errorf("no mysql mocks found for encrypted password during full auth")
 	}
 
 	// Get the encrypted password from the mock
-	encryptedPassMock := req[1].PacketBundle
+	encryptedPassMock := req[0].PacketBundle
 
 	if encryptedPassMock.Header.Type != mysql.EncryptedPassword {
 		utils.LogError(logger, nil, "expected encrypted password mock not found", zap.Any("found", encryptedPassMock.Header.Type))
@@ -563,16 +576,16 @@ func simulateFullAuth(ctx context.Context, logger *zap.Logger, clientConn net.Co
 	}
 
 	//Now send the final response (OK/Err) to the client
-	if len(resp) < 2 {
+	if len(resp) < 1 {
 		utils.LogError(logger, nil, "final response mock not found for full auth")
 		return fmt.Errorf("final response mock not found for full auth")
 	}
 
-	logger.Debug("final response for full auth", zap.Any("response", resp[1].Header.Type))
+	logger.Debug("final response for full auth", zap.Any("response", resp[0].Header.Type))
 
 	// Get the final response (OK/Err) from the mock
 	// Send the final response (OK/Err) to the client
-	buf, err = wire.EncodeToBinary(ctx, logger, &resp[1].PacketBundle, clientConn, decodeCtx)
+	buf, err := wire.EncodeToBinary(ctx, logger, &resp[0].PacketBundle, clientConn, decodeCtx)
 	if err != nil {
 		utils.LogError(logger, err, "failed to encode final response packet for full auth")
 		return err
@@ -589,7 +602,7 @@ func simulateFullAuth(ctx context.Context, logger *zap.Logger, clientConn net.Co
 
 	// FullAuth mechanism only comes for the first time unless COM_CHANGE_USER is called (that is not supported for now).
 	// Afterwards only fast auth success is expected. So, we can delete this.
-	ok = mockDb.DeleteUnFilteredMock(*initialHandshakeMock)
+	ok := mockDb.DeleteUnFilteredMock(*initialHandshakeMock)
 	// TODO: need to check what to do in this case
 	if !ok {
 		utils.LogError(logger, nil, "failed to delete unfiltered mock during full auth")
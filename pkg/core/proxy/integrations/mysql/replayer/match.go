@@ -267,7 +267,13 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 						matchedResp = &mysql.Response{}
 						matchedMock = mock
 					}
-				// case mysql.CommandStatusToString(mysql.COM_STMT_SEND_LONG_DATA):
+				case mysql.CommandStatusToString(mysql.COM_STMT_SEND_LONG_DATA):
+					matchCount := matchSendLongDataPacket(ctx, logger, mockReq.PacketBundle, req.PacketBundle)
+					if matchCount > maxMatchedCount {
+						maxMatchedCount = matchCount
+						matchedResp = &mysql.Response{}
+						matchedMock = mock
+					}
 				case mysql.CommandStatusToString(mysql.COM_QUERY):
 					matchCount := matchQueryPacket(ctx, logger, mockReq.PacketBundle, req.PacketBundle)
 					if matchCount > maxMatchedCount {
@@ -349,6 +355,33 @@ func matchClosePacket(_ context.Context, _ *zap.Logger, expected, actual mysql.P
 	return matchCount
 }
 
+func matchSendLongDataPacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) int {
+	matchCount := 0
+	// Match the type and return zero if the types are not equal
+	if expected.Header.Type != actual.Header.Type {
+		return 0
+	}
+	// Match the header
+	ok := matchHeader(*expected.Header.Header, *actual.Header.Header)
+	if ok {
+		matchCount += 2
+	}
+	expectedMessage, _ := expected.Message.(*mysql.StmtSendLongDataPacket)
+	actualMessage, _ := actual.Message.(*mysql.StmtSendLongDataPacket)
+	// Match the statementID and the parameter the chunk belongs to
+	if expectedMessage.StatementID == actualMessage.StatementID {
+		matchCount++
+	}
+	if expectedMessage.ParameterID == actualMessage.ParameterID {
+		matchCount++
+	}
+	// Match the chunk
+	if bytes.Equal(expectedMessage.Data, actualMessage.Data) {
+		matchCount++
+	}
+	return matchCount
+}
+
 func matchQueryPacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) int {
 	matchCount := 0
 	// Match the type and return zero if the types are not equal
@@ -106,7 +106,51 @@ func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn ne
 				return err
 			}
 
+			// For LOAD DATA LOCAL INFILE, the client streams the requested file before the final response is sent
+			if localInFile, ok := resp.Message.(*mysql.LocalInFileResponse); ok {
+				err = simulateLocalInFile(ctx, logger, clientConn, localInFile)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					utils.LogError(logger, err, "failed to simulate the local infile transfer")
+					return err
+				}
+				decodeCtx.LastOp.Store(clientConn, wire.RESET)
+			}
+
 			logger.Debug("successfully wrote the response to the client", zap.Any("request", req.Header.Type))
 		}
 	}
 }
+
+// simulateLocalInFile consumes the file data packets sent by the client until the terminating
+// empty packet and then writes the recorded final response.
+func simulateLocalInFile(ctx context.Context, logger *zap.Logger, clientConn net.Conn, localInFile *mysql.LocalInFileResponse) error {
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		data, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+		if err != nil {
+			return fmt.Errorf("failed to read file data packet from the client: %w", err)
+		}
+
+		// an empty packet marks the end of the file
+		if len(data) == 4 {
+			break
+		}
+	}
+
+	if localInFile.FinalResponse == nil || len(localInFile.FinalResponse.Data) == 0 {
+		return fmt.Errorf("no final response recorded for the local infile request")
+	}
+
+	_, err := clientConn.Write(localInFile.FinalResponse.Data)
+	if err != nil {
+		return fmt.Errorf("failed to write the final response for local infile: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package replayer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestSimulateLocalInFile(t *testing.T) {
+	request := append([]byte{mysql.LocalInFile}, "/tmp/users.csv"...)
+	fileRequest, err := query.DecodeLocalInFileRequest(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileRequest.Filename != "/tmp/users.csv" {
+		t.Errorf("filename %q, want /tmp/users.csv", fileRequest.Filename)
+	}
+	encoded, err := query.EncodeLocalInFileRequest(context.Background(), fileRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, request) {
+		t.Errorf("encoded % x, want % x", encoded, request)
+	}
+
+	// OK, 2 affected rows
+	finalResponse := []byte{0x07, 0x00, 0x00, 0x04, 0x00, 0x02, 0x00, 0x02, 0x00, 0x00, 0x00}
+	localInFile := &mysql.LocalInFileResponse{
+		Request:       fileRequest,
+		FinalResponse: &mysql.GenericResponse{Data: finalResponse},
+	}
+
+	clientConn, proxyConn := net.Pipe()
+	defer clientConn.Close()
+	defer proxyConn.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- simulateLocalInFile(context.Background(), zap.NewNop(), proxyConn, localInFile)
+	}()
+
+	content := "1,ann\n2,bob\n"
+	for _, packet := range [][]byte{
+		append([]byte{byte(len(content)), 0x00, 0x00, 0x02}, content...),
+		// the empty packet that ends the file
+		{0x00, 0x00, 0x00, 0x03},
+	} {
+		if _, err := clientConn.Write(packet); err != nil {
+			t.Fatalf("writing file data: %v", err)
+		}
+	}
+
+	response := make([]byte, len(finalResponse))
+	if _, err := io.ReadFull(clientConn, response); err != nil {
+		t.Fatalf("reading the final response: %v", err)
+	}
+	if !bytes.Equal(response, finalResponse) {
+		t.Errorf("final response % x, want % x", response, finalResponse)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("simulateLocalInFile: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_compression_packet.html
+
+// CompressedHeaderLength is the size of the header that wraps each packet of the compressed protocol
+const CompressedHeaderLength = 7
+
+// DecompressPacket unwraps a packet of the compressed protocol (CLIENT_COMPRESS) and returns
+// the MySQL packet(s) it carries. Payloads below the compression threshold are sent raw, which
+// is signalled by an uncompressed length of 0.
+func DecompressPacket(data []byte) ([]byte, error) {
+	if len(data) < CompressedHeaderLength {
+		return nil, errors.New("compressed packet is too short")
+	}
+
+	compressedLength := int(ReadUint24(data[:3]))
+	uncompressedLength := int(ReadUint24(data[4:7]))
+	if compressedLength > len(data)-CompressedHeaderLength {
+		return nil, fmt.Errorf("compressed packet length %d exceeds available %d bytes", compressedLength, len(data)-CompressedHeaderLength)
+	}
+	payload := data[CompressedHeaderLength : CompressedHeaderLength+compressedLength]
+
+	// Not compressed
+	if uncompressedLength == 0 {
+		return payload, nil
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zlib reader: %w", err)
+	}
+	defer r.Close()
+
+	out := make([]byte, uncompressedLength)
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, fmt.Errorf("failed to inflate compressed packet: %w", err)
+	}
+	return out, nil
+}
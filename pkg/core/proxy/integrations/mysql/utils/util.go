@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 
@@ -129,34 +130,49 @@ func GetPayloadLength(src []byte) (length uint32) {
 	return length
 }
 
-func ReadLengthEncodedInteger(b []byte) (num uint64, isNull bool, n int) {
+// ReadLengthEncodedInteger reads the length-encoded integer at the start of b and returns
+// its value and the number of bytes it takes. 0xfb is the NULL marker, 0xfc, 0xfd and 0xfe
+// are followed by a 2, 3 and 8 byte integer respectively and any other byte below them is
+// the value itself. An error is returned instead of reading past the end of b when b is
+// empty or shorter than its prefix announces, and for the invalid 0xff prefix.
+func ReadLengthEncodedInteger(b []byte) (num uint64, isNull bool, n int, err error) {
 	if len(b) == 0 {
-		return 0, true, 0
+		return 0, false, 0, fmt.Errorf("failed to read length-encoded integer: %w", io.ErrUnexpectedEOF)
 	}
 
 	switch b[0] {
 	// 251: NULL
 	case 0xfb:
-		return 0, true, 1
+		return 0, true, 1, nil
 
 		// 252: value of following 2
 	case 0xfc:
-		return uint64(b[1]) | uint64(b[2])<<8, false, 3
+		n = 3
 
 		// 253: value of following 3
 	case 0xfd:
-		return uint64(b[1]) | uint64(b[2])<<8 | uint64(b[3])<<16, false, 4
+		n = 4
 
 		// 254: value of following 8
 	case 0xfe:
-		return uint64(b[1]) | uint64(b[2])<<8 | uint64(b[3])<<16 |
-				uint64(b[4])<<24 | uint64(b[5])<<32 | uint64(b[6])<<40 |
-				uint64(b[7])<<48 | uint64(b[8])<<56,
-			false, 9
+		n = 9
+
+		// 255: not a valid prefix (it marks ERR packets)
+	case 0xff:
+		return 0, false, 0, errors.New("invalid length-encoded integer prefix 0xff")
+
+		// 0-250: value of first byte
+	default:
+		return uint64(b[0]), false, 1, nil
 	}
 
-	// 0-250: value of first byte
-	return uint64(b[0]), false, 1
+	if len(b) < n {
+		return 0, false, 0, fmt.Errorf("length-encoded integer with prefix %#x needs %d bytes, got %d: %w", b[0], n, len(b), io.ErrUnexpectedEOF)
+	}
+	for i := n - 1; i >= 1; i-- {
+		num = num<<8 | uint64(b[i])
+	}
+	return num, false, n, nil
 }
 
 func IsEOFPacket(data []byte) bool {
@@ -181,6 +197,46 @@ func IsOKPacket(data []byte) bool {
 	return len(data) > 7 && data[4] == mysql.OK
 }
 
+// IsRequestPublicKeyPacket reports whether the packet is the client's request for the server's
+// RSA public key during the caching_sha2_password full authentication.
+func IsRequestPublicKeyPacket(data []byte) bool {
+	return len(data) == 5 && data[4] == byte(mysql.RequestPublicKey)
+}
+
+// HasMoreResults reports whether the status flags of the EOF or OK packet ending a result
+// have SERVER_MORE_RESULTS_EXISTS set, i.e. whether another resultset or OK/ERR packet
+// follows (multi-statement queries, CALL). The packet includes its header and is expected
+// to use the CLIENT_PROTOCOL_41 layout.
+func HasMoreResults(data []byte) bool {
+	if len(data) < 5 {
+		return false
+	}
+	payload := data[4:]
+
+	pos := 1
+	switch {
+	case payload[0] == mysql.EOF && len(payload) < 9:
+		// EOF packet: the warning count precedes the status flags
+		pos += 2
+	case payload[0] == mysql.OK || payload[0] == mysql.EOF:
+		// OK packet: affected rows and last insert id precede the status flags
+		for i := 0; i < 2; i++ {
+			_, _, n, err := ReadLengthEncodedInteger(payload[pos:])
+			if err != nil {
+				return false
+			}
+			pos += n
+		}
+	default:
+		return false
+	}
+
+	if len(payload) < pos+2 {
+		return false
+	}
+	return binary.LittleEndian.Uint16(payload[pos:])&mysql.SERVER_MORE_RESULTS_EXISTS != 0
+}
+
 func IsGenericResponse(data []byte) (string, bool) {
 	if IsOKPacket(data) {
 		return "OK", true
@@ -200,18 +256,21 @@ func ReadUint24(b []byte) uint32 {
 
 func ReadLengthEncodedString(b []byte) ([]byte, bool, int, error) {
 	// Get length
-	num, isNull, n := ReadLengthEncodedInteger(b)
+	num, isNull, n, err := ReadLengthEncodedInteger(b)
+	if err != nil {
+		return nil, false, 0, err
+	}
 	if num < 1 {
 		return b[n:n], isNull, n, nil
 	}
 
-	n += int(num)
-
 	// Check data length
-	if len(b) >= n {
-		return b[n-int(num) : n : n], false, n, nil
+	if num > uint64(len(b)-n) {
+		return nil, false, n, io.EOF
 	}
-	return nil, false, n, io.EOF
+
+	n += int(num)
+	return b[n-int(num) : n : n], false, n, nil
 }
 
 // ReadNullTerminatedString reads a null-terminated string from a byte slice
@@ -237,6 +296,7 @@ func WriteStream(ctx context.Context, logger *zap.Logger, conn net.Conn, buff []
 	return nil
 }
 
+// WriteLengthEncodedString writes s prefixed by its length as a length-encoded integer.
 func WriteLengthEncodedString(buf *bytes.Buffer, s string) error {
 	length := len(s)
 	if err := WriteLengthEncodedInteger(buf, uint64(length)); err != nil {
@@ -248,6 +308,10 @@ func WriteLengthEncodedString(buf *bytes.Buffer, s string) error {
 	return nil
 }
 
+// WriteLengthEncodedInteger writes num in the smallest form the server uses: values up to 250
+// as a single byte, up to 0xFFFF as 0xfc and 2 bytes, up to 0xFFFFFF as 0xfd and 3 bytes and
+// anything larger as 0xfe and 8 bytes. 251 to 255 can't be written as a single byte since
+// 0xfb-0xff are the prefixes themselves.
 func WriteLengthEncodedInteger(buf *bytes.Buffer, num uint64) error {
 	switch {
 	case num <= 250:
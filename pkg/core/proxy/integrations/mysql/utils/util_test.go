@@ -0,0 +1,81 @@
+//go:build linux
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadLengthEncodedInteger(t *testing.T) {
+	for _, c := range []struct {
+		data   []byte
+		num    uint64
+		isNull bool
+		n      int
+	}{
+		{[]byte{0x00}, 0, false, 1},
+		{[]byte{0xfa, 0x01}, 250, false, 1},
+		{[]byte{0xfb}, 0, true, 1},
+		{[]byte{0xfc, 0xfb, 0x00}, 251, false, 3},
+		{[]byte{0xfc, 0xff, 0xff}, 0xffff, false, 3},
+		{[]byte{0xfd, 0x00, 0x00, 0x01}, 0x10000, false, 4},
+		{[]byte{0xfe, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2a}, 0x1000000, false, 9},
+	} {
+		num, isNull, n, err := ReadLengthEncodedInteger(c.data)
+		if err != nil {
+			t.Errorf("% x: %v", c.data, err)
+			continue
+		}
+		if num != c.num || isNull != c.isNull || n != c.n {
+			t.Errorf("% x read as (%d, %v, %d), want (%d, %v, %d)", c.data, num, isNull, n, c.num, c.isNull, c.n)
+		}
+	}
+}
+
+func TestReadLengthEncodedIntegerTruncated(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		{0xfc, 0x01},
+		{0xfd, 0x01, 0x02},
+		{0xfe, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07},
+	} {
+		if _, _, _, err := ReadLengthEncodedInteger(data); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("% x: got %v, want io.ErrUnexpectedEOF", data, err)
+		}
+	}
+	if _, _, _, err := ReadLengthEncodedInteger([]byte{0xff, 0x00, 0x00}); err == nil {
+		t.Error("the 0xff prefix was accepted")
+	}
+}
+
+func TestWriteLengthEncodedStringBoundaries(t *testing.T) {
+	for _, c := range []struct {
+		length int
+		prefix []byte
+	}{
+		{250, []byte{0xfa}},
+		{251, []byte{0xfc, 0xfb, 0x00}},
+		{65535, []byte{0xfc, 0xff, 0xff}},
+		{65536, []byte{0xfd, 0x00, 0x00, 0x01}},
+		{16777215, []byte{0xfd, 0xff, 0xff, 0xff}},
+		{16777216, []byte{0xfe, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+	} {
+		var buf bytes.Buffer
+		if err := WriteLengthEncodedString(&buf, strings.Repeat("x", c.length)); err != nil {
+			t.Fatalf("length %d: %v", c.length, err)
+		}
+		encoded := buf.Bytes()
+		if !bytes.HasPrefix(encoded, c.prefix) || len(encoded) != len(c.prefix)+c.length {
+			t.Errorf("length %d written with prefix % x and %d bytes in total, want prefix % x", c.length, encoded[:min(len(encoded), 9)], len(encoded), c.prefix)
+		}
+
+		value, _, n, err := ReadLengthEncodedString(encoded)
+		if err != nil || n != len(encoded) || len(value) != c.length {
+			t.Errorf("length %d read back as %d bytes using %d: %v", c.length, len(value), n, err)
+		}
+	}
+}
@@ -65,8 +65,6 @@ func handleQueryStmtResponse(ctx context.Context, logger *zap.Logger, packet mys
 		},
 	}
 
-	payloadType := payload[0]
-
 	sg, ok := decodeCtx.ServerGreetings.Load(clientConn)
 	if !ok {
 		return parsedPacket, fmt.Errorf("server Greetings not found")
@@ -74,58 +72,75 @@ func handleQueryStmtResponse(ctx context.Context, logger *zap.Logger, packet mys
 
 	logger.Debug("Last operation when handling client query", zap.Any("last operation", mysql.CommandStatusToString(lastOp)))
 
-	switch payloadType {
+	pkt, pktType, err := DecodeQueryResponse(ctx, logger, payload, lastOp, sg.CapabilityFlags, decodeCtx.ClientCapabilities)
+	if err != nil {
+		return parsedPacket, err
+	}
+
+	switch pktType {
+	case mysql.StatusToString(mysql.OK), mysql.StatusToString(mysql.ERR), mysql.StatusToString(mysql.EOF):
+		setPacketInfo(ctx, parsedPacket, pkt, pktType, clientConn, RESET, decodeCtx)
+	default:
+		// The client streams the file next for a LocalInFile request and the rows of a result set are still to be
+		// received, the last operation is reset once the response is complete
+		setPacketInfo(ctx, parsedPacket, pkt, pktType, clientConn, lastOp, decodeCtx)
+	}
+
+	return parsedPacket, nil
+}
+
+// DecodeQueryResponse decodes the first packet of the response to a COM_QUERY or COM_STMT_EXECUTE
+// (lastOp) according to its leading byte: 0x00 is an OK packet (e.g. for an INSERT or UPDATE),
+// 0xff an ERR packet, 0xfe an EOF packet, 0xfb a LOCAL INFILE request and anything else the
+// column count starting a resultset, text for COM_QUERY and binary for COM_STMT_EXECUTE. It
+// returns the decoded packet and its type.
+func DecodeQueryResponse(ctx context.Context, logger *zap.Logger, payload []byte, lastOp byte, serverCapabilities, clientCapabilities uint32) (interface{}, string, error) {
+	if len(payload) < 1 {
+		return nil, "", fmt.Errorf("invalid packet, payload is empty")
+	}
+
+	switch payload[0] {
 	case mysql.OK:
-		pkt, err := phase.DecodeOk(ctx, payload, sg.CapabilityFlags)
+		pkt, err := phase.DecodeOk(ctx, payload, okPacketCapabilities(serverCapabilities, clientCapabilities))
 		if err != nil {
-			return parsedPacket, fmt.Errorf("failed to decode OK packet: %w", err)
+			return nil, "", fmt.Errorf("failed to decode OK packet: %w", err)
 		}
-
-		setPacketInfo(ctx, parsedPacket, pkt, mysql.StatusToString(mysql.OK), clientConn, RESET, decodeCtx)
+		return pkt, mysql.StatusToString(mysql.OK), nil
 
 	case mysql.ERR:
-
-		pkt, err := phase.DecodeERR(ctx, payload, sg.CapabilityFlags)
+		pkt, err := phase.DecodeERR(ctx, payload, serverCapabilities)
 		if err != nil {
-			return parsedPacket, fmt.Errorf("failed to decode ERR packet: %w", err)
+			return nil, "", fmt.Errorf("failed to decode ERR packet: %w", err)
 		}
-
-		setPacketInfo(ctx, parsedPacket, pkt, mysql.StatusToString(mysql.ERR), clientConn, RESET, decodeCtx)
+		return pkt, mysql.StatusToString(mysql.ERR), nil
 
 	case mysql.EOF:
-		pkt, err := phase.DecodeEOF(ctx, payload, sg.CapabilityFlags)
+		pkt, err := phase.DecodeEOF(ctx, payload, serverCapabilities)
 		if err != nil {
-			return parsedPacket, fmt.Errorf("failed to decode EOF packet: %w", err)
+			return nil, "", fmt.Errorf("failed to decode EOF packet: %w", err)
 		}
-
-		setPacketInfo(ctx, parsedPacket, pkt, mysql.StatusToString(mysql.EOF), clientConn, RESET, decodeCtx)
+		return pkt, mysql.StatusToString(mysql.EOF), nil
 
 	case mysql.LocalInFile:
-		parsedPacket.Header.Type = "LocalInFile"
-		decodeCtx.LastOp.Store(clientConn, RESET) //reset the last operation
-		return parsedPacket, fmt.Errorf("LocalInFile not supported")
+		pkt, err := query.DecodeLocalInFileRequest(ctx, payload)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode LocalInFile request packet: %w", err)
+		}
+		return pkt, mysql.StatusToString(mysql.LocalInFile), nil
+
 	default:
 		//If the packet is not OK, ERR, EOF or LocalInFile, then it is a result set
-		var pktType string
-		var rowType query.RowType
-		if lastOp == mysql.COM_STMT_EXECUTE {
-			rowType = query.Binary
+		rowType, pktType := query.RowTypeFor(lastOp), string(mysql.Text)
+		if rowType == query.Binary {
 			pktType = string(mysql.Binary)
-		} else {
-			rowType = query.Text
-			pktType = string(mysql.Text)
 		}
 
 		pkt, err := query.DecodeResultSetMetadata(ctx, logger, payload, rowType)
 		if err != nil {
-			return parsedPacket, fmt.Errorf("failed to decode result set: %w", err)
+			return nil, "", fmt.Errorf("failed to decode result set: %w", err)
 		}
-
-		// Do not change the last operation if the packet is a result set, it will be changed when the result set is fully received
-		setPacketInfo(ctx, parsedPacket, pkt, pktType, clientConn, lastOp, decodeCtx)
+		return pkt, pktType, nil
 	}
-
-	return parsedPacket, nil
 }
 
 func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet, clientConn net.Conn, lastOp byte, decodeCtx *DecodeContext) (*mysql.PacketBundle, error) {
@@ -225,7 +240,7 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 
 		} else {
 			logger.Debug("OK packet", zap.Any("Type", payloadType))
-			pkt, err := phase.DecodeOk(ctx, payload, sg.CapabilityFlags)
+			pkt, err := phase.DecodeOk(ctx, payload, okPacketCapabilities(sg.CapabilityFlags, decodeCtx.ClientCapabilities))
 			if err != nil {
 				return parsedPacket, fmt.Errorf("failed to decode OK packet: %w", err)
 			}
@@ -385,11 +400,14 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 
 	case payloadType == mysql.COM_STMT_EXECUTE:
 		logger.Debug("COM_STMT_EXECUTE packet", zap.Any("Type", payloadType))
-		pkt, err := preparedstmt.DecodeStmtExecute(ctx, logger, payload, decodeCtx.PreparedStatements)
+		pkt, err := preparedstmt.DecodeStmtExecute(ctx, logger, payload, decodeCtx.PreparedStatements, decodeCtx.LongData)
 		if err != nil {
 			return parsedPacket, fmt.Errorf("failed to decode COM_STMT_EXECUTE packet: %w", err)
 		}
 
+		// the long data is consumed by the execution
+		delete(decodeCtx.LongData, pkt.StatementID)
+
 		setPacketInfo(ctx, parsedPacket, pkt, mysql.CommandStatusToString(mysql.COM_STMT_EXECUTE), clientConn, mysql.COM_STMT_EXECUTE, decodeCtx)
 		logger.Debug("COM_STMT_EXECUTE decoded", zap.Any("parsed packet", parsedPacket))
 
@@ -401,6 +419,8 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 			return parsedPacket, fmt.Errorf("failed to decode COM_STMT_CLOSE packet: %w", err)
 		}
 
+		delete(decodeCtx.LongData, pkt.StatementID)
+
 		setPacketInfo(ctx, parsedPacket, pkt, mysql.CommandStatusToString(mysql.COM_STMT_CLOSE), clientConn, mysql.COM_STMT_CLOSE, decodeCtx)
 		logger.Debug("COM_STMT_CLOSE decoded", zap.Any("parsed packet", parsedPacket))
 
@@ -411,6 +431,9 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 			return parsedPacket, fmt.Errorf("failed to decode COM_STMT_RESET packet: %w", err)
 		}
 
+		// COM_STMT_RESET discards the long data accumulated for the statement
+		delete(decodeCtx.LongData, pkt.StatementID)
+
 		setPacketInfo(ctx, parsedPacket, pkt, mysql.CommandStatusToString(mysql.COM_STMT_RESET), clientConn, mysql.COM_STMT_RESET, decodeCtx)
 
 		logger.Debug("COM_STMT_RESET decoded", zap.Any("parsed packet", parsedPacket))
@@ -422,6 +445,9 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 			return parsedPacket, fmt.Errorf("failed to decode COM_STMT_SEND_LONG_DATA packet: %w", err)
 		}
 
+		// accumulate the chunks, they are reassembled into the parameter value on COM_STMT_EXECUTE
+		preparedstmt.AppendLongData(decodeCtx.LongData, pkt)
+
 		setPacketInfo(ctx, parsedPacket, pkt, mysql.CommandStatusToString(mysql.COM_STMT_SEND_LONG_DATA), clientConn, mysql.COM_STMT_SEND_LONG_DATA, decodeCtx)
 		logger.Debug("COM_STMT_SEND_LONG_DATA decoded", zap.Any("parsed packet", parsedPacket))
 	default:
@@ -0,0 +1,70 @@
+//go:build linux
+
+package wire
+
+import (
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestDecodeQueryResponse(t *testing.T) {
+	capabilities := uint32(mysql.CLIENT_PROTOCOL_41)
+	ctx, logger := context.Background(), zap.NewNop()
+
+	// INSERT: 1 affected row, last insert id 7, SERVER_STATUS_AUTOCOMMIT
+	pkt, pktType, err := DecodeQueryResponse(ctx, logger, []byte{0x00, 0x01, 0x07, 0x02, 0x00, 0x00, 0x00}, mysql.COM_QUERY, capabilities, capabilities)
+	if err != nil {
+		t.Fatalf("OK packet: %v", err)
+	}
+	ok, isOk := pkt.(*mysql.OKPacket)
+	if !isOk || pktType != mysql.StatusToString(mysql.OK) || ok.AffectedRows != 1 || ok.LastInsertID != 7 {
+		t.Errorf("OK packet decoded as %s %+v", pktType, pkt)
+	}
+
+	// ER_NO_SUCH_TABLE (1146)
+	errPayload := append([]byte{0xff, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'shop.nope' doesn't exist"...)
+	pkt, pktType, err = DecodeQueryResponse(ctx, logger, errPayload, mysql.COM_QUERY, capabilities, capabilities)
+	if err != nil {
+		t.Fatalf("ERR packet: %v", err)
+	}
+	errPkt, isErr := pkt.(*mysql.ERRPacket)
+	if !isErr || pktType != mysql.StatusToString(mysql.ERR) || errPkt.ErrorCode != 1146 || errPkt.SQLState != "42S02" {
+		t.Errorf("ERR packet decoded as %s %+v", pktType, pkt)
+	}
+
+	pkt, pktType, err = DecodeQueryResponse(ctx, logger, append([]byte{0xfb}, "/tmp/users.csv"...), mysql.COM_QUERY, capabilities, capabilities)
+	if err != nil {
+		t.Fatalf("LOCAL INFILE request: %v", err)
+	}
+	infile, isInfile := pkt.(*mysql.LocalInFileRequestPacket)
+	if !isInfile || pktType != mysql.StatusToString(mysql.LocalInFile) || infile.Filename != "/tmp/users.csv" {
+		t.Errorf("LOCAL INFILE request decoded as %s %+v", pktType, pkt)
+	}
+
+	pkt, pktType, err = DecodeQueryResponse(ctx, logger, []byte{0x03}, mysql.COM_QUERY, capabilities, capabilities)
+	if err != nil {
+		t.Fatalf("column count: %v", err)
+	}
+	text, isText := pkt.(*mysql.TextResultSet)
+	if !isText || pktType != string(mysql.Text) || text.ColumnCount != 3 {
+		t.Errorf("COM_QUERY column count decoded as %s %+v", pktType, pkt)
+	}
+
+	pkt, pktType, err = DecodeQueryResponse(ctx, logger, []byte{0x03}, mysql.COM_STMT_EXECUTE, capabilities, capabilities)
+	if err != nil {
+		t.Fatalf("column count: %v", err)
+	}
+	binary, isBinary := pkt.(*mysql.BinaryProtocolResultSet)
+	if !isBinary || pktType != string(mysql.Binary) || binary.ColumnCount != 3 {
+		t.Errorf("COM_STMT_EXECUTE column count decoded as %s %+v", pktType, pkt)
+	}
+}
+
+func TestDecodeQueryResponseEmptyPayload(t *testing.T) {
+	if _, _, err := DecodeQueryResponse(context.Background(), zap.NewNop(), nil, mysql.COM_QUERY, 0, 0); err == nil {
+		t.Error("expected an error for an empty payload")
+	}
+}
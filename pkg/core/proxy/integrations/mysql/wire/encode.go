@@ -143,6 +143,22 @@ func EncodeToBinary(ctx context.Context, logger *zap.Logger, packet *mysql.Packe
 		if err != nil {
 			return nil, fmt.Errorf("error encoding BinaryProtocolResultSet: %v", err)
 		}
+
+	case *mysql.LocalInFileResponse:
+		pkt, ok := packet.Message.(*mysql.LocalInFileResponse)
+		if !ok {
+			return nil, fmt.Errorf("expected LocalInFileResponse, got %T", packet.Message)
+		}
+
+		if pkt.Request == nil {
+			return nil, fmt.Errorf("LocalInFileResponse has no file request")
+		}
+
+		// only the file request is sent here, the final response is sent once the client has streamed the file
+		data, err = query.EncodeLocalInFileRequest(ctx, pkt.Request)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding LocalInFile request packet: %v", err)
+		}
 	}
 
 	// Encode the header for the packet
@@ -13,6 +13,9 @@ import (
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_auth_more_data.html
 
 func DecodeAuthMoreData(_ context.Context, data []byte) (*mysql.AuthMoreDataPacket, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("AuthMoreData packet is empty")
+	}
 	return &mysql.AuthMoreDataPacket{
 		StatusTag: data[0],
 		Data:      string(data[1:]),
@@ -14,6 +14,9 @@ import (
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_auth_switch_request.html
 
 func DecodeAuthSwitchRequest(_ context.Context, data []byte) (*mysql.AuthSwitchRequestPacket, error) {
+	if len(data) < 1 {
+		return nil, errors.New("AuthSwitchRequest packet is empty")
+	}
 
 	packet := &mysql.AuthSwitchRequestPacket{
 		StatusTag: data[0],
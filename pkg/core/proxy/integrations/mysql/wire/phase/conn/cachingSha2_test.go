@@ -0,0 +1,106 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// authSwitchToCachingSha2 is the payload of the AuthSwitchRequest a MySQL 8.0 server sends
+// to switch a mysql_native_password client to caching_sha2_password, with a fresh 20 byte
+// scramble.
+var authSwitchToCachingSha2 = append(append([]byte{0xfe}, "caching_sha2_password\x00"...),
+	0x1c, 0x4f, 0x2a, 0x0b, 0x61, 0x70, 0x12, 0x37, 0x2e, 0x19,
+	0x5d, 0x03, 0x44, 0x6b, 0x08, 0x27, 0x7a, 0x31, 0x65, 0x50, 0x00)
+
+func TestAuthSwitchRequestRoundTrip(t *testing.T) {
+	packet, err := DecodeAuthSwitchRequest(context.Background(), authSwitchToCachingSha2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.PluginName != string(mysql.CachingSha2) {
+		t.Errorf("plugin %q, want %q", packet.PluginName, mysql.CachingSha2)
+	}
+	encoded, err := EncodeAuthSwitchRequest(context.Background(), packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, authSwitchToCachingSha2) {
+		t.Errorf("encoded % x, want % x", encoded, authSwitchToCachingSha2)
+	}
+
+	if _, err := DecodeAuthSwitchRequest(context.Background(), nil); err == nil {
+		t.Error("decoding an empty AuthSwitchRequest succeeded")
+	}
+}
+
+// TestCachingSha2Exchanges replays the server and client payloads that follow the scramble
+// in the fast path, where the server has the password cached, and in the full path, where the
+// client sends the password encrypted with the server's RSA public key.
+func TestCachingSha2Exchanges(t *testing.T) {
+	publicKey := "-----BEGIN PUBLIC KEY-----\nMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAu\n-----END PUBLIC KEY-----\n"
+
+	for _, c := range []struct {
+		name      string
+		payload   []byte
+		mechanism mysql.CachingSha2Password
+		data      string
+	}{
+		{"fast auth", []byte{0x01, byte(mysql.FastAuthSuccess)}, mysql.FastAuthSuccess, ""},
+		{"full auth", []byte{0x01, byte(mysql.PerformFullAuthentication)}, mysql.PerformFullAuthentication, ""},
+		{"public key", append([]byte{0x01}, publicKey...), 0, publicKey},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			packet, err := DecodeAuthMoreData(context.Background(), c.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packet.StatusTag != 0x01 {
+				t.Errorf("status tag %#x, want 0x01", packet.StatusTag)
+			}
+			if c.mechanism != 0 {
+				if packet.Data != string(c.mechanism) {
+					t.Fatalf("data %q, want the mechanism %#x", packet.Data, byte(c.mechanism))
+				}
+				// the recorder stores the mechanism by name
+				packet.Data = mysql.CachingSha2PasswordToString(c.mechanism)
+			} else if packet.Data != c.data {
+				t.Errorf("data %q, want %q", packet.Data, c.data)
+			}
+
+			encoded, err := EncodeAuthMoreData(context.Background(), packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, c.payload) {
+				t.Errorf("encoded % x, want % x", encoded, c.payload)
+			}
+		})
+	}
+
+	if _, err := DecodeAuthMoreData(context.Background(), nil); err == nil {
+		t.Error("decoding an empty AuthMoreData succeeded")
+	}
+}
+
+func TestFullAuthPublicKeyRequest(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		packet []byte
+		want   bool
+	}{
+		{"public key request", []byte{0x01, 0x00, 0x00, 0x05, byte(mysql.RequestPublicKey)}, true},
+		// over TLS the password is sent in clear text, null terminated
+		{"cleartext password", []byte{0x07, 0x00, 0x00, 0x05, 's', 'e', 'c', 'r', 'e', 't', 0x00}, false},
+		{"encrypted password", append([]byte{0x00, 0x01, 0x00, 0x05}, bytes.Repeat([]byte{0x5a}, 256)...), false},
+	} {
+		if got := utils.IsRequestPublicKeyPacket(c.packet); got != c.want {
+			t.Errorf("%s: IsRequestPublicKeyPacket = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
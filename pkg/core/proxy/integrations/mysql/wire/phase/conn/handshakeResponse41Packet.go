@@ -103,31 +103,46 @@ func DecodeHandshakeResponse(_ context.Context, logger *zap.Logger, data []byte)
 			return nil, errors.New("handshake response packet too short for connection attributes")
 		}
 
-		totalLength, isNull, n := utils.ReadLengthEncodedInteger(data)
-		if isNull || n == 0 {
+		totalLength, isNull, n, err := utils.ReadLengthEncodedInteger(data)
+		if err != nil || isNull {
 			return nil, errors.New("error decoding total length of connection attributes")
 		}
 		data = data[n:]
+		if totalLength > uint64(len(data)) {
+			return nil, errors.New("malformed handshake response packet: connection attributes exceed the packet")
+		}
 
 		attributesData := data[:totalLength]
 		data = data[totalLength:]
 
 		packet.ConnectionAttributes = make(map[string]string)
 		for len(attributesData) > 0 {
-			keyLength, isNull, n := utils.ReadLengthEncodedInteger(attributesData)
+			keyLength, isNull, n, err := utils.ReadLengthEncodedInteger(attributesData)
+			if err != nil {
+				return nil, fmt.Errorf("malformed handshake response packet: connection attribute key length: %w", err)
+			}
 			if isNull {
 				return nil, errors.New("malformed handshake response packet: null length encoded integer for connection attribute key")
 			}
 			attributesData = attributesData[n:]
+			if keyLength > uint64(len(attributesData)) {
+				return nil, errors.New("malformed handshake response packet: connection attribute key exceeds the attributes")
+			}
 
 			key := string(attributesData[:keyLength])
 			attributesData = attributesData[keyLength:]
 
-			valueLength, isNull, n := utils.ReadLengthEncodedInteger(attributesData)
+			valueLength, isNull, n, err := utils.ReadLengthEncodedInteger(attributesData)
+			if err != nil {
+				return nil, fmt.Errorf("malformed handshake response packet: connection attribute value length: %w", err)
+			}
 			if isNull {
 				return nil, errors.New("malformed handshake response packet: null length encoded integer for connection attribute value")
 			}
 			attributesData = attributesData[n:]
+			if valueLength > uint64(len(attributesData)) {
+				return nil, errors.New("malformed handshake response packet: connection attribute value exceeds the attributes")
+			}
 
 			value := string(attributesData[:valueLength])
 			attributesData = attributesData[valueLength:]
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
 
 	"go.keploy.io/server/v2/pkg/models/mysql"
 	"go.uber.org/zap"
@@ -16,6 +17,11 @@ import (
 
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_handshake_v10.html
 
+// DecodeHandshakeV10 decodes the initial handshake packet of the server. The sections after
+// the capability flags depend on them: auth-plugin-data-part-2 is only present with
+// CLIENT_SECURE_CONNECTION and its length and the auth plugin name only with
+// CLIENT_PLUGIN_AUTH, so servers that don't advertise them (like MySQL before 5.5) are read
+// without those sections.
 func DecodeHandshakeV10(_ context.Context, _ *zap.Logger, data []byte) (*mysql.HandshakeV10Packet, error) {
 
 	if len(data) < 4 {
@@ -49,12 +55,22 @@ func DecodeHandshakeV10(_ context.Context, _ *zap.Logger, data []byte) (*mysql.H
 
 	data = data[9:] // Skip 8 bytes of AuthPluginData and 1 byte filler
 
-	if len(data) < 5 { // Capability flags (2 bytes), character set (1 byte), status flags (2 bytes)
+	if len(data) < 2 {
 		return nil, fmt.Errorf("handshake packet too short for flags")
 	}
 
 	capabilityFlagsLower := binary.LittleEndian.Uint16(data[:2])
 	data = data[2:]
+	packet.CapabilityFlags = uint32(capabilityFlagsLower)
+
+	// very old servers end the packet after the lower capability flags
+	if len(data) == 0 {
+		return packet, nil
+	}
+
+	if len(data) < 16 { // character set (1 byte), status flags (2 bytes), upper capability flags (2 bytes), auth plugin data length (1 byte), reserved (10 bytes)
+		return nil, fmt.Errorf("handshake packet too short for flags")
+	}
 
 	packet.CharacterSet = data[0]
 	data = data[1:]
@@ -65,34 +81,44 @@ func DecodeHandshakeV10(_ context.Context, _ *zap.Logger, data []byte) (*mysql.H
 	capabilityFlagsUpper := binary.LittleEndian.Uint16(data[:2])
 	data = data[2:]
 
-	packet.CapabilityFlags = uint32(capabilityFlagsLower) | uint32(capabilityFlagsUpper)<<16
-	var authPluginDataLen int
-
 	packet.CapabilityFlags = uint32(capabilityFlagsLower) | uint32(capabilityFlagsUpper)<<16
 
+	// the length of the auth plugin data is only sent with CLIENT_PLUGIN_AUTH, the byte is 0x00 otherwise
+	var authPluginDataLen int
 	if packet.CapabilityFlags&mysql.CLIENT_PLUGIN_AUTH != 0 {
 		authPluginDataLen = int(data[0])
-		data = data[1:] // Skip 1 byte AuthPluginDataLen
-	} else {
-		data = data[1:] // constant 0x00
 	}
+	data = data[1:]
 
+	// MariaDB sends its extended capabilities in the last 4 of the 10 reserved bytes
+	if hasMariaDBCapabilities(packet) {
+		packet.MariaDBCapabilityFlags = binary.LittleEndian.Uint32(data[6:10])
+	}
 	data = data[10:] // Skip 10 bytes reserved (all 0s)
 
-	if authPluginDataLen > 8 {
-		lenToRead := min(authPluginDataLen-8, len(data))
+	// auth-plugin-data-part-2 is only present with CLIENT_SECURE_CONNECTION, it is at least 13 bytes
+	// long (12 bytes and a null terminator) even when the length isn't sent
+	if packet.CapabilityFlags&mysql.CLIENT_SECURE_CONNECTION != 0 {
+		lenToRead := max(13, authPluginDataLen-8)
+		if len(data) < lenToRead {
+			// some servers omit the null terminator when the plugin name doesn't follow
+			if packet.CapabilityFlags&mysql.CLIENT_PLUGIN_AUTH != 0 || len(data) < lenToRead-1 {
+				return nil, fmt.Errorf("handshake packet too short for AuthPluginData")
+			}
+			lenToRead = len(data)
+		}
 		packet.AuthPluginData = append(packet.AuthPluginData, data[:lenToRead]...)
 		data = data[lenToRead:]
 	}
 
-	if len(data) == 0 {
-		return nil, fmt.Errorf("handshake packet too short for AuthPluginName")
-	}
-
 	if packet.CapabilityFlags&mysql.CLIENT_PLUGIN_AUTH != 0 {
+		if len(data) == 0 {
+			return nil, fmt.Errorf("handshake packet too short for AuthPluginName")
+		}
 		idx = bytes.IndexByte(data, 0x00)
 		if idx == -1 {
-			return nil, fmt.Errorf("malformed handshake packet: missing null terminator for AuthPluginName")
+			// some servers omit the null terminator of the plugin name at the end of the packet
+			idx = len(data)
 		}
 		packet.AuthPluginName = string(data[:idx])
 	}
@@ -149,11 +175,15 @@ func EncodeHandshakeV10(_ context.Context, _ *zap.Logger, packet *mysql.Handshak
 		buf.WriteByte(0x00)
 	}
 
-	// Reserved (10 zero bytes)
-	buf.Write(make([]byte, 10))
+	// Reserved (10 zero bytes), the last 4 are the extended capabilities of MariaDB
+	reserved := make([]byte, 10)
+	if hasMariaDBCapabilities(packet) {
+		binary.LittleEndian.PutUint32(reserved[6:], packet.MariaDBCapabilityFlags)
+	}
+	buf.Write(reserved)
 
 	// Auth-plugin-data-part-2 (remaining auth data)
-	if packet.CapabilityFlags&mysql.CLIENT_PLUGIN_AUTH != 0 && len(packet.AuthPluginData) > 8 {
+	if packet.CapabilityFlags&(mysql.CLIENT_PLUGIN_AUTH|mysql.CLIENT_SECURE_CONNECTION) != 0 && len(packet.AuthPluginData) > 8 {
 		buf.Write(packet.AuthPluginData[8:]) // Write all remaining bytes of auth plugin data
 	}
 
@@ -165,3 +195,15 @@ func EncodeHandshakeV10(_ context.Context, _ *zap.Logger, packet *mysql.Handshak
 
 	return buf.Bytes(), nil
 }
+
+// IsMariaDB reports whether the server version of a handshake packet is the one of a MariaDB
+// server, like "5.5.5-10.11.6-MariaDB" or "11.4.2-MariaDB-ubu2404".
+func IsMariaDB(serverVersion string) bool {
+	return strings.Contains(serverVersion, "MariaDB")
+}
+
+// hasMariaDBCapabilities reports whether the reserved bytes of the handshake packet hold the
+// extended capabilities, MariaDB servers send them when they don't set CLIENT_LONG_PASSWORD.
+func hasMariaDBCapabilities(packet *mysql.HandshakeV10Packet) bool {
+	return IsMariaDB(packet.ServerVersion) && packet.CapabilityFlags&mysql.CLIENT_LONG_PASSWORD == 0
+}
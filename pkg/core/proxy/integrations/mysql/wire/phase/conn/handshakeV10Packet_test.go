@@ -0,0 +1,144 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// scramble is the 20 byte auth plugin data of the captured handshakes, sent in a first part of
+// 8 bytes and a second part of 12 bytes followed by a null terminator.
+var scramble = []byte{0x3b, 0x55, 0x78, 0x7d, 0x2c, 0x5f, 0x41, 0x0c,
+	0x23, 0x4a, 0x61, 0x19, 0x6e, 0x5b, 0x27, 0x44, 0x02, 0x70, 0x33, 0x51}
+
+// handshake builds the payload of a HandshakeV10 packet with the layout of a MySQL 5.5+
+// server: the full capability flags, the length of the auth plugin data, 10 reserved bytes,
+// auth-plugin-data-part-2 and the auth plugin name when it isn't empty.
+func handshake(version string, capabilities uint32, charset byte, reserved []byte, plugin string) []byte {
+	data := append([]byte{0x0a}, version...)
+	data = append(data, 0x00, 0x2a, 0x00, 0x00, 0x00)
+	data = append(data, scramble[:8]...)
+	data = append(data, 0x00)
+	data = binary.LittleEndian.AppendUint16(data, uint16(capabilities))
+	data = append(data, charset, 0x02, 0x00)
+	data = binary.LittleEndian.AppendUint16(data, uint16(capabilities>>16))
+	if plugin != "" {
+		data = append(data, byte(len(scramble)+1))
+	} else {
+		data = append(data, 0x00)
+	}
+	data = append(data, reserved...)
+	data = append(data, scramble[8:]...)
+	data = append(data, 0x00)
+	if plugin != "" {
+		data = append(data, plugin...)
+		data = append(data, 0x00)
+	}
+	return data
+}
+
+func TestHandshakeV10ServerVersions(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		version      string
+		capabilities uint32
+		charset      byte
+		plugin       string
+	}{
+		{"mysql 5.1", "5.1.73", 0x0000f7ff, 0x08, ""},
+		{"mysql 5.6", "5.6.51", 0x807ff7ff, 0x08, string(mysql.Native)},
+		{"mysql 5.7", "5.7.44", 0x81fff7ff, 0x21, string(mysql.Native)},
+		{"mysql 8.0", "8.0.36", 0xdfffffff, 0xff, string(mysql.CachingSha2)},
+		{"mariadb 10.1", "5.5.5-10.1.48-MariaDB-0ubuntu0.18.04.1", 0xa0fff7ff, 0x08, string(mysql.Native)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := handshake(tt.version, tt.capabilities, tt.charset, make([]byte, 10), tt.plugin)
+
+			packet, err := DecodeHandshakeV10(context.Background(), zap.NewNop(), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packet.ServerVersion != tt.version || packet.ConnectionID != 42 || packet.CharacterSet != tt.charset {
+				t.Errorf("decoded version %q, connection id %d and charset %#x", packet.ServerVersion, packet.ConnectionID, packet.CharacterSet)
+			}
+			if packet.CapabilityFlags != tt.capabilities {
+				t.Errorf("capability flags %#x, want %#x", packet.CapabilityFlags, tt.capabilities)
+			}
+			if !bytes.Equal(packet.AuthPluginData, append(append([]byte{}, scramble...), 0x00)) {
+				t.Errorf("auth plugin data % x, want the scramble and its null terminator", packet.AuthPluginData)
+			}
+			if packet.AuthPluginName != tt.plugin {
+				t.Errorf("auth plugin %q, want %q", packet.AuthPluginName, tt.plugin)
+			}
+
+			encoded, err := EncodeHandshakeV10(context.Background(), zap.NewNop(), packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, data) {
+				t.Errorf("encoded % x, want % x", encoded, data)
+			}
+		})
+	}
+}
+
+func TestHandshakeV10WithoutPluginNameTerminator(t *testing.T) {
+	data := handshake("5.6.51", 0x807ff7ff, 0x08, make([]byte, 10), string(mysql.Native))
+	packet, err := DecodeHandshakeV10(context.Background(), zap.NewNop(), data[:len(data)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.AuthPluginName != string(mysql.Native) {
+		t.Errorf("auth plugin %q, want %q", packet.AuthPluginName, mysql.Native)
+	}
+}
+
+func TestHandshakeV10MariaDBExtendedCapabilities(t *testing.T) {
+	// MariaDB 11.4 leaves CLIENT_LONG_PASSWORD unset and sends its extended capabilities in
+	// the last 4 reserved bytes
+	extended := mysql.MARIADB_CLIENT_PROGRESS | mysql.MARIADB_CLIENT_STMT_BULK_OPERATIONS |
+		mysql.MARIADB_CLIENT_EXTENDED_METADATA | mysql.MARIADB_CLIENT_CACHE_METADATA
+	reserved := binary.LittleEndian.AppendUint32(make([]byte, 6), extended)
+	data := handshake("11.4.2-MariaDB-ubu2404", 0xa1fff7fe, 0x2d, reserved, string(mysql.Ed25519))
+
+	packet, err := DecodeHandshakeV10(context.Background(), zap.NewNop(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsMariaDB(packet.ServerVersion) {
+		t.Errorf("%q not detected as MariaDB", packet.ServerVersion)
+	}
+	if packet.MariaDBCapabilityFlags != extended {
+		t.Errorf("extended capabilities %#x, want %#x", packet.MariaDBCapabilityFlags, extended)
+	}
+	if packet.AuthPluginName != string(mysql.Ed25519) {
+		t.Errorf("auth plugin %q, want %q", packet.AuthPluginName, mysql.Ed25519)
+	}
+
+	encoded, err := EncodeHandshakeV10(context.Background(), zap.NewNop(), packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("encoded % x, want % x", encoded, data)
+	}
+}
+
+func TestIsMariaDB(t *testing.T) {
+	for version, want := range map[string]bool{
+		"5.5.5-10.11.6-MariaDB":  true,
+		"11.4.2-MariaDB-ubu2404": true,
+		"8.0.36":                 false,
+		"5.7.44-log":             false,
+	} {
+		if got := IsMariaDB(version); got != want {
+			t.Errorf("IsMariaDB(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
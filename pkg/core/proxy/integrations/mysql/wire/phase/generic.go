@@ -60,8 +60,12 @@ func EncodeEOF(_ context.Context, packet *mysql.EOFPacket, capabilities uint32)
 
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_err_packet.html
 
+// DecodeERR decodes the ERR packet in data. The SQL state marker and SQL state are only
+// present with CLIENT_PROTOCOL_41, and even then the server leaves them out of the ERR packets
+// sent before the capabilities are negotiated, so they are decoded only when the '#' marker is
+// there. The error message is kept as is.
 func DecodeERR(_ context.Context, data []byte, capabilities uint32) (*mysql.ERRPacket, error) {
-	if len(data) < 9 {
+	if len(data) < 3 {
 		return nil, fmt.Errorf("ERR packet too short")
 	}
 
@@ -73,9 +77,9 @@ func DecodeERR(_ context.Context, data []byte, capabilities uint32) (*mysql.ERRP
 	packet.ErrorCode = binary.LittleEndian.Uint16(data[pos : pos+2])
 	pos += 2
 
-	if capabilities&uint32(mysql.CLIENT_PROTOCOL_41) > 0 {
-		if data[pos] != '#' {
-			return nil, fmt.Errorf("invalid SQL state marker: %c", data[pos])
+	if capabilities&uint32(mysql.CLIENT_PROTOCOL_41) > 0 && len(data) > pos && data[pos] == '#' {
+		if len(data) < pos+6 {
+			return nil, fmt.Errorf("ERR packet too short for SQL state")
 		}
 		packet.SQLStateMarker = string(data[pos])
 
@@ -88,6 +92,8 @@ func DecodeERR(_ context.Context, data []byte, capabilities uint32) (*mysql.ERRP
 	return packet, nil
 }
 
+// EncodeErr encodes the ERR packet, it is the inverse of DecodeERR. The SQL state marker and
+// SQL state are written when CLIENT_PROTOCOL_41 is set and the packet has them.
 func EncodeErr(_ context.Context, packet *mysql.ERRPacket, capabilities uint32) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
@@ -102,7 +108,7 @@ func EncodeErr(_ context.Context, packet *mysql.ERRPacket, capabilities uint32)
 	}
 
 	// Write the SQL state marker and SQL state if CLIENT_PROTOCOL_41 is set
-	if capabilities&uint32(mysql.CLIENT_PROTOCOL_41) > 0 {
+	if capabilities&uint32(mysql.CLIENT_PROTOCOL_41) > 0 && packet.SQLStateMarker != "" {
 		if len(packet.SQLStateMarker) != 1 || len(packet.SQLState) != 5 {
 			return nil, fmt.Errorf("invalid SQL state marker or SQL state length")
 		}
@@ -127,7 +133,9 @@ func EncodeErr(_ context.Context, packet *mysql.ERRPacket, capabilities uint32)
 // ref:https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_ok_packet.html
 
 func DecodeOk(_ context.Context, data []byte, capabilities uint32) (*mysql.OKPacket, error) {
-	if len(data) < 7 {
+	// header, affected rows and last insert id, the status flags and warnings are only present with
+	// CLIENT_PROTOCOL_41 or CLIENT_TRANSACTIONS
+	if len(data) < 3 {
 		return nil, fmt.Errorf("OK packet too short")
 	}
 
@@ -135,25 +143,61 @@ func DecodeOk(_ context.Context, data []byte, capabilities uint32) (*mysql.OKPac
 		Header: data[0],
 	}
 
-	var n int
+	var (
+		n   int
+		err error
+	)
 	var pos = 1
 
-	packet.AffectedRows, _, n = utils.ReadLengthEncodedInteger(data[pos:])
+	packet.AffectedRows, _, n, err = utils.ReadLengthEncodedInteger(data[pos:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read affected rows: %w", err)
+	}
 	pos += n
-	packet.LastInsertID, _, n = utils.ReadLengthEncodedInteger(data[pos:])
+	packet.LastInsertID, _, n, err = utils.ReadLengthEncodedInteger(data[pos:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last insert id: %w", err)
+	}
 	pos += n
 
 	if capabilities&uint32(mysql.CLIENT_PROTOCOL_41) > 0 {
+		if len(data) < pos+4 {
+			return nil, fmt.Errorf("OK packet too short for status flags and warnings")
+		}
 		packet.StatusFlags = binary.LittleEndian.Uint16(data[pos:])
 		pos += 2
 		packet.Warnings = binary.LittleEndian.Uint16(data[pos:])
 		pos += 2
 	} else if capabilities&uint32(mysql.CLIENT_TRANSACTIONS) > 0 {
+		if len(data) < pos+2 {
+			return nil, fmt.Errorf("OK packet too short for status flags")
+		}
 		packet.StatusFlags = binary.LittleEndian.Uint16(data[pos:])
 		pos += 2
 	}
 
-	// new ok package will check CLIENT_SESSION_TRACK too, but it is not supported in this version
+	if capabilities&uint32(mysql.CLIENT_SESSION_TRACK) > 0 {
+		packet.SessionTrack = true
+		if pos < len(data) {
+			info, _, n, err := utils.ReadLengthEncodedString(data[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read info of OK packet: %w", err)
+			}
+			packet.Info = string(info)
+			pos += n
+		}
+		if packet.StatusFlags&mysql.SERVER_SESSION_STATE_CHANGED > 0 {
+			state, _, _, err := utils.ReadLengthEncodedString(data[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read session state info of OK packet: %w", err)
+			}
+			packet.SessionStateChanges, err = decodeSessionStateChanges(state)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return packet, nil
+	}
 
 	if pos < len(data) {
 		packet.Info = string(data[pos:])
@@ -162,6 +206,75 @@ func DecodeOk(_ context.Context, data []byte, capabilities uint32) (*mysql.OKPac
 	return packet, nil
 }
 
+func decodeSessionStateChanges(data []byte) ([]mysql.SessionStateChange, error) {
+	var changes []mysql.SessionStateChange
+	pos := 0
+	for pos < len(data) {
+		change := mysql.SessionStateChange{
+			Type: data[pos],
+		}
+		pos++
+		entry, _, n, err := utils.ReadLengthEncodedString(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session state change of type %d: %w", change.Type, err)
+		}
+		pos += n
+
+		switch change.Type {
+		case mysql.SESSION_TRACK_SYSTEM_VARIABLES:
+			name, _, n, err := utils.ReadLengthEncodedString(entry)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read name of changed system variable: %w", err)
+			}
+			value, _, _, err := utils.ReadLengthEncodedString(entry[n:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read value of changed system variable %s: %w", name, err)
+			}
+			change.Name = string(name)
+			change.Value = string(value)
+		case mysql.SESSION_TRACK_SCHEMA:
+			schema, _, _, err := utils.ReadLengthEncodedString(entry)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read changed schema: %w", err)
+			}
+			change.Value = string(schema)
+		default:
+			change.Data = append([]byte(nil), entry...)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func encodeSessionStateChanges(buf *bytes.Buffer, changes []mysql.SessionStateChange) error {
+	for _, change := range changes {
+		entry := new(bytes.Buffer)
+		switch change.Type {
+		case mysql.SESSION_TRACK_SYSTEM_VARIABLES:
+			if err := utils.WriteLengthEncodedString(entry, change.Name); err != nil {
+				return fmt.Errorf("failed to write name of changed system variable: %w", err)
+			}
+			if err := utils.WriteLengthEncodedString(entry, change.Value); err != nil {
+				return fmt.Errorf("failed to write value of changed system variable: %w", err)
+			}
+		case mysql.SESSION_TRACK_SCHEMA:
+			if err := utils.WriteLengthEncodedString(entry, change.Value); err != nil {
+				return fmt.Errorf("failed to write changed schema: %w", err)
+			}
+		default:
+			entry.Write(change.Data)
+		}
+
+		if err := buf.WriteByte(change.Type); err != nil {
+			return fmt.Errorf("failed to write session state change type: %w", err)
+		}
+		if err := utils.WriteLengthEncodedString(buf, entry.String()); err != nil {
+			return fmt.Errorf("failed to write session state change: %w", err)
+		}
+	}
+	return nil
+}
+
 func EncodeOk(_ context.Context, packet *mysql.OKPacket, capabilities uint32) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
@@ -194,6 +307,25 @@ func EncodeOk(_ context.Context, packet *mysql.OKPacket, capabilities uint32) ([
 		}
 	}
 
+	if packet.SessionTrack {
+		stateChanged := packet.StatusFlags&mysql.SERVER_SESSION_STATE_CHANGED > 0
+		if packet.Info != "" || stateChanged {
+			if err := utils.WriteLengthEncodedString(buf, packet.Info); err != nil {
+				return nil, fmt.Errorf("failed to write Info for OK packet: %w", err)
+			}
+		}
+		if stateChanged {
+			state := new(bytes.Buffer)
+			if err := encodeSessionStateChanges(state, packet.SessionStateChanges); err != nil {
+				return nil, fmt.Errorf("failed to write session state info for OK packet: %w", err)
+			}
+			if err := utils.WriteLengthEncodedString(buf, state.String()); err != nil {
+				return nil, fmt.Errorf("failed to write session state info for OK packet: %w", err)
+			}
+		}
+		return buf.Bytes(), nil
+	}
+
 	// Write Info
 	if packet.Info != "" {
 		if _, err := buf.WriteString(packet.Info); err != nil {
@@ -0,0 +1,119 @@
+//go:build linux
+
+package phase
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestOkSessionStateChanges(t *testing.T) {
+	// the OK packet sent after SET character_set_client = utf8mb4; USE shop
+	data := []byte{0x00, 0x00, 0x00, 0x02, 0x40, 0x00, 0x00, 0x00, 0x26,
+		mysql.SESSION_TRACK_SYSTEM_VARIABLES, 0x1d, 0x14}
+	data = append(data, "character_set_client"...)
+	data = append(data, 0x07)
+	data = append(data, "utf8mb4"...)
+	data = append(data, mysql.SESSION_TRACK_SCHEMA, 0x05, 0x04)
+	data = append(data, "shop"...)
+	capabilities := uint32(mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SESSION_TRACK)
+
+	packet, err := DecodeOk(context.Background(), data, capabilities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []mysql.SessionStateChange{
+		{Type: mysql.SESSION_TRACK_SYSTEM_VARIABLES, Name: "character_set_client", Value: "utf8mb4"},
+		{Type: mysql.SESSION_TRACK_SCHEMA, Value: "shop"},
+	}
+	if !reflect.DeepEqual(packet.SessionStateChanges, want) {
+		t.Errorf("session state changes %+v, want %+v", packet.SessionStateChanges, want)
+	}
+
+	encoded, err := EncodeOk(context.Background(), packet, capabilities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, data) {
+		t.Errorf("encoded % x, want % x", encoded, data)
+	}
+}
+
+func TestOkAffectedRowsAndLastInsertID(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		capabilities uint32
+		data         []byte
+		lastInsertID uint64
+	}{
+		{"protocol 41", uint32(mysql.CLIENT_PROTOCOL_41), []byte{0x00, 0x05, 0x2a, 0x02, 0x00, 0x00, 0x00}, 42},
+		{"transactions", uint32(mysql.CLIENT_TRANSACTIONS), []byte{0x00, 0x05, 0x2a, 0x02, 0x00}, 42},
+		{"minimal", 0, []byte{0x00, 0x05, 0x2a}, 42},
+		// 70000 needs the 3 byte length-encoded form
+		{"large last insert id", uint32(mysql.CLIENT_PROTOCOL_41), []byte{0x00, 0x05, 0xfd, 0x70, 0x11, 0x01, 0x02, 0x00, 0x00, 0x00}, 70000},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			packet, err := DecodeOk(context.Background(), tt.data, tt.capabilities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if packet.AffectedRows != 5 {
+				t.Errorf("affected rows %d, want 5", packet.AffectedRows)
+			}
+			if packet.LastInsertID != tt.lastInsertID {
+				t.Errorf("last insert id %d, want %d", packet.LastInsertID, tt.lastInsertID)
+			}
+
+			encoded, err := EncodeOk(context.Background(), packet, tt.capabilities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, tt.data) {
+				t.Errorf("encoded % x, want % x", encoded, tt.data)
+			}
+		})
+	}
+}
+
+func TestErrRoundTrip(t *testing.T) {
+	message := "Table 'shop.nope' doesn't exist"
+	withState := append([]byte{0xff, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, message...)
+	withoutState := append([]byte{0xff, 0x7a, 0x04}, message...)
+
+	for _, tt := range []struct {
+		name         string
+		capabilities uint32
+		data         []byte
+		want         mysql.ERRPacket
+	}{
+		{"protocol 41", uint32(mysql.CLIENT_PROTOCOL_41), withState,
+			mysql.ERRPacket{Header: 0xff, ErrorCode: 1146, SQLStateMarker: "#", SQLState: "42S02", ErrorMessage: message}},
+		{"pre protocol 41", 0, withoutState,
+			mysql.ERRPacket{Header: 0xff, ErrorCode: 1146, ErrorMessage: message}},
+		// sent before the capabilities are negotiated, e.g. for too many connections
+		{"protocol 41 without marker", uint32(mysql.CLIENT_PROTOCOL_41), withoutState,
+			mysql.ERRPacket{Header: 0xff, ErrorCode: 1146, ErrorMessage: message}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			packet, err := DecodeERR(context.Background(), tt.data, tt.capabilities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *packet != tt.want {
+				t.Errorf("decoded %+v, want %+v", *packet, tt.want)
+			}
+
+			encoded, err := EncodeErr(context.Background(), packet, tt.capabilities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, tt.data) {
+				t.Errorf("encoded % x, want % x", encoded, tt.data)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ package query
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/rowscols"
@@ -19,6 +21,9 @@ type RowType int
 const (
 	Binary RowType = iota
 	Text
+	// Unknown is the row type of a resultset whose command isn't known, DecodeRow then
+	// detects the protocol of each row from its bytes.
+	Unknown
 )
 
 func DecodeResultSetMetadata(ctx context.Context, logger *zap.Logger, data []byte, rowType RowType) (interface{}, error) {
@@ -42,6 +47,98 @@ func DecodeResultSetMetadata(ctx context.Context, logger *zap.Logger, data []byt
 	return nil, nil
 }
 
+// DecodeTextResultSet decodes the response to a COM_QUERY returning rows at the start of data:
+// the column count, the column definitions, the EOF packet following them unless
+// CLIENT_DEPRECATE_EOF is set in clientCapabilities and the text rows, up to the EOF/OK or ERR
+// packet terminating them which is kept as the final response. It returns the number of bytes
+// consumed, the results following a final packet with SERVER_MORE_RESULTS_EXISTS are left to
+// the caller.
+func DecodeTextResultSet(ctx context.Context, logger *zap.Logger, data []byte, clientCapabilities uint32) (*mysql.TextResultSet, int, error) {
+	colCount, offset, err := rowscols.DecodeColumnCountPacket(ctx, logger, data)
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to decode column count packet: %w", err)
+	}
+	resultSet := &mysql.TextResultSet{ColumnCount: colCount}
+
+	for i := uint64(0); i < colCount; i++ {
+		packet, err := nextPacket(data, offset)
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to read column definition packet %d: %w", i, err)
+		}
+		column, _, err := rowscols.DecodeColumn(ctx, logger, packet)
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to decode column definition packet %d: %w", i, err)
+		}
+		resultSet.Columns = append(resultSet.Columns, column)
+		offset += len(packet)
+	}
+
+	deprecateEOF := clientCapabilities&mysql.CLIENT_DEPRECATE_EOF != 0
+	if !deprecateEOF {
+		packet, err := nextPacket(data, offset)
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to read EOF packet after columns: %w", err)
+		}
+		if !utils.IsEOFPacket(packet) {
+			return nil, offset, fmt.Errorf("expected EOF packet after columns, got %v", packet)
+		}
+		resultSet.EOFAfterColumns = bytes.Clone(packet)
+		offset += len(packet)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, offset, err
+		}
+		packet, err := nextPacket(data, offset)
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to read row %d of text resultset: %w", len(resultSet.Rows), err)
+		}
+
+		// a text row never starts with 0xfe (the prefix of an 8 byte length, only found in
+		// payloads split across packets) nor 0xff, so these are the packets ending the rows
+		switch payload := packet[4:]; {
+		case len(payload) > 0 && payload[0] == mysql.EOF && len(payload) < 0xffffff:
+			status := mysql.EOF
+			if deprecateEOF {
+				status = mysql.OK
+			}
+			resultSet.FinalResponse = &mysql.GenericResponse{
+				Data: bytes.Clone(packet),
+				Type: mysql.StatusToString(status),
+			}
+			return resultSet, offset + len(packet), nil
+		case len(payload) > 0 && payload[0] == mysql.ERR:
+			resultSet.FinalResponse = &mysql.GenericResponse{
+				Data: bytes.Clone(packet),
+				Type: mysql.StatusToString(mysql.ERR),
+			}
+			return resultSet, offset + len(packet), nil
+		case len(payload) == 0xffffff:
+			return nil, offset, fmt.Errorf("row %d of text resultset is split across packets, which isn't supported", len(resultSet.Rows))
+		}
+
+		row, _, err := rowscols.DecodeTextRow(ctx, logger, packet, resultSet.Columns)
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to decode row %d of text resultset: %w", len(resultSet.Rows), err)
+		}
+		resultSet.Rows = append(resultSet.Rows, row)
+		offset += len(packet)
+	}
+}
+
+// nextPacket returns the packet, header included, at offset in data.
+func nextPacket(data []byte, offset int) ([]byte, error) {
+	if len(data)-offset < 4 {
+		return nil, fmt.Errorf("missing packet at offset %d: %w", offset, io.ErrUnexpectedEOF)
+	}
+	end := offset + 4 + int(utils.ReadUint24(data[offset:offset+3]))
+	if end > len(data) {
+		return nil, fmt.Errorf("packet at offset %d is truncated: %w", offset, io.ErrUnexpectedEOF)
+	}
+	return data[offset:end], nil
+}
+
 func EncodeTextResultSet(ctx context.Context, logger *zap.Logger, resultSet *mysql.TextResultSet) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
@@ -85,6 +182,10 @@ func EncodeTextResultSet(ctx context.Context, logger *zap.Logger, resultSet *mys
 		}
 	}
 
+	if err := encodeMoreResults(ctx, logger, buf, resultSet.MoreResults); err != nil {
+		return nil, err
+	}
+
 	return buf.Bytes(), nil
 }
 
@@ -114,7 +215,7 @@ func EncodeBinaryResultSet(ctx context.Context, logger *zap.Logger, resultSet *m
 
 	// Encode each row data packet
 	for _, row := range resultSet.Rows {
-		rowBytes, err := rowscols.EncodeBinaryRow(ctx, logger, row, resultSet.Columns)
+		rowBytes, err := rowscols.EncodeBinaryRow(ctx, logger, row, resultSet.Columns, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to encode row: %w", err)
 		}
@@ -123,12 +224,65 @@ func EncodeBinaryResultSet(ctx context.Context, logger *zap.Logger, resultSet *m
 		}
 	}
 
-	// Write the final EOF packet if present
-	if resultSet.FinalResponse != nil && utils.IsEOFPacket(resultSet.FinalResponse.Data) {
+	// Write the final EOF/OK packet if present
+	if resultSet.FinalResponse != nil && len(resultSet.FinalResponse.Data) != 0 {
 		if _, err := buf.Write(resultSet.FinalResponse.Data); err != nil {
-			return nil, fmt.Errorf("failed to write final EOF packet: %w", err)
+			return nil, fmt.Errorf("failed to write final EOF/OK packet: %w", err)
 		}
 	}
 
+	if err := encodeMoreResults(ctx, logger, buf, resultSet.MoreResults); err != nil {
+		return nil, err
+	}
+
 	return buf.Bytes(), nil
 }
+
+// encodeMoreResults writes the results following a resultset (see mysql.MoreResult) in the
+// order they were recorded.
+func encodeMoreResults(ctx context.Context, logger *zap.Logger, buf *bytes.Buffer, moreResults []*mysql.MoreResult) error {
+	for i, result := range moreResults {
+		var (
+			data []byte
+			err  error
+		)
+		switch {
+		case result.TextResultSet != nil:
+			data, err = EncodeTextResultSet(ctx, logger, result.TextResultSet)
+			if err == nil {
+				data, err = withColumnCountHeader(data, result.TextResultSet.ColumnCount, result.TextResultSet.Columns)
+			}
+		case result.BinaryResultSet != nil:
+			data, err = EncodeBinaryResultSet(ctx, logger, result.BinaryResultSet)
+			if err == nil {
+				data, err = withColumnCountHeader(data, result.BinaryResultSet.ColumnCount, result.BinaryResultSet.Columns)
+			}
+		case result.Response != nil:
+			data = result.Response.Data
+		default:
+			err = errors.New("empty result")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode result %d following the resultset: %w", i, err)
+		}
+		if _, err := buf.Write(data); err != nil {
+			return fmt.Errorf("failed to write result %d following the resultset: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// withColumnCountHeader prepends the header of the column count packet to a resultset encoded
+// by EncodeTextResultSet or EncodeBinaryResultSet, which leave it to the caller for the first
+// resultset of a response. Its sequence id is the one preceding the first column definition.
+func withColumnCountHeader(data []byte, columnCount uint64, columns []*mysql.ColumnDefinition41) ([]byte, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("resultset has no column definitions")
+	}
+	var count bytes.Buffer
+	if err := utils.WriteLengthEncodedInteger(&count, columnCount); err != nil {
+		return nil, fmt.Errorf("failed to write column count: %w", err)
+	}
+	header := []byte{byte(count.Len()), 0x00, 0x00, columns[0].Header.SequenceID - 1}
+	return append(header, data...), nil
+}
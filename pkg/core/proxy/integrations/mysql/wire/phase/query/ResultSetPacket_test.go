@@ -0,0 +1,208 @@
+//go:build linux
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// packet prefixes payload with its header.
+func packet(seq byte, payload ...byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+// columnDefinition returns the definition packet of a BIGINT column of a procedure's
+// resultset, which has no table.
+func columnDefinition(seq byte, name string) []byte {
+	payload := []byte{0x03, 'd', 'e', 'f', 0x00, 0x00, 0x00, byte(len(name))}
+	payload = append(payload, name...)
+	payload = append(payload, 0x00, 0x0c, 0x3f, 0x00, 0x15, 0x00, 0x00, 0x00, 0x08, 0x81, 0x00, 0x00, 0x00, 0x00)
+	return packet(seq, payload...)
+}
+
+// callResponse is the response to CALL two_results(), a procedure running SELECT 1 AS a and
+// SELECT 'x' AS b: two resultsets whose final EOF packets have SERVER_MORE_RESULTS_EXISTS set,
+// and the OK packet of the CALL itself.
+var callResponse = bytes.Join([][]byte{
+	packet(1, 0x01),
+	columnDefinition(2, "a"),
+	packet(3, 0xfe, 0x00, 0x00, 0x0a, 0x00),
+	packet(4, 0x01, '1'),
+	packet(5, 0xfe, 0x00, 0x00, 0x0a, 0x00),
+	packet(6, 0x01),
+	columnDefinition(7, "b"),
+	packet(8, 0xfe, 0x00, 0x00, 0x0a, 0x00),
+	packet(9, 0x01, 'x'),
+	packet(10, 0xfe, 0x00, 0x00, 0x0a, 0x00),
+	packet(11, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00),
+}, nil)
+
+func TestEncodeCallWithTwoResultSets(t *testing.T) {
+	ctx, logger := context.Background(), zap.NewNop()
+
+	first, n, err := DecodeTextResultSet(ctx, logger, callResponse, mysql.CLIENT_PROTOCOL_41)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.HasMoreResults(first.FinalResponse.Data) {
+		t.Fatal("the first resultset isn't followed by more results")
+	}
+	second, m, err := DecodeTextResultSet(ctx, logger, callResponse[n:], mysql.CLIENT_PROTOCOL_41)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.HasMoreResults(second.FinalResponse.Data) {
+		t.Fatal("the second resultset isn't followed by more results")
+	}
+	ok := callResponse[n+m:]
+	if utils.HasMoreResults(ok) {
+		t.Error("more results follow the OK packet of the CALL")
+	}
+	if len(first.Rows) != 1 || len(second.Rows) != 1 || second.Columns[0].Name != "b" {
+		t.Fatalf("decoded %+v and %+v", first, second)
+	}
+
+	// as recorded, the results following the first resultset are kept with it
+	first.MoreResults = []*mysql.MoreResult{
+		{TextResultSet: second},
+		{Response: &mysql.GenericResponse{Data: ok, Type: mysql.StatusToString(mysql.OK)}},
+	}
+	encoded, err := EncodeTextResultSet(ctx, logger, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header of the first column count packet is written by the caller
+	encoded = append([]byte{0x01, 0x00, 0x00, 0x01}, encoded...)
+	if !bytes.Equal(encoded, callResponse) {
+		t.Errorf("encoded\n% x\nwant\n% x", encoded, callResponse)
+	}
+}
+
+// usersColumn returns the definition packet of a column of the shop.users table.
+func usersColumn(seq byte, name string, ft mysql.FieldType, charset uint16, length uint32, flags uint16) []byte {
+	payload := []byte{0x03, 'd', 'e', 'f', 0x04, 's', 'h', 'o', 'p', 0x05, 'u', 's', 'e', 'r', 's', 0x05, 'u', 's', 'e', 'r', 's'}
+	for i := 0; i < 2; i++ {
+		payload = append(payload, byte(len(name)))
+		payload = append(payload, name...)
+	}
+	payload = append(payload, 0x0c, byte(charset), byte(charset>>8),
+		byte(length), byte(length>>8), byte(length>>16), byte(length>>24),
+		byte(ft), byte(flags), byte(flags>>8), 0x00, 0x00, 0x00)
+	return packet(seq, payload...)
+}
+
+// textRow returns a text row packet of the values, nil values are NULL.
+func textRow(seq byte, values ...interface{}) []byte {
+	var payload []byte
+	for _, v := range values {
+		if v == nil {
+			payload = append(payload, 0xfb)
+			continue
+		}
+		s := v.(string)
+		payload = append(payload, byte(len(s)))
+		payload = append(payload, s...)
+	}
+	return packet(seq, payload...)
+}
+
+// selectUsers is the response to SELECT * FROM users with the EOF packets of a client
+// without CLIENT_DEPRECATE_EOF.
+var selectUsers = bytes.Join([][]byte{
+	packet(1, 0x04),
+	usersColumn(2, "id", mysql.FieldTypeLongLong, 63, 20, 0x4203),
+	usersColumn(3, "name", mysql.FieldTypeVarString, 255, 256, 0x0001),
+	usersColumn(4, "email", mysql.FieldTypeVarString, 255, 1020, 0x0000),
+	usersColumn(5, "created_at", mysql.FieldTypeDateTime, 63, 19, 0x0081),
+	packet(6, 0xfe, 0x00, 0x00, 0x22, 0x00),
+	textRow(7, "1", "ann", "ann@example.com", "2024-02-29 13:45:06"),
+	textRow(8, "2", "bob", nil, "2024-03-01 08:00:00"),
+	packet(9, 0xfe, 0x00, 0x00, 0x22, 0x00),
+}, nil)
+
+func TestDecodeTextResultSetSelectUsers(t *testing.T) {
+	ctx, logger := context.Background(), zap.NewNop()
+	// the packets following the resultset in the stream are left to the caller
+	data := append(append([]byte{}, selectUsers...), packet(1, 0x0e)...)
+
+	resultSet, n, err := DecodeTextResultSet(ctx, logger, data, mysql.CLIENT_PROTOCOL_41)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(selectUsers) {
+		t.Errorf("consumed %d bytes, want %d", n, len(selectUsers))
+	}
+	if resultSet.ColumnCount != 4 || len(resultSet.Columns) != 4 {
+		t.Fatalf("decoded %d columns (count %d), want 4", len(resultSet.Columns), resultSet.ColumnCount)
+	}
+	for i, name := range []string{"id", "name", "email", "created_at"} {
+		col := resultSet.Columns[i]
+		if col.Name != name || col.Schema != "shop" || col.Table != "users" {
+			t.Errorf("column %d is %s.%s.%s, want shop.users.%s", i, col.Schema, col.Table, col.Name, name)
+		}
+	}
+
+	want := [][]interface{}{
+		{"1", "ann", "ann@example.com", "2024-02-29 13:45:06"},
+		{"2", "bob", nil, "2024-03-01 08:00:00"},
+	}
+	if len(resultSet.Rows) != len(want) {
+		t.Fatalf("decoded %d rows, want %d", len(resultSet.Rows), len(want))
+	}
+	for i, row := range resultSet.Rows {
+		for j, v := range row.Values {
+			if v.Value != want[i][j] || v.Name != resultSet.Columns[j].Name {
+				t.Errorf("row %d column %s = %#v, want %#v", i, v.Name, v.Value, want[i][j])
+			}
+		}
+	}
+	if resultSet.FinalResponse == nil || resultSet.FinalResponse.Type != mysql.StatusToString(mysql.EOF) {
+		t.Errorf("final response %+v, want the EOF packet", resultSet.FinalResponse)
+	}
+
+	encoded, err := EncodeTextResultSet(ctx, logger, resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header of the column count packet is written by the caller
+	encoded = append([]byte{0x01, 0x00, 0x00, 0x01}, encoded...)
+	if !bytes.Equal(encoded, selectUsers) {
+		t.Errorf("encoded\n% x\nwant\n% x", encoded, selectUsers)
+	}
+}
+
+func TestDecodeTextResultSetDeprecateEOF(t *testing.T) {
+	// without the EOF packet after the columns, and an OK packet with the 0xfe header ending the rows
+	data := bytes.Join([][]byte{
+		packet(1, 0x01),
+		usersColumn(2, "name", mysql.FieldTypeVarString, 255, 256, 0x0001),
+		textRow(3, "ann"),
+		packet(4, 0xfe, 0x00, 0x00, 0x22, 0x00, 0x00, 0x00),
+	}, nil)
+
+	resultSet, n, err := DecodeTextResultSet(context.Background(), zap.NewNop(), data, mysql.CLIENT_PROTOCOL_41|mysql.CLIENT_DEPRECATE_EOF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) || len(resultSet.Rows) != 1 || resultSet.Rows[0].Values[0].Value != "ann" {
+		t.Errorf("decoded %+v from %d bytes", resultSet, n)
+	}
+	if resultSet.EOFAfterColumns != nil || resultSet.FinalResponse.Type != mysql.StatusToString(mysql.OK) {
+		t.Errorf("decoded EOF % x and final response %+v, want no EOF and an OK packet", resultSet.EOFAfterColumns, resultSet.FinalResponse)
+	}
+}
+
+func TestDecodeTextResultSetTruncated(t *testing.T) {
+	// the response ends before the EOF packet terminating the rows
+	data := selectUsers[:len(selectUsers)-9]
+	if _, _, err := DecodeTextResultSet(context.Background(), zap.NewNop(), data, mysql.CLIENT_PROTOCOL_41); err == nil {
+		t.Error("expected an error for a resultset without its final packet")
+	}
+}
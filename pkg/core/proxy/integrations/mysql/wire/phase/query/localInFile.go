@@ -0,0 +1,40 @@
+//go:build linux
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// LOCAL INFILE Request: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_local_infile_request.html
+
+func DecodeLocalInFileRequest(_ context.Context, data []byte) (*mysql.LocalInFileRequestPacket, error) {
+	if len(data) < 1 || data[0] != mysql.LocalInFile {
+		return nil, fmt.Errorf("invalid LOCAL INFILE request packet")
+	}
+
+	packet := &mysql.LocalInFileRequestPacket{
+		PacketType: data[0],
+		Filename:   string(data[1:]),
+	}
+
+	return packet, nil
+}
+
+func EncodeLocalInFileRequest(_ context.Context, packet *mysql.LocalInFileRequestPacket) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	if err := buf.WriteByte(mysql.LocalInFile); err != nil {
+		return nil, fmt.Errorf("failed to write packet type: %w", err)
+	}
+
+	if _, err := buf.WriteString(packet.Filename); err != nil {
+		return nil, fmt.Errorf("failed to write filename: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -15,7 +15,8 @@ import (
 
 // COM_STMT_EXECUTE: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_stmt_execute.html
 
-func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedStmts map[uint32]*mysql.StmtPrepareOkPacket) (*mysql.StmtExecutePacket, error) {
+// The values of parameters sent with COM_STMT_SEND_LONG_DATA are not part of the packet, they are taken from longData instead.
+func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedStmts map[uint32]*mysql.StmtPrepareOkPacket, longData map[uint32]map[uint16][]byte) (*mysql.StmtExecutePacket, error) {
 	if len(data) < 10 {
 		return &mysql.StmtExecutePacket{}, fmt.Errorf("packet length too short for COM_STMT_EXECUTE")
 	}
@@ -94,12 +95,26 @@ func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedSt
 		}
 	}
 
+	// The parameter types are omitted when they were bound by a previous execution
+	if packet.Parameters == nil {
+		packet.Parameters = make([]mysql.Parameter, packet.ParameterCount)
+	}
+
 	// Read Parameter Values
+	stmtLongData := longData[packet.StatementID]
 	for i := 0; i < packet.ParameterCount; i++ {
+		if chunks, ok := stmtLongData[uint16(i)]; ok {
+			packet.Parameters[i].Value = chunks
+			packet.Parameters[i].LongData = true
+			continue
+		}
 		if pos >= len(data) {
 			return nil, io.ErrUnexpectedEOF
 		}
-		length, _, n := utils.ReadLengthEncodedInteger(data[pos:])
+		length, _, n, err := utils.ReadLengthEncodedInteger(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read length of parameter %d: %w", i, err)
+		}
 		pos += n
 		if pos+int(length) > len(data) {
 			return nil, io.ErrUnexpectedEOF
@@ -26,3 +26,14 @@ func DecodeStmtSendLongData(_ context.Context, data []byte) (*mysql.StmtSendLong
 
 	return packet, nil
 }
+
+// AppendLongData appends the chunk of the packet to the data accumulated for its statement and parameter,
+// a parameter can be sent in several COM_STMT_SEND_LONG_DATA packets before the statement is executed.
+func AppendLongData(longData map[uint32]map[uint16][]byte, packet *mysql.StmtSendLongDataPacket) {
+	params, ok := longData[packet.StatementID]
+	if !ok {
+		params = make(map[uint16][]byte)
+		longData[packet.StatementID] = params
+	}
+	params[packet.ParameterID] = append(params[packet.ParameterID], packet.Data...)
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package preparedstmt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestReassembleMultiChunkLongData(t *testing.T) {
+	preparedStmts := map[uint32]*mysql.StmtPrepareOkPacket{1: {StatementID: 1, NumParams: 2}}
+	longData := map[uint32]map[uint16][]byte{}
+
+	// the second parameter of statement 1 is streamed in three chunks
+	for _, chunk := range []string{"first,", "second,", "third"} {
+		data := append([]byte{0x18, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00}, chunk...)
+		packet, err := DecodeStmtSendLongData(context.Background(), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AppendLongData(longData, packet)
+	}
+	// a chunk of another statement stays separate
+	other, err := DecodeStmtSendLongData(context.Background(), append([]byte{0x18, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00}, "other"...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AppendLongData(longData, other)
+
+	// the execute packet only carries the value of the first parameter
+	execute := []byte{
+		0x17, 0x01, 0x00, 0x00, 0x00, // COM_STMT_EXECUTE, statement id
+		0x00, 0x01, 0x00, 0x00, 0x00, // flags, iteration count
+		0x00, 0x01, // null bitmap, new params bound
+		byte(mysql.FieldTypeLongLong), 0x00, byte(mysql.FieldTypeBLOB), 0x00,
+		0x02, '4', '2',
+	}
+	packet, err := DecodeStmtExecute(context.Background(), zap.NewNop(), execute, preparedStmts, longData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := packet.Parameters[0]; got.LongData || !bytes.Equal(got.Value, []byte("42")) {
+		t.Errorf("first parameter %+v, want the inline value 42", got)
+	}
+	if got := packet.Parameters[1]; !got.LongData || string(got.Value) != "first,second,third" {
+		t.Errorf("second parameter %+v, want the reassembled long data", got)
+	}
+}
+
+func TestDecodeStmtSendLongDataTooShort(t *testing.T) {
+	if _, err := DecodeStmtSendLongData(context.Background(), []byte{0x18, 0x01, 0x00, 0x00, 0x00, 0x01}); err == nil {
+		t.Error("decoding a packet without a parameter id succeeded")
+	}
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/rowscols"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// RowTypeFor returns the row type of the resultsets sent in response to the command, only
+// COM_STMT_EXECUTE responds with binary protocol rows.
+func RowTypeFor(command byte) RowType {
+	if command == mysql.COM_STMT_EXECUTE {
+		return Binary
+	}
+	return Text
+}
+
+// DecodeRow decodes the row packet at the start of data, including its header, as a binary
+// protocol row (*mysql.BinaryRow) or a text protocol row (*mysql.TextRow) according to
+// rowType, see RowTypeFor. It returns the row and the number of bytes consumed.
+//
+// For the Unknown row type the protocol is detected from the packet: a binary row starts
+// with a 0x00 header followed by the null bitmap, while a text row starts with the first
+// column value, so a row whose payload doesn't start with 0x00 is a text row. A payload
+// starting with 0x00 can also be a text row whose first value is empty, it is decoded as a
+// binary row only when the columns consume the payload exactly.
+func DecodeRow(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41, rowType RowType) (interface{}, int, error) {
+	switch rowType {
+	case Binary:
+		return decodeBinaryRow(ctx, logger, data, columns)
+	case Text:
+		return decodeTextRow(ctx, logger, data, columns)
+	case Unknown:
+	default:
+		return nil, 0, fmt.Errorf("unknown row type %d", rowType)
+	}
+
+	if len(data) < 5 {
+		return nil, 0, errors.New("malformed row packet: packet too short")
+	}
+	if data[4] == 0x00 {
+		row, n, err := rowscols.DecodeBinaryRowWithOptions(ctx, logger, data, columns, rowscols.DecodeOptions{VerifyLength: true})
+		if err == nil && n == 4+int(utils.ReadUint24(data[:3])) {
+			return row, n, nil
+		}
+		logger.Debug("row packet is not a binary row, decoding it as a text row", zap.Error(err))
+	}
+	return decodeTextRow(ctx, logger, data, columns)
+}
+
+// decodeBinaryRow and decodeTextRow return a nil interface rather than a nil row on error.
+
+func decodeBinaryRow(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) (interface{}, int, error) {
+	row, n, err := rowscols.DecodeBinaryRow(ctx, logger, data, columns)
+	if err != nil {
+		return nil, n, err
+	}
+	return row, n, nil
+}
+
+func decodeTextRow(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) (interface{}, int, error) {
+	row, n, err := rowscols.DecodeTextRow(ctx, logger, data, columns)
+	if err != nil {
+		return nil, n, err
+	}
+	return row, n, nil
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// rowColumns are the columns of SELECT id, name FROM users.
+var rowColumns = []*mysql.ColumnDefinition41{
+	{Name: "id", Type: byte(mysql.FieldTypeLong)},
+	{Name: "name", Type: byte(mysql.FieldTypeVarString)},
+}
+
+func TestDecodeRow(t *testing.T) {
+	binaryRow := packet(1, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x03, 'a', 'n', 'n')
+	textRow := packet(1, 0x01, '7', 0x03, 'a', 'n', 'n')
+	// a text row whose first value is the empty string also starts with 0x00
+	emptyFirstTextRow := packet(1, 0x00, 0x03, 'a', 'n', 'n')
+
+	for _, tt := range []struct {
+		name     string
+		data     []byte
+		rowType  RowType
+		wantText bool
+	}{
+		{"binary", binaryRow, Binary, false},
+		{"text", textRow, Text, true},
+		{"detected binary", binaryRow, Unknown, false},
+		{"detected text", textRow, Unknown, true},
+		{"detected text with empty first value", emptyFirstTextRow, Unknown, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			row, n, err := DecodeRow(context.Background(), zap.NewNop(), tt.data, rowColumns, tt.rowType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(tt.data) {
+				t.Errorf("consumed %d bytes, want %d", n, len(tt.data))
+			}
+
+			var values []mysql.ColumnEntry
+			switch row := row.(type) {
+			case *mysql.TextRow:
+				if !tt.wantText {
+					t.Fatal("decoded a binary row as a text row")
+				}
+				values = row.Values
+			case *mysql.BinaryRow:
+				if tt.wantText {
+					t.Fatal("decoded a text row as a binary row")
+				}
+				values = row.Values
+			default:
+				t.Fatalf("decoded a %T", row)
+			}
+			if len(values) != 2 || values[1].Value != "ann" {
+				t.Errorf("decoded values %+v, want name ann", values)
+			}
+		})
+	}
+}
+
+func TestDecodeRowUnknownRowType(t *testing.T) {
+	if _, _, err := DecodeRow(context.Background(), zap.NewNop(), packet(1, 0x01, '7'), rowColumns, RowType(42)); err == nil {
+		t.Error("expected an error for an unknown row type")
+	}
+}
+
+func TestRowTypeFor(t *testing.T) {
+	if RowTypeFor(mysql.COM_STMT_EXECUTE) != Binary || RowTypeFor(mysql.COM_QUERY) != Text {
+		t.Error("only COM_STMT_EXECUTE responds with binary rows")
+	}
+}
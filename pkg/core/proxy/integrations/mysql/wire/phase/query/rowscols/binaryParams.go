@@ -0,0 +1,120 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// COM_STMT_EXECUTE parameter values use the same binary encoding as the values of resultset
+// rows, but their NULL bitmap has no reserved bits.
+// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_stmt_execute.html
+
+// DecodeBinaryParams decodes the parameter block of a COM_STMT_EXECUTE packet: the NULL bitmap,
+// the new-params-bound flag, the parameter types and the values of paramCount parameters.
+// When the types aren't sent again (new-params-bound flag of 0) they are taken from bound,
+// the parameters of the previous execution of the statement. It returns the parameters and
+// the number of bytes consumed.
+func DecodeBinaryParams(_ context.Context, _ *zap.Logger, data []byte, paramCount int, bound []mysql.ColumnEntry) ([]mysql.ColumnEntry, int, error) {
+	if paramCount <= 0 {
+		return nil, 0, nil
+	}
+
+	pos := 0
+	nullBitmapLen := (paramCount + 7 + paramsNullBitmapOffset) / 8
+	if len(data) < nullBitmapLen+1 {
+		return nil, 0, errors.New("malformed COM_STMT_EXECUTE parameters: truncated null bitmap")
+	}
+	nullBitmap := data[pos : pos+nullBitmapLen]
+	pos += nullBitmapLen
+
+	newParamsBound := data[pos]
+	pos++
+
+	params := make([]mysql.ColumnEntry, paramCount)
+	if newParamsBound == 1 {
+		if len(data) < pos+2*paramCount {
+			return nil, pos, errors.New("malformed COM_STMT_EXECUTE parameters: truncated parameter types")
+		}
+		for i := range params {
+			params[i].Type = mysql.FieldType(data[pos])
+			params[i].Unsigned = data[pos+1]&0x80 != 0
+			pos += 2
+		}
+	} else {
+		if len(bound) != paramCount {
+			return nil, pos, fmt.Errorf("parameter types are not sent and %d bound types are known for %d parameters", len(bound), paramCount)
+		}
+		for i := range params {
+			params[i].Type = bound[i].Type
+			params[i].Unsigned = bound[i].Unsigned
+		}
+	}
+
+	for i := range params {
+		if isNullAt(nullBitmap, i, paramsNullBitmapOffset) {
+			continue
+		}
+		col := &mysql.ColumnDefinition41{Type: byte(params[i].Type)}
+		if params[i].Unsigned {
+			col.Flags |= mysql.UNSIGNED_FLAG
+		}
+		value, n, err := DecodeColumnValue(data[pos:], col)
+		if err != nil {
+			return nil, pos, fmt.Errorf("malformed COM_STMT_EXECUTE parameter %d at offset %d: %w", i, pos, err)
+		}
+		params[i].Value = value
+		pos += n
+	}
+
+	return params, pos, nil
+}
+
+// EncodeBinaryParams encodes the parameter block of a COM_STMT_EXECUTE packet, the reverse
+// of DecodeBinaryParams. A nil value is sent as NULL and the parameter types are only
+// written when sendTypes is set.
+func EncodeBinaryParams(_ context.Context, _ *zap.Logger, params []mysql.ColumnEntry, sendTypes bool) ([]byte, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	buf := new(bytes.Buffer)
+
+	nulls := make([]bool, len(params))
+	for i, p := range params {
+		nulls[i] = p.Value == nil
+	}
+	buf.Write(buildNullBitmapAt(nulls, paramsNullBitmapOffset))
+
+	if !sendTypes {
+		buf.WriteByte(0)
+	} else {
+		buf.WriteByte(1)
+		for _, p := range params {
+			var flags byte
+			if p.Unsigned {
+				flags = 0x80
+			}
+			buf.WriteByte(byte(p.Type))
+			buf.WriteByte(flags)
+		}
+	}
+
+	for i, p := range params {
+		if p.Value == nil {
+			continue
+		}
+		col := &mysql.ColumnDefinition41{Type: byte(p.Type), Name: fmt.Sprintf("parameter %d", i)}
+		if err := writeBinaryValue(buf, col, p, &EncodeOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
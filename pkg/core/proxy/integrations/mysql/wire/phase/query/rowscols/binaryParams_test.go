@@ -0,0 +1,55 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestBinaryParamsWithNull(t *testing.T) {
+	// SELECT * FROM users WHERE id = ? AND name = ? executed with 5 and NULL
+	block := []byte{
+		0x02,       // the second parameter is NULL, no reserved bits
+		0x01,       // new params bound
+		0x08, 0x00, // LONGLONG
+		0xfd, 0x00, // VAR_STRING
+		0x05, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	params, n, err := DecodeBinaryParams(context.Background(), zap.NewNop(), block, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(block) {
+		t.Errorf("decoded %d of the %d bytes", n, len(block))
+	}
+	if params[0].Type != mysql.FieldTypeLongLong || params[0].Value != int64(5) {
+		t.Errorf("first parameter decoded as %+v", params[0])
+	}
+	if params[1].Type != mysql.FieldTypeVarString || params[1].Value != nil {
+		t.Errorf("second parameter decoded as %+v", params[1])
+	}
+
+	encoded, err := EncodeBinaryParams(context.Background(), zap.NewNop(), params, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, block) {
+		t.Errorf("encoded % x, want % x", encoded, block)
+	}
+
+	// a later execution of the statement doesn't send the types again
+	rebound := []byte{0x02, 0x00, 0x06, 0, 0, 0, 0, 0, 0, 0}
+	params, _, err = DecodeBinaryParams(context.Background(), zap.NewNop(), rebound, 2, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params[0].Value != int64(6) || params[1].Value != nil {
+		t.Errorf("parameters decoded with the bound types as %+v", params)
+	}
+}
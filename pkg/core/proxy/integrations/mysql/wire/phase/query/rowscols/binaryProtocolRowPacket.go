@@ -6,10 +6,19 @@ package rowscols
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -18,31 +27,349 @@ import (
 
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html#sect_protocol_binary_resultset_row
 
-func DecodeBinaryRow(_ context.Context, _ *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) (*mysql.BinaryRow, int, error) {
+// ErrUnsupportedType is wrapped by the errors returned for values of a field type that
+// can't be decoded or encoded.
+var ErrUnsupportedType = errors.New("unsupported column type")
 
-	offset := 0
-	row := &mysql.BinaryRow{
-		Header: mysql.Header{
-			PayloadLength: utils.ReadUint24(data[offset : offset+3]),
-			SequenceID:    data[offset+3],
-		},
+// ErrResultSetEnd is returned by DecodeBinaryRow when data holds the EOF/OK packet that
+// terminates the rows of a resultset instead of a row.
+var ErrResultSetEnd = errors.New("end of resultset")
+
+// ErrRowTooLarge is wrapped by the errors returned for rows whose packet headers announce
+// more than DecodeOptions.MaxRowBytes of payload.
+var ErrRowTooLarge = errors.New("row exceeds the maximum size")
+
+// ErrColumnCountMismatch is wrapped by the DecodeError returned for a row whose payload has
+// bytes left over once the values of all the columns are read, which happens when fewer
+// columns are passed than the row has, e.g. definitions from a corrupted mock.
+var ErrColumnCountMismatch = errors.New("column count mismatch")
+
+// DefaultMaxRowBytes is the limit on the payload of a single row used when
+// DecodeOptions.MaxRowBytes is not set. It is well above what real resultsets need (the
+// server's max_allowed_packet defaults to 64MiB) while keeping a corrupt or hostile length
+// from exhausting memory.
+const DefaultMaxRowBytes = 256 << 20
+
+// DecodeError describes where decoding a binary row failed. Offset is the byte offset
+// within the packet (including the 4 byte header), or within the reassembled payload for
+// rows split across several packets, and Column the index of the column being decoded,
+// or -1 when the failure is not specific to a column.
+type DecodeError struct {
+	Offset int
+	Column int
+	Msg    string
+	// Context holds the bytes around Offset, starting at ContextStart, when the row was
+	// decoded with DecodeOptions.VerboseErrors. They are shown as a hex dump in the message.
+	Context      []byte
+	ContextStart int
+
+	err error
+}
+
+func (e *DecodeError) Error() string {
+	var msg string
+	if e.Column < 0 {
+		msg = fmt.Sprintf("malformed binary row packet at offset %d: %s", e.Offset, e.Msg)
+	} else {
+		msg = fmt.Sprintf("malformed binary row packet at offset %d (column %d): %s", e.Offset, e.Column, e.Msg)
+	}
+	if len(e.Context) > 0 {
+		msg += fmt.Sprintf(" [bytes %d-%d: % x]", e.ContextStart, e.ContextStart+len(e.Context)-1, e.Context)
+	}
+	return msg
+}
+
+// errorContextBytes is the number of bytes on each side of the offset of a DecodeError kept
+// as its context.
+const errorContextBytes = 8
+
+// withContext adds the bytes of data around the offset of a DecodeError to it, errors that
+// aren't a DecodeError are returned unchanged.
+func withContext(err error, data []byte) error {
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Offset > len(data) {
+		return err
+	}
+	start := max(de.Offset-errorContextBytes, 0)
+	end := min(de.Offset+errorContextBytes, len(data))
+	de.ContextStart = start
+	de.Context = append([]byte(nil), data[start:end]...)
+	return err
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.err
+}
+
+// ValueTransformer is applied to each decoded non-NULL value of a row, the value it returns
+// is stored in the row instead. It can be used to mask sensitive data before it is persisted
+// in the mocks; the returned value may have a different length (or type, as long as it can be
+// encoded for the column) since the lengths are recomputed on encode.
+type ValueTransformer func(col *mysql.ColumnDefinition41, raw interface{}) interface{}
+
+// DecodeOptions controls how DecodeBinaryRowWithOptions decodes a row, the zero value
+// decodes a complete packet like DecodeBinaryRow.
+type DecodeOptions struct {
+	// WithoutHeader is set when data holds only the row payload, the 4 byte packet header
+	// having already been stripped by the caller. The returned row then has an empty Header
+	// and the returned length is the number of payload bytes consumed.
+	WithoutHeader bool
+	// Transformer, if set, is applied to each decoded non-NULL value, see ValueTransformer.
+	Transformer ValueTransformer
+	// Stats, if set, accumulates the number of rows, bytes and values per type decoded.
+	Stats *DecodeStats
+	// CaptureUnsupported stores the values of field types without dedicated support as their
+	// raw bytes, flagged as Opaque, instead of failing the whole row. It only applies to types
+	// whose value framing is known, others still fail with ErrUnsupportedType.
+	CaptureUnsupported bool
+	// VerifyLength checks that the columns consume exactly the payload length declared in the
+	// packet header, a value decoded with the wrong size otherwise silently shifts the columns
+	// after it. A DecodeError is returned when bytes are missing, bytes left over are always
+	// reported (see ErrColumnCountMismatch).
+	VerifyLength bool
+	// MaxRowBytes limits the payload a single row may claim, a row whose headers announce
+	// more fails with ErrRowTooLarge before anything is allocated for it. DefaultMaxRowBytes
+	// is used when it is zero.
+	MaxRowBytes int
+	// RawFloats keeps the little-endian bytes of FLOAT and DOUBLE values in ColumnEntry.Raw,
+	// next to the decoded value. Subnormals, infinities and NaN survive the yaml mocks as
+	// decoded values, and the bytes of -0 are always kept as it reads back as the integer 0,
+	// but NaN payloads other than the canonical one are lost: the raw bytes let the replay
+	// send back the exact IEEE-754 bit pattern the server sent. They are not kept for values
+	// changed by a Transformer.
+	RawFloats bool
+	// SkipCorruptRows makes DecodeBinaryResultSetWithOptions skip a row packet that fails to
+	// decode and continue at the next packet found by ResyncToNextPacket, instead of ending
+	// the resultset with the error. The skipped bytes are logged.
+	SkipCorruptRows bool
+	// ZeroCopy returns the values of the string, BLOB, JSON, BIT and GEOMETRY columns as []byte
+	// views into data instead of copying them into new strings, halving the memory taken by
+	// rows with large text or BLOB values. The decoded row then aliases data: the caller must
+	// not modify or reuse data while the row is in use. The views are capped to their length
+	// so that appending to them doesn't write into data.
+	ZeroCopy bool
+	// VerboseErrors adds the bytes around the failing offset to the DecodeError returned for
+	// a malformed row, shown as a hex dump in its message. It is off by default as the bytes
+	// may hold sensitive values.
+	VerboseErrors bool
+	// IncludeColumns, if set, names the only columns whose values are kept, and
+	// ExcludeColumns names columns whose values aren't, e.g. large BLOBs the application
+	// doesn't read. The value of a left out column is still read past so that the columns
+	// after it stay aligned, but it is replaced by the "sha256:" prefixed hex digest of its
+	// wire bytes and flagged as Omitted. Replaying such a row sends the digest in place of
+	// the value, so only the columns sent as length-encoded strings (strings, BLOBs, JSON,
+	// DECIMAL, BIT and GEOMETRY) can be left out, the fixed width values of the other types
+	// are always kept. Names are matched case-insensitively.
+	IncludeColumns []string
+	ExcludeColumns []string
+	// EnumLabels maps the names of ENUM and SET columns to their labels in definition order,
+	// the column definitions don't carry them. The values of these columns are decoded into
+	// their ordinal as an uint64 instead of the label, for applications that compare on the
+	// numeric value: the 1-based index of the label of an ENUM (0 for the empty string of an
+	// invalid value) and the bitmask of the members of a SET. Names are matched
+	// case-insensitively, see EncodeOptions.EnumLabels to write the labels back.
+	EnumLabels map[string][]string
+}
+
+// omits reports whether the value of the column is left out according to IncludeColumns and
+// ExcludeColumns.
+func (o *DecodeOptions) omits(col *mysql.ColumnDefinition41) bool {
+	if len(o.IncludeColumns) > 0 && !containsFold(o.IncludeColumns, col.Name) {
+		return true
+	}
+	return containsFold(o.ExcludeColumns, col.Name)
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
 	}
-	offset += 4
+	return false
+}
+
+// isLengthEncodedType reports whether the values of the field type are sent as length-encoded
+// strings, which any string (like the digest of a left out value) can be written as.
+func isLengthEncodedType(ft mysql.FieldType) bool {
+	return isByteViewType(ft) || ft == mysql.FieldTypeDecimal || ft == mysql.FieldTypeNewDecimal
+}
 
-	if data[offset] != 0x00 {
-		return nil, offset, errors.New("malformed binary row packet")
+// valueDigest returns the placeholder stored for the value of a left out column.
+func valueDigest(wire []byte) string {
+	sum := sha256.Sum256(wire)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// maxRowBytes returns the row size limit of the options.
+func (o *DecodeOptions) maxRowBytes() int {
+	if o.MaxRowBytes > 0 {
+		return o.MaxRowBytes
+	}
+	return DefaultMaxRowBytes
+}
+
+// checkRowSize returns a DecodeError wrapping ErrRowTooLarge if a payload of payloadLength
+// bytes exceeds the limit.
+func checkRowSize(payloadLength, limit, offset int) error {
+	if payloadLength <= limit {
+		return nil
+	}
+	return &DecodeError{
+		Offset: offset,
+		Column: -1,
+		Msg:    fmt.Sprintf("%v: payload of %d bytes, the limit is %d", ErrRowTooLarge, payloadLength, limit),
+		err:    ErrRowTooLarge,
+	}
+}
+
+func DecodeBinaryRow(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) (*mysql.BinaryRow, int, error) {
+	return DecodeBinaryRowWithOptions(ctx, logger, data, columns, DecodeOptions{})
+}
+
+// DecodeBinaryRowWithTransformer decodes the binary row like DecodeBinaryRow, passing every
+// decoded non-NULL value through transform.
+func DecodeBinaryRowWithTransformer(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41, transform ValueTransformer) (*mysql.BinaryRow, int, error) {
+	return DecodeBinaryRowWithOptions(ctx, logger, data, columns, DecodeOptions{Transformer: transform})
+}
+
+// DecodeBinaryRowPayload decodes a binary row whose 4 byte packet header has already been
+// stripped by the caller. The returned row has an empty Header and the returned length is
+// the number of payload bytes consumed.
+func DecodeBinaryRowPayload(ctx context.Context, logger *zap.Logger, payload []byte, columns []*mysql.ColumnDefinition41) (*mysql.BinaryRow, int, error) {
+	return DecodeBinaryRowWithOptions(ctx, logger, payload, columns, DecodeOptions{WithoutHeader: true})
+}
+
+// DecodeBinaryRowWithOptions decodes the binary row in data according to opts.
+func DecodeBinaryRowWithOptions(_ context.Context, _ *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41, opts DecodeOptions) (*mysql.BinaryRow, int, error) {
+	row, n, err := decodeBinaryRow(data, columns, &opts)
+	if err != nil && opts.VerboseErrors && !isSplitRow(data, &opts) {
+		err = withContext(err, data)
+	}
+	if opts.Stats != nil {
+		if err == nil {
+			opts.Stats.addRow(row, n)
+		} else if errors.Is(err, ErrUnsupportedType) {
+			opts.Stats.addUnsupported()
+		}
+	}
+	return row, n, err
+}
+
+func decodeBinaryRow(data []byte, columns []*mysql.ColumnDefinition41, opts *DecodeOptions) (*mysql.BinaryRow, int, error) {
+	if opts.WithoutHeader {
+		if len(data) < 1 {
+			return nil, 0, &DecodeError{Offset: 0, Column: -1, Msg: "payload too short"}
+		}
+		if err := checkRowSize(len(data), opts.maxRowBytes(), 0); err != nil {
+			return nil, 0, err
+		}
+		if isResultSetTerminator(data[0], uint32(len(data))) {
+			return nil, 0, ErrResultSetEnd
+		}
+		row, n, err := decodeBinaryRowPayload(data, columns, 0, opts)
+		if err == nil {
+			err = verifyConsumed(n, len(data), 0, opts.VerifyLength)
+		}
+		if err != nil {
+			return nil, n, err
+		}
+		return row, n, nil
+	}
+
+	if len(data) < 5 {
+		return nil, 0, &DecodeError{Offset: 0, Column: -1, Msg: "packet too short"}
+	}
+
+	header := mysql.Header{
+		PayloadLength: utils.ReadUint24(data[:3]),
+		SequenceID:    data[3],
+	}
+
+	if isResultSetTerminator(data[4], header.PayloadLength) {
+		return nil, 4, ErrResultSetEnd
+	}
+
+	if err := checkRowSize(int(header.PayloadLength), opts.maxRowBytes(), 0); err != nil {
+		return nil, 0, err
+	}
+
+	if header.PayloadLength == maxPacketPayload {
+		// the row is split across several packets, reassemble it before decoding
+		payload, n, err := readMultiPacketPayload(data, opts.maxRowBytes())
+		if err != nil {
+			return nil, n, &DecodeError{Offset: n, Column: -1, Msg: err.Error(), err: err}
+		}
+		row, consumed, err := decodeBinaryRowPayload(payload, columns, 0, opts)
+		if err == nil {
+			err = verifyConsumed(consumed, len(payload), 0, opts.VerifyLength)
+		}
+		if err != nil {
+			return nil, n, err
+		}
+		row.Header = header
+		return row, n, nil
+	}
+
+	payload := data[4:]
+	if opts.VerifyLength {
+		if int(header.PayloadLength) > len(payload) {
+			return nil, 4, &DecodeError{Offset: 4, Column: -1, Msg: fmt.Sprintf("payload length %d exceeds the %d bytes available", header.PayloadLength, len(payload))}
+		}
+		payload = payload[:header.PayloadLength]
+	}
+
+	row, n, err := decodeBinaryRowPayload(payload, columns, 4, opts)
+	if err == nil {
+		err = verifyConsumed(n, int(header.PayloadLength), 4, opts.VerifyLength)
+	}
+	if err != nil {
+		return nil, 4 + n, err
+	}
+	row.Header = header
+	return row, 4 + n, nil
+}
+
+// decodeBinaryRowPayload decodes the row payload, base is added to the offsets reported in
+// errors so that they point into the packet the payload was taken from.
+func decodeBinaryRowPayload(payload []byte, columns []*mysql.ColumnDefinition41, base int, opts *DecodeOptions) (*mysql.BinaryRow, int, error) {
+	offset := 0
+	row := &mysql.BinaryRow{}
+
+	if len(payload) == 0 {
+		return nil, offset, &DecodeError{Offset: base, Column: -1, Msg: "empty payload"}
+	}
+	if payload[offset] != 0x00 {
+		return nil, offset, &DecodeError{Offset: base + offset, Column: -1, Msg: fmt.Sprintf("unexpected packet header %#x", payload[offset])}
 	}
 	row.OkAfterRow = true
 	offset++
 
-	nullBitmapLen := (len(columns) + 7 + 2) / 8
-	nullBitmap := data[offset : offset+nullBitmapLen]
+	// NULL bitmap of (column_count + 7 + 2) / 8 bytes, the first 2 bits are reserved for resultset rows
+	nullBitmapLen := (len(columns) + 7 + resultSetNullBitmapOffset) / 8
+	if len(payload) < offset+nullBitmapLen {
+		return nil, offset, &DecodeError{Offset: base + offset, Column: -1, Msg: "truncated null bitmap"}
+	}
+	// copy the bitmap so that the row doesn't keep the packet buffer alive
+	nullBitmap := make([]byte, nullBitmapLen)
+	copy(nullBitmap, payload[offset:offset+nullBitmapLen])
 	row.RowNullBuffer = nullBitmap
 
 	offset += nullBitmapLen
 
+	// presize the values, wide rows would otherwise grow the slice repeatedly
+	row.Values = make([]mysql.ColumnEntry, 0, len(columns))
+
+	if allNullAt(nullBitmap, len(columns), resultSetNullBitmapOffset) {
+		// nothing follows the bitmap, no need to look at the column types
+		for _, col := range columns {
+			row.Values = append(row.Values, mysql.ColumnEntry{Type: mysql.FieldType(col.Type), Name: col.Name})
+		}
+		return row, offset, nil
+	}
+
 	for i, col := range columns {
-		if isNull(nullBitmap, i) { // This Null doesn't progress the offset
+		if isNullAt(nullBitmap, i, resultSetNullBitmapOffset) { // This Null doesn't progress the offset
 			row.Values = append(row.Values, mysql.ColumnEntry{
 				Type:  mysql.FieldType(col.Type),
 				Name:  col.Name,
@@ -51,43 +378,275 @@ func DecodeBinaryRow(_ context.Context, _ *zap.Logger, data []byte, columns []*m
 			continue
 		}
 
-		res, n, err := readBinaryValue(data[offset:], col)
+		var (
+			value interface{}
+			n     int
+			err   error
+		)
+		if opts.ZeroCopy && isByteViewType(mysql.FieldType(col.Type)) {
+			value, n, err = readByteView(payload[offset:])
+		} else {
+			value, n, err = DecodeColumnValue(payload[offset:], col)
+		}
+		if errors.Is(err, ErrUnsupportedType) && opts.CaptureUnsupported {
+			var raw []byte
+			raw, n, err = readOpaqueValue(payload[offset:], mysql.FieldType(col.Type))
+			if err == nil {
+				row.Values = append(row.Values, mysql.ColumnEntry{
+					Type:   mysql.FieldType(col.Type),
+					Name:   col.Name,
+					Value:  raw,
+					Opaque: true,
+				})
+				offset += n
+				continue
+			}
+		}
 		if err != nil {
-			return nil, offset, err
+			return nil, offset, &DecodeError{Offset: base + offset, Column: i, Msg: err.Error(), err: err}
+		}
+		if opts.omits(col) && isLengthEncodedType(mysql.FieldType(col.Type)) {
+			row.Values = append(row.Values, mysql.ColumnEntry{
+				Type:     mysql.FieldType(col.Type),
+				Name:     col.Name,
+				Value:    valueDigest(payload[offset : offset+n]),
+				Unsigned: col.Flags&mysql.UNSIGNED_FLAG != 0,
+				Omitted:  true,
+			})
+			offset += n
+			continue
+		}
+		if labels, ok := enumLabels(opts.EnumLabels, col); ok && value != nil {
+			value, err = enumOrdinal(col, labels, value)
+			if err != nil {
+				return nil, offset, &DecodeError{Offset: base + offset, Column: i, Msg: err.Error(), err: err}
+			}
+		}
+		var raw []byte
+		if opts.Transformer != nil {
+			value = opts.Transformer(col, value)
+		} else if isFloatType(mysql.FieldType(col.Type)) && (opts.RawFloats || isNegativeZero(value)) {
+			raw = make([]byte, n)
+			copy(raw, payload[offset:offset+n])
 		}
 
 		row.Values = append(row.Values, mysql.ColumnEntry{
 			Type:     mysql.FieldType(col.Type),
 			Name:     col.Name,
-			Value:    res.value,
-			Unsigned: res.isUnsigned,
+			Value:    value,
+			Unsigned: col.Flags&mysql.UNSIGNED_FLAG != 0,
+			Raw:      raw,
 		})
 		offset += n
 	}
 	return row, offset, nil
 }
 
-func isNull(nullBitmap []byte, index int) bool {
-	bytePos := (index + 2) / 8
-	bitPos := (index + 2) % 8
+// isByteViewType reports whether the values of the field type are length-encoded strings
+// that DecodeOptions.ZeroCopy returns as views into the packet.
+func isByteViewType(ft mysql.FieldType) bool {
+	switch ft {
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeJSON, mysql.FieldTypeEnum, mysql.FieldTypeSet,
+		mysql.FieldTypeBit, mysql.FieldTypeGeometry:
+		return true
+	default:
+		return false
+	}
+}
+
+// readByteView reads the length-encoded string at the start of data and returns it as a
+// view into data, or nil for the NULL marker.
+func readByteView(data []byte) (interface{}, int, error) {
+	value, isNull, n, err := utils.ReadLengthEncodedString(data)
+	if err != nil || isNull {
+		return nil, n, err
+	}
+	return value[:len(value):len(value)], n, nil
+}
+
+// isSplitRow reports whether data holds a row split across several packets, the offsets of
+// its errors are within the reassembled payload rather than data.
+func isSplitRow(data []byte, opts *DecodeOptions) bool {
+	return !opts.WithoutHeader && len(data) >= 4 && utils.ReadUint24(data[:3]) == maxPacketPayload
+}
+
+// verifyConsumed returns a DecodeError wrapping ErrColumnCountMismatch when the columns left
+// bytes of the payload over and, if strict is set, one when they consumed more than the
+// payload length.
+func verifyConsumed(consumed, payloadLength, base int, strict bool) error {
+	if consumed < payloadLength {
+		return &DecodeError{
+			Offset: base + consumed,
+			Column: -1,
+			Msg:    fmt.Sprintf("%d of the %d payload bytes left over after the last column, the row likely has more columns than were passed", payloadLength-consumed, payloadLength),
+			err:    ErrColumnCountMismatch,
+		}
+	}
+	if consumed == payloadLength || !strict {
+		return nil
+	}
+	return &DecodeError{
+		Offset: base + consumed,
+		Column: -1,
+		Msg:    fmt.Sprintf("columns consumed %d bytes of the %d byte payload, overrunning it by %d bytes", consumed, payloadLength, consumed-payloadLength),
+	}
+}
+
+// DecodeCompressedBinaryRow decodes a binary row sent over a compressed (CLIENT_COMPRESS) session.
+// The packet is inflated first, the returned length is the size of the compressed packet consumed.
+func DecodeCompressedBinaryRow(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) (*mysql.BinaryRow, int, error) {
+	packet, err := utils.DecompressPacket(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decompress binary row packet: %w", err)
+	}
+	row, _, err := DecodeBinaryRow(ctx, logger, packet, columns)
+	if err != nil {
+		return nil, 0, err
+	}
+	return row, utils.CompressedHeaderLength + int(utils.ReadUint24(data[:3])), nil
+}
+
+// isResultSetTerminator reports whether a payload starting with header and of the given
+// length is the EOF packet (payload shorter than 9 bytes) or, when CLIENT_DEPRECATE_EOF is
+// set, the OK packet with the 0xfe header that ends the rows. Binary rows always start with
+// 0x00 so the only other 0xfe packet would be a full 0xffffff sized one, which is a
+// multi-packet row.
+func isResultSetTerminator(header byte, payloadLength uint32) bool {
+	return header == mysql.EOF && payloadLength < 0xFFFFFF
+}
+
+// BuildNullBitmap returns the NULL bitmap of a binary resultset row in which column i is
+// NULL when isNull[i] is set. The bitmap takes (len(isNull) + 7 + 2) / 8 bytes, the first
+// 2 bits being reserved, as expected in mysql.BinaryRow.RowNullBuffer.
+func BuildNullBitmap(isNull []bool) []byte {
+	return buildNullBitmapAt(isNull, resultSetNullBitmapOffset)
+}
+
+// buildNullBitmapAt builds a NULL bitmap whose first offset bits are reserved.
+func buildNullBitmapAt(isNull []bool, offset int) []byte {
+	nullBitmap := make([]byte, (len(isNull)+7+offset)/8)
+	for i, null := range isNull {
+		if null {
+			nullBitmap[(i+offset)/8] |= 1 << ((i + offset) % 8)
+		}
+	}
+	return nullBitmap
+}
+
+// nullBitmapFromValues builds the NULL bitmap of a resultset row, marking every nil value as NULL.
+func nullBitmapFromValues(values []mysql.ColumnEntry) []byte {
+	nulls := make([]bool, len(values))
+	for i, v := range values {
+		nulls[i] = v.Value == nil
+	}
+	return BuildNullBitmap(nulls)
+}
+
+// ValidateBinaryRow checks that data holds a well-formed binary row for the given columns
+// without panicking on truncated or malformed input.
+func ValidateBinaryRow(data []byte, columns []*mysql.ColumnDefinition41) error {
+	if len(data) < 4 {
+		return errors.New("malformed binary row packet: missing packet header")
+	}
+	payloadLength := utils.ReadUint24(data[:3])
+	if int(payloadLength) > len(data)-4 {
+		return fmt.Errorf("malformed binary row packet: payload length %d exceeds available %d bytes", payloadLength, len(data)-4)
+	}
+	_, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), data[:4+payloadLength], columns)
+	return err
+}
+
+// The NULL bitmap of resultset rows starts with 2 reserved bits, the one of COM_STMT_EXECUTE
+// parameters with none.
+const (
+	resultSetNullBitmapOffset = 2
+	paramsNullBitmapOffset    = 0
+)
+
+// allNullAt reports whether the bitmap flags all of the count columns as NULL.
+func allNullAt(nullBitmap []byte, count, offset int) bool {
+	if count == 0 {
+		return false
+	}
+	for i := 0; i < count; i++ {
+		if !isNullAt(nullBitmap, i, offset) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNullAt reports whether the value at index is marked NULL in a bitmap whose first offset
+// bits are reserved.
+func isNullAt(nullBitmap []byte, index, offset int) bool {
+	bytePos := (index + offset) / 8
+	bitPos := (index + offset) % 8
 	return nullBitmap[bytePos]&(1<<bitPos) != 0
 }
 
+// GoTypeFor returns the Go type that a binary protocol value of the given field type is
+// decoded into, which is also the type EncodeBinaryRow expects back. It returns nil for
+// NULL typed columns, which carry no value, and for unsupported field types. String and
+// blob values that aren't valid UTF-8, and binary JSON values, are decoded into []byte
+// instead of string.
+func GoTypeFor(ft mysql.FieldType, unsigned bool) reflect.Type {
+	switch baseFieldType(ft) {
+	case mysql.FieldTypeTiny:
+		if unsigned {
+			return reflect.TypeOf(uint8(0))
+		}
+		return reflect.TypeOf(int8(0))
+	case mysql.FieldTypeShort, mysql.FieldTypeYear:
+		if unsigned {
+			return reflect.TypeOf(uint16(0))
+		}
+		return reflect.TypeOf(int16(0))
+	case mysql.FieldTypeInt24, mysql.FieldTypeLong:
+		if unsigned {
+			return reflect.TypeOf(uint32(0))
+		}
+		return reflect.TypeOf(int32(0))
+	case mysql.FieldTypeLongLong:
+		if unsigned {
+			return reflect.TypeOf(uint64(0))
+		}
+		return reflect.TypeOf(int64(0))
+	case mysql.FieldTypeFloat:
+		return reflect.TypeOf(float32(0))
+	case mysql.FieldTypeDouble:
+		return reflect.TypeOf(float64(0))
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeJSON, mysql.FieldTypeEnum, mysql.FieldTypeSet,
+		mysql.FieldTypeDecimal, mysql.FieldTypeNewDecimal,
+		mysql.FieldTypeDate, mysql.FieldTypeNewDate, mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime, mysql.FieldTypeTime:
+		return reflect.TypeOf("")
+	case mysql.FieldTypeBit, mysql.FieldTypeGeometry:
+		return reflect.TypeOf([]byte(nil))
+	default:
+		return nil
+	}
+}
+
+// DecodeColumnValue decodes the binary protocol value of the column at the start of data,
+// the value of a resultset row or of a COM_STMT_EXECUTE parameter. It returns the value, of
+// the Go type GoTypeFor reports for the column, and the number of bytes it takes. Errors for
+// field types that can't be decoded wrap ErrUnsupportedType.
+func DecodeColumnValue(data []byte, col *mysql.ColumnDefinition41) (interface{}, int, error) {
+	res, n, err := readBinaryValue(data, col)
+	return res.value, n, err
+}
+
 type binaryValueResult struct {
-	value      interface{}
-	isUnsigned bool
+	value interface{}
 }
 
-func readBinaryValue(data []byte, col *mysql.ColumnDefinition41) (*binaryValueResult, int, error) {
+func readBinaryValue(data []byte, col *mysql.ColumnDefinition41) (binaryValueResult, int, error) {
 	isUnsigned := col.Flags&mysql.UNSIGNED_FLAG != 0
-	res := &binaryValueResult{
-		isUnsigned: isUnsigned,
-	}
+	res := binaryValueResult{}
 
-	switch mysql.FieldType(col.Type) {
+	switch baseFieldType(mysql.FieldType(col.Type)) {
 	case mysql.FieldTypeLong:
 		if len(data) < 4 {
-			return nil, 0, errors.New("malformed FieldTypeLong value")
+			return res, 0, errors.New("malformed FieldTypeLong value")
 		}
 		if isUnsigned {
 			res.value = uint32(binary.LittleEndian.Uint32(data[:4]))
@@ -96,12 +655,101 @@ func readBinaryValue(data []byte, col *mysql.ColumnDefinition41) (*binaryValueRe
 		res.value = int32(binary.LittleEndian.Uint32(data[:4]))
 		return res, 4, nil
 
-	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeJSON:
-		value, _, n, err := utils.ReadLengthEncodedString(data)
+	case mysql.FieldTypeNULL:
+		// NULL typed columns carry no value bytes, they are reported through the null bitmap
+		res.value = nil
+		return res, 0, nil
+
+	case mysql.FieldTypeInt24:
+		// MEDIUMINT is 3 bytes logically but is sent as 4 bytes on the wire
+		if len(data) < 4 {
+			return res, 0, errors.New("malformed FieldTypeInt24 value")
+		}
+		if isUnsigned {
+			res.value = uint32(binary.LittleEndian.Uint32(data[:4]))
+			return res, 4, nil
+		}
+		res.value = int32(binary.LittleEndian.Uint32(data[:4]))
+		return res, 4, nil
+
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeEnum, mysql.FieldTypeSet:
+		value, isNull, n, err := utils.ReadLengthEncodedString(data)
+		if err != nil {
+			return res, n, err
+		}
+		if isNull {
+			// keep the NULL marker as nil rather than turning it into an empty string
+			res.value = nil
+			return res, n, nil
+		}
+		if !utf8.Valid(value) {
+			// latin1, gbk, utf16 etc. text and binary blobs aren't valid UTF-8, keep the
+			// raw bytes so that they survive being stored in the mocks unchanged
+			raw := make([]byte, len(value))
+			copy(raw, value)
+			res.value = raw
+			return res, n, nil
+		}
+		// an empty value (0x00 length) stays an empty string, distinct from the nil of NULL
 		res.value = string(value)
-		return res, n, err
+		return res, n, nil
+
+	case mysql.FieldTypeJSON:
+		// JSON is sent in its text form, keep the server's exact formatting (whitespace, key order)
+		// so that it is written back byte for byte
+		value, isNull, n, err := utils.ReadLengthEncodedString(data)
+		if err != nil {
+			return res, n, err
+		}
+		if isNull {
+			res.value = nil
+			return res, n, nil
+		}
+		if !utf8.Valid(value) || bytes.IndexByte(value, 0x00) >= 0 {
+			// JSON text never contains a raw NUL, this is the binary JSON representation
+			// some modes send, keep the bytes instead of corrupting them as a string
+			raw := make([]byte, len(value))
+			copy(raw, value)
+			res.value = raw
+			return res, n, nil
+		}
+		res.value = string(value)
+		return res, n, nil
+
+	case mysql.FieldTypeDecimal, mysql.FieldTypeNewDecimal:
+		// DECIMAL values are sent as length-encoded strings; keep the textual form as-is
+		// so that precision, sign and trailing zeros survive the round trip.
+		value, isNull, n, err := utils.ReadLengthEncodedString(data)
+		if err != nil {
+			return res, n, err
+		}
+		if isNull {
+			res.value = nil
+			return res, n, nil
+		}
+		res.value = string(value)
+		return res, n, nil
+
+	case mysql.FieldTypeBit, mysql.FieldTypeGeometry:
+		// BIT values are the big-endian bytes of the bit field and GEOMETRY values are
+		// a 4-byte SRID followed by WKB, neither of them is text so keep the raw bytes.
+		value, isNull, n, err := utils.ReadLengthEncodedString(data)
+		if err != nil {
+			return res, n, err
+		}
+		if isNull {
+			res.value = nil
+			return res, n, nil
+		}
+		raw := make([]byte, len(value))
+		copy(raw, value)
+		res.value = raw
+		return res, n, nil
 
 	case mysql.FieldTypeTiny:
+		if len(data) < 1 {
+			return res, 0, errors.New("malformed FieldTypeTiny value")
+		}
 		if isUnsigned {
 			res.value = uint8(data[0])
 			return res, 1, nil
@@ -109,9 +757,22 @@ func readBinaryValue(data []byte, col *mysql.ColumnDefinition41) (*binaryValueRe
 		res.value = int8(data[0])
 		return res, 1, nil
 
-	case mysql.FieldTypeShort, mysql.FieldTypeYear:
+	case mysql.FieldTypeShort:
+		if len(data) < 2 {
+			return res, 0, errors.New("malformed FieldTypeShort value")
+		}
+		if isUnsigned {
+			res.value = uint16(binary.LittleEndian.Uint16(data[:2]))
+			return res, 2, nil
+		}
+		res.value = int16(binary.LittleEndian.Uint16(data[:2]))
+		return res, 2, nil
+
+	case mysql.FieldTypeYear:
+		// YEAR is sent as int<2> in the binary protocol (not a single byte),
+		// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_binary_resultset.html#sect_protocol_binary_resultset_row_value
 		if len(data) < 2 {
-			return nil, 0, errors.New("malformed FieldTypeShort value")
+			return res, 0, errors.New("malformed FieldTypeYear value")
 		}
 		if isUnsigned {
 			res.value = uint16(binary.LittleEndian.Uint16(data[:2]))
@@ -122,7 +783,7 @@ func readBinaryValue(data []byte, col *mysql.ColumnDefinition41) (*binaryValueRe
 
 	case mysql.FieldTypeLongLong:
 		if len(data) < 8 {
-			return nil, 0, errors.New("malformed FieldTypeLongLong value")
+			return res, 0, errors.New("malformed FieldTypeLongLong value")
 		}
 		if isUnsigned {
 			res.value = uint64(binary.LittleEndian.Uint64(data[:8]))
@@ -133,45 +794,55 @@ func readBinaryValue(data []byte, col *mysql.ColumnDefinition41) (*binaryValueRe
 
 	case mysql.FieldTypeFloat:
 		if len(data) < 4 {
-			return nil, 0, errors.New("malformed FieldTypeFloat value")
+			return res, 0, errors.New("malformed FieldTypeFloat value")
 		}
-		res.value = float32(binary.LittleEndian.Uint32(data[:4]))
+		res.value = math.Float32frombits(binary.LittleEndian.Uint32(data[:4]))
 		return res, 4, nil
 
 	case mysql.FieldTypeDouble:
 		if len(data) < 8 {
-			return nil, 0, errors.New("malformed FieldTypeDouble value")
+			return res, 0, errors.New("malformed FieldTypeDouble value")
 		}
-		res.value = float64(binary.LittleEndian.Uint64(data[:8]))
+		res.value = math.Float64frombits(binary.LittleEndian.Uint64(data[:8]))
 		return res, 8, nil
 
 	case mysql.FieldTypeDate, mysql.FieldTypeNewDate:
+		// NEWDATE is the packed 3 byte storage format of DATE columns, it is internal to the
+		// server which sends such columns as DATE, so it never reaches the protocol in that form.
+		// It is decoded like DATE for peers that send the type id anyway.
 		value, n, err := parseBinaryDate(data)
 		res.value = value
 		return res, n, err
 
 	case mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime:
-		value, n, err := parseBinaryDateTime(data)
+		value, n, err := parseBinaryDateTime(data, col.Decimals)
 		res.value = value
 		return res, n, err
 
 	case mysql.FieldTypeTime:
-		value, n, err := parseBinaryTime(data)
+		value, n, err := parseBinaryTime(data, col.Decimals)
 		res.value = value
 		return res, n, err
 
 	default:
-		return nil, 0, fmt.Errorf("unsupported column type: %v", col.Type)
+		return res, 0, fmt.Errorf("%w: %v", ErrUnsupportedType, col.Type)
 	}
 }
 
 func parseBinaryDate(b []byte) (interface{}, int, error) {
 	if len(b) == 0 {
-		return nil, 0, nil
+		return nil, 0, errors.New("malformed FieldTypeDate value: missing length byte")
 	}
 	length := b[0]
 	if length == 0 {
-		return nil, 1, nil
+		// zero date, NULL is signalled through the null bitmap instead
+		return "0000-00-00", 1, nil
+	}
+	if length < 4 {
+		return nil, 0, fmt.Errorf("malformed FieldTypeDate value: invalid length %d", length)
+	}
+	if len(b) < int(length)+1 {
+		return nil, 0, fmt.Errorf("malformed FieldTypeDate value: expected %d bytes, got %d", int(length)+1, len(b))
 	}
 	year := binary.LittleEndian.Uint16(b[1:3])
 	month := b[3]
@@ -179,56 +850,160 @@ func parseBinaryDate(b []byte) (interface{}, int, error) {
 	return fmt.Sprintf("%04d-%02d-%02d", year, month, day), int(length) + 1, nil
 }
 
-func parseBinaryDateTime(b []byte) (interface{}, int, error) {
+func parseBinaryDateTime(b []byte, decimals byte) (interface{}, int, error) {
 	if len(b) == 0 {
-		return nil, 0, nil
+		return nil, 0, errors.New("malformed FieldTypeDateTime value: missing length byte")
 	}
 	length := b[0]
 	if length == 0 {
-		return nil, 1, nil
+		// zero datetime allowed in non-strict mode, NULL is signalled through the null bitmap instead
+		return "0000-00-00 00:00:00", 1, nil
+	}
+	if length != 4 && length != 7 && length != 11 {
+		return nil, 0, fmt.Errorf("malformed FieldTypeDateTime value: invalid length %d", length)
+	}
+	if len(b) < int(length)+1 {
+		return nil, 0, fmt.Errorf("malformed FieldTypeDateTime value: expected %d bytes, got %d", int(length)+1, len(b))
 	}
 	year := binary.LittleEndian.Uint16(b[1:3])
 	month := b[3]
 	day := b[4]
+	if length == 4 {
+		// date only, the time part is 00:00:00
+		return fmt.Sprintf("%04d-%02d-%02d 00:00:00", year, month, day), int(length) + 1, nil
+	}
 	hour := b[5]
 	minute := b[6]
 	second := b[7]
 	if length > 7 {
 		microsecond := binary.LittleEndian.Uint32(b[8:12])
-		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d.%06d", year, month, day, hour, minute, second, microsecond), int(length) + 1, nil
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d%s", year, month, day, hour, minute, second, formatFraction(microsecond, decimals)), int(length) + 1, nil
 	}
 	return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, month, day, hour, minute, second), int(length) + 1, nil
 }
 
-func parseBinaryTime(b []byte) (interface{}, int, error) {
+func parseBinaryTime(b []byte, decimals byte) (interface{}, int, error) {
 	if len(b) == 0 {
-		return nil, 0, nil
+		return nil, 0, errors.New("malformed FieldTypeTime value: missing length byte")
 	}
 	length := b[0]
 	if length == 0 {
-		return nil, 1, nil
+		return "0 00:00:00", 1, nil
+	}
+	if length != 8 && length != 12 {
+		return nil, 0, fmt.Errorf("malformed FieldTypeTime value: invalid length %d", length)
+	}
+	if len(b) < int(length)+1 {
+		return nil, 0, fmt.Errorf("malformed FieldTypeTime value: expected %d bytes, got %d", int(length)+1, len(b))
 	}
 	isNegative := b[1] == 1
 	days := binary.LittleEndian.Uint32(b[2:6])
 	hours := b[6]
 	minutes := b[7]
 	seconds := b[8]
-	var microseconds uint32
+	// only emit the fractional part when it is present on the wire so that encodeTime
+	// writes back the same length
+	timeString := fmt.Sprintf("%d %02d:%02d:%02d", days, hours, minutes, seconds)
 	if length > 8 {
-		microseconds = binary.LittleEndian.Uint32(b[9:13])
+		microseconds := binary.LittleEndian.Uint32(b[9:13])
+		timeString += formatFraction(microseconds, decimals)
 	}
-	timeString := fmt.Sprintf("%d %02d:%02d:%02d.%06d", days, hours, minutes, seconds, microseconds)
 	if isNegative {
 		timeString = "-" + timeString
 	}
 	return timeString, int(length) + 1, nil
 }
 
-func EncodeBinaryRow(_ context.Context, logger *zap.Logger, row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41) ([]byte, error) {
-	buf := new(bytes.Buffer)
+// formatFraction formats the fractional seconds of a temporal value with the precision
+// declared in the column definition (e.g. DATETIME(3)), using all 6 digits when the
+// precision is not fixed.
+func formatFraction(microseconds uint32, decimals byte) string {
+	if decimals == 0 || decimals > 6 {
+		return fmt.Sprintf(".%06d", microseconds)
+	}
+	for i := decimals; i < 6; i++ {
+		microseconds /= 10
+	}
+	return fmt.Sprintf(".%0*d", int(decimals), microseconds)
+}
+
+// parseFraction converts the digits after the decimal point of a temporal value
+// into microseconds, e.g. "123" -> 123000.
+func parseFraction(frac string) (int, error) {
+	if len(frac) == 0 || len(frac) > 6 {
+		return 0, fmt.Errorf("invalid fractional seconds %q", frac)
+	}
+	microseconds, err := strconv.Atoi(frac + strings.Repeat("0", 6-len(frac)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid fractional seconds %q: %w", frac, err)
+	}
+	return microseconds, nil
+}
+
+// binaryRowBufferPool holds the buffers used by EncodeBinaryRow, replaying large resultsets
+// encodes many rows and reusing the buffers keeps the allocations down.
+var binaryRowBufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// EncodeOptions controls how EncodeBinaryRowWithOptions writes a row. Length-encoded values
+// are always written with the smallest valid prefix (see utils.WriteLengthEncodedInteger), so
+// the encoding is canonical and there is no option for it: a row is encoded the same way
+// whatever prefix class the server used, which may differ from the recorded bytes when the
+// server picked a longer, still valid, prefix.
+type EncodeOptions struct {
+	// RecomputeNullBitmap rebuilds the NULL bitmap from the values (a nil value is NULL),
+	// which keeps hand-edited rows consistent. When unset an error is returned if the stored
+	// bitmap doesn't match the values.
+	RecomputeNullBitmap bool
+	// ConformDecimalScale pads DECIMAL values with zeros to the scale declared by the column
+	// (e.g. "10" is written as "10.00" for DECIMAL(p,2)) and rejects values with more
+	// significant fractional digits than that. When unset the stored string is written as is.
+	ConformDecimalScale bool
+	// Clock, if set, replaces the non-NULL values of the TIMESTAMP and DATETIME columns set
+	// from the current time by the time it returns, so that a replayed row carries a value
+	// that tracks the replay instead of the recording (see FixedClock and OffsetClock). The
+	// columns are the ones flagged ON_UPDATE_NOW_FLAG (ON UPDATE CURRENT_TIMESTAMP) and the
+	// ones named in ClockColumns, the server doesn't flag columns whose default is
+	// CURRENT_TIMESTAMP.
+	Clock func() time.Time
+	// ClockColumns names the columns, besides the ON_UPDATE_NOW_FLAG ones, whose values are
+	// replaced by the time of Clock. Names are matched case-insensitively.
+	ClockColumns []string
+	// EnumLabels maps the names of ENUM and SET columns to their labels in definition order,
+	// like DecodeOptions.EnumLabels. Integer values of these columns are taken as ordinals
+	// and written as the label (or members) they stand for, string values as they are. An
+	// integer value of a column without labels fails the encode.
+	EnumLabels map[string][]string
+}
+
+// EncodeBinaryRow encodes the row as a binary resultset row packet. When recomputeNullBitmap is set
+// the NULL bitmap is rebuilt from the values (a nil value is NULL), which keeps hand-edited rows
+// consistent, otherwise an error is returned if the stored bitmap doesn't match the values.
+func EncodeBinaryRow(ctx context.Context, logger *zap.Logger, row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41, recomputeNullBitmap bool) ([]byte, error) {
+	return EncodeBinaryRowWithOptions(ctx, logger, row, columns, EncodeOptions{RecomputeNullBitmap: recomputeNullBitmap})
+}
+
+// EncodeBinaryRowWithOptions encodes the row as a binary resultset row packet according to opts.
+func EncodeBinaryRowWithOptions(_ context.Context, logger *zap.Logger, row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41, opts EncodeOptions) ([]byte, error) {
+	if len(row.Values) != len(columns) {
+		return nil, fmt.Errorf("binary row has %d values but the resultset has %d columns", len(row.Values), len(columns))
+	}
+
+	nullBitmap := nullBitmapFromValues(row.Values)
+	if !opts.RecomputeNullBitmap && !bytes.Equal(nullBitmap, row.RowNullBuffer) {
+		return nil, fmt.Errorf("null bitmap %#v doesn't match the row values, expected %#v", row.RowNullBuffer, nullBitmap)
+	}
+
+	buf := binaryRowBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer binaryRowBufferPool.Put(buf)
 
-	// Write the packet header
-	if err := utils.WriteUint24(buf, row.Header.PayloadLength); err != nil {
+	// Write the packet header, the payload length is a placeholder that is filled
+	// once the row is encoded as the stored value may be stale if the row was modified
+	if err := utils.WriteUint24(buf, 0); err != nil {
 		return nil, fmt.Errorf("failed to write PayloadLength: %w", err)
 	}
 	if err := buf.WriteByte(row.Header.SequenceID); err != nil {
@@ -241,7 +1016,7 @@ func EncodeBinaryRow(_ context.Context, logger *zap.Logger, row *mysql.BinaryRow
 	}
 
 	// Write the row's NULL bitmap
-	if _, err := buf.Write(row.RowNullBuffer); err != nil {
+	if _, err := buf.Write(nullBitmap); err != nil {
 		return nil, fmt.Errorf("failed to write NULL bitmap: %w", err)
 	}
 
@@ -249,263 +1024,690 @@ func EncodeBinaryRow(_ context.Context, logger *zap.Logger, row *mysql.BinaryRow
 	for i, col := range columns {
 		logger.Debug("encoding column", zap.String("name", col.Name), zap.Any("value", row.Values[i].Value))
 
-		if isNull(row.RowNullBuffer, i) {
+		if isNullAt(nullBitmap, i, resultSetNullBitmapOffset) {
 			continue
 		}
 
-		columnEntry := row.Values[i]
+		if err := writeBinaryValue(buf, col, row.Values[i], &opts); err != nil {
+			return nil, err
+		}
+	}
 
-		switch columnEntry.Type {
-		case mysql.FieldTypeLong:
-			var val any
-			if columnEntry.Unsigned {
-				val = uint32(columnEntry.Value.(int))
-			} else {
-				val = int32(columnEntry.Value.(int))
-			}
-			if err := binary.Write(buf, binary.LittleEndian, val); err != nil {
-				return nil, fmt.Errorf("failed to write %T value: %w", val, err)
-			}
-		case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeJSON:
-			strValue, ok := columnEntry.Value.(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for string field")
-			}
-			if err := utils.WriteLengthEncodedString(buf, strValue); err != nil {
-				return nil, fmt.Errorf("failed to write length-encoded string: %w", err)
-			}
-		case mysql.FieldTypeTiny:
-			var val any
-			if columnEntry.Unsigned {
-				val = uint8(columnEntry.Value.(int))
-			} else {
-				val = int8(columnEntry.Value.(int))
-			}
-			if err := binary.Write(buf, binary.LittleEndian, val); err != nil {
-				return nil, fmt.Errorf("failed to write %T value: %w", val, err)
-			}
+	// rows that don't fit into a single packet are split into several ones
+	if buf.Len()-4 >= maxPacketPayload {
+		return splitPayload(buf.Bytes()[4:], row.Header.SequenceID), nil
+	}
 
-		case mysql.FieldTypeShort, mysql.FieldTypeYear:
-			var val any
-			if columnEntry.Unsigned {
-				val = uint16(columnEntry.Value.(int))
-			} else {
-				val = int16(columnEntry.Value.(int))
-			}
-			if err := binary.Write(buf, binary.LittleEndian, val); err != nil {
-				return nil, fmt.Errorf("failed to write int16 value: %w", err)
-			}
-		case mysql.FieldTypeLongLong:
-			var val any
-			if columnEntry.Unsigned {
-				val = uint64(columnEntry.Value.(int))
-			} else {
-				val = int64(columnEntry.Value.(int))
-			}
+	// Copy the row out of the pooled buffer and fill in the actual payload length
+	packet := make([]byte, buf.Len())
+	copy(packet, buf.Bytes())
+	payloadLength := len(packet) - 4
+	packet[0] = byte(payloadLength)
+	packet[1] = byte(payloadLength >> 8)
+	packet[2] = byte(payloadLength >> 16)
 
-			if err := binary.Write(buf, binary.LittleEndian, val); err != nil {
-				return nil, fmt.Errorf("failed to write %T value: %w", val, err)
-			}
-		case mysql.FieldTypeFloat:
-			floatValue, ok := columnEntry.Value.(float32)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for float field")
-			}
-			if err := binary.Write(buf, binary.LittleEndian, floatValue); err != nil {
-				return nil, fmt.Errorf("failed to write float32 value: %w", err)
-			}
-		case mysql.FieldTypeDouble:
-			doubleValue, ok := columnEntry.Value.(float64)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for double field")
-			}
-			if err := binary.Write(buf, binary.LittleEndian, doubleValue); err != nil {
-				return nil, fmt.Errorf("failed to write float64 value: %w", err)
-			}
-		case mysql.FieldTypeDate, mysql.FieldTypeNewDate, mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime, mysql.FieldTypeTime:
-			dateTimeBytes, err := encodeBinaryDateTime(row.Values[i].Type, columnEntry.Value)
-			if err != nil {
-				return nil, fmt.Errorf("failed to encode date/time value: %w", err)
+	return packet, nil
+}
+
+// writeBinaryValue writes the non-NULL value of a column in its binary protocol encoding,
+// which is shared by resultset rows and COM_STMT_EXECUTE parameters.
+func writeBinaryValue(buf *bytes.Buffer, col *mysql.ColumnDefinition41, columnEntry mysql.ColumnEntry, opts *EncodeOptions) error {
+	if columnEntry.Opaque {
+		return writeOpaqueValue(buf, columnEntry.Type, columnEntry.Value)
+	}
+	if columnEntry.Omitted {
+		// the digest stands in for the value, whatever the value looked like
+		return writeOmittedValue(buf, col, columnEntry)
+	}
+	if raw, ok := rawFloat(columnEntry); ok {
+		if _, err := buf.Write(raw); err != nil {
+			return fmt.Errorf("failed to write raw %v value: %w", columnEntry.Type, err)
+		}
+		return nil
+	}
+
+	unsigned := columnEntry.Unsigned || col.Flags&mysql.UNSIGNED_FLAG != 0
+	value := columnEntry.Value
+	if opts.Clock != nil && opts.isClockColumn(col) {
+		value = clockValue(opts.Clock(), col.Decimals)
+	}
+	if isEnumColumn(col) && !isTextValue(value) {
+		labels, ok := enumLabels(opts.EnumLabels, col)
+		if !ok {
+			// the server sends the label, which can't be told from the ordinal alone
+			return fmt.Errorf("invalid value for column %s: ordinal %v without the labels of the column in EncodeOptions.EnumLabels", col.Name, value)
+		}
+		ordinal, err := uintFromValue(value)
+		if err == nil {
+			value, err = enumValue(col, labels, ordinal)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for column %s: %w", col.Name, err)
+		}
+	}
+	if ft := columnEntry.Type; opts.ConformDecimalScale && (ft == mysql.FieldTypeDecimal || ft == mysql.FieldTypeNewDecimal) {
+		decimalValue, err := textFromValue(value)
+		if err == nil {
+			value, err = conformDecimalScale(decimalValue, col.Decimals)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for column %s: %w", col.Name, err)
+		}
+	}
+
+	if err := EncodeColumnValue(buf, value, columnEntry.Type, unsigned); err != nil {
+		return fmt.Errorf("invalid value for column %s: %w", col.Name, err)
+	}
+	return nil
+}
+
+// writeOmittedValue writes the digest stored for a value left out when the row was recorded
+// (see DecodeOptions.ExcludeColumns) as a length-encoded string.
+func writeOmittedValue(buf *bytes.Buffer, col *mysql.ColumnDefinition41, columnEntry mysql.ColumnEntry) error {
+	if !isLengthEncodedType(columnEntry.Type) {
+		return fmt.Errorf("column %s of type %v can't hold the digest of an omitted value", col.Name, columnEntry.Type)
+	}
+	digest, ok := columnEntry.Value.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T for the digest of the omitted value of column %s", columnEntry.Value, col.Name)
+	}
+	if err := utils.WriteLengthEncodedString(buf, digest); err != nil {
+		return fmt.Errorf("failed to write the digest of the omitted value of column %s: %w", col.Name, err)
+	}
+	return nil
+}
+
+// EncodeColumnValue writes the non-NULL value in the binary protocol encoding of fieldType,
+// the inverse of DecodeColumnValue. The value is converted to the Go type DecodeColumnValue
+// returns for the field type first (see CoerceValue), an error is returned when that isn't
+// possible or the value is out of the range of the type. Errors for field types that can't
+// be encoded wrap ErrUnsupportedType.
+func EncodeColumnValue(buf *bytes.Buffer, value interface{}, fieldType mysql.FieldType, unsigned bool) error {
+	// the internal _2 temporal types are written like their base type
+	fieldType = baseFieldType(fieldType)
+
+	// values of mocks loaded from yaml or json may not have the exact type expected below
+	value, err := coerceValue(fieldType, unsigned, value)
+	if err != nil {
+		return err
+	}
+
+	// scratch space for the fixed width values
+	var scratch [8]byte
+
+	switch fieldType {
+	case mysql.FieldTypeTiny, mysql.FieldTypeShort, mysql.FieldTypeYear, mysql.FieldTypeInt24, mysql.FieldTypeLong, mysql.FieldTypeLongLong:
+		// MEDIUMINT is sent as 4 bytes and YEAR as 2 bytes, like INT and SMALLINT
+		width := integerWidth(fieldType)
+		if err := putInteger(scratch[:width], value, unsigned); err != nil {
+			return err
+		}
+		if _, err := buf.Write(scratch[:width]); err != nil {
+			return fmt.Errorf("failed to write integer value: %w", err)
+		}
+	case mysql.FieldTypeNULL:
+		// nothing to write, NULL typed columns have no value bytes
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeEnum, mysql.FieldTypeSet:
+		if value == nil {
+			// NULL marker inside the value section
+			if err := buf.WriteByte(0xfb); err != nil {
+				return fmt.Errorf("failed to write NULL marker: %w", err)
 			}
-			if _, err := buf.Write(dateTimeBytes); err != nil {
-				return nil, fmt.Errorf("failed to write date/time value: %w", err)
+			return nil
+		}
+		// non UTF-8 values are stored as raw bytes, write them back as they were received.
+		// Empty values ("", []byte{} or an empty list read back from yaml) are written with
+		// a 0x00 length so that they don't turn into NULL.
+		raw, err := bytesFromValue(value)
+		if err != nil {
+			return fmt.Errorf("invalid value type for string field: %w", err)
+		}
+		if err := utils.WriteLengthEncodedInteger(buf, uint64(len(raw))); err != nil {
+			return fmt.Errorf("failed to write length of string value: %w", err)
+		}
+		if _, err := buf.Write(raw); err != nil {
+			return fmt.Errorf("failed to write string value: %w", err)
+		}
+	case mysql.FieldTypeJSON:
+		if value == nil {
+			if err := buf.WriteByte(0xfb); err != nil {
+				return fmt.Errorf("failed to write NULL marker: %w", err)
 			}
-		default:
-			return nil, fmt.Errorf("unsupported column type: %v", row.Values[i].Type)
+			return nil
+		}
+		// written back as recorded, never re-marshaled, binary JSON is stored as raw bytes
+		jsonValue, err := bytesFromValue(value)
+		if err != nil {
+			return fmt.Errorf("invalid value type for json field: %w", err)
+		}
+		if err := utils.WriteLengthEncodedInteger(buf, uint64(len(jsonValue))); err != nil {
+			return fmt.Errorf("failed to write length of json value: %w", err)
+		}
+		if _, err := buf.Write(jsonValue); err != nil {
+			return fmt.Errorf("failed to write json value: %w", err)
+		}
+	case mysql.FieldTypeDecimal, mysql.FieldTypeNewDecimal:
+		decimalValue, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value type for decimal field")
+		}
+		if err := utils.WriteLengthEncodedString(buf, decimalValue); err != nil {
+			return fmt.Errorf("failed to write length-encoded decimal: %w", err)
+		}
+	case mysql.FieldTypeBit, mysql.FieldTypeGeometry:
+		raw, err := bytesFromValue(value)
+		if err != nil {
+			return fmt.Errorf("invalid value type for %v field: %w", fieldType, err)
+		}
+		if err := utils.WriteLengthEncodedInteger(buf, uint64(len(raw))); err != nil {
+			return fmt.Errorf("failed to write length of binary value: %w", err)
+		}
+		if _, err := buf.Write(raw); err != nil {
+			return fmt.Errorf("failed to write binary value: %w", err)
+		}
+	case mysql.FieldTypeFloat:
+		floatValue, ok := value.(float32)
+		if !ok {
+			return fmt.Errorf("invalid value type for float field")
+		}
+		binary.LittleEndian.PutUint32(scratch[:4], math.Float32bits(floatValue))
+		if _, err := buf.Write(scratch[:4]); err != nil {
+			return fmt.Errorf("failed to write float32 value: %w", err)
+		}
+	case mysql.FieldTypeDouble:
+		doubleValue, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("invalid value type for double field")
+		}
+		binary.LittleEndian.PutUint64(scratch[:8], math.Float64bits(doubleValue))
+		if _, err := buf.Write(scratch[:8]); err != nil {
+			return fmt.Errorf("failed to write float64 value: %w", err)
+		}
+	case mysql.FieldTypeDate, mysql.FieldTypeNewDate, mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime, mysql.FieldTypeTime:
+		if err := encodeBinaryDateTime(buf, fieldType, value); err != nil {
+			return fmt.Errorf("failed to encode date/time value: %w", err)
+		}
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedType, fieldType)
+	}
+	return nil
+}
+
+// conformDecimalScale rewrites the decimal string with exactly scale fractional digits,
+// padding with zeros. Trailing zeros beyond the scale are dropped but any other extra digit
+// is an error as it can't be represented by the column.
+func conformDecimalScale(value string, scale byte) (string, error) {
+	if scale > 30 {
+		// not a fixed scale (0x1f), nothing to conform to
+		return value, nil
+	}
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" || whole == "-" || whole == "+" {
+		return "", fmt.Errorf("invalid decimal value %q", value)
+	}
+	for _, c := range strings.TrimLeft(whole, "+-") + frac {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("invalid decimal value %q", value)
+		}
+	}
+	if len(frac) > int(scale) {
+		if strings.Trim(frac[scale:], "0") != "" {
+			return "", fmt.Errorf("decimal value %q has more than %d fractional digits", value, scale)
+		}
+		frac = frac[:scale]
+	}
+	if scale == 0 {
+		return whole, nil
+	}
+	return whole + "." + frac + strings.Repeat("0", int(scale)-len(frac)), nil
+}
+
+// isFloatType reports whether ft is FLOAT or DOUBLE.
+func isFloatType(ft mysql.FieldType) bool {
+	return ft == mysql.FieldTypeFloat || ft == mysql.FieldTypeDouble
+}
+
+// isNegativeZero reports whether the value is a FLOAT or DOUBLE -0, which the yaml mocks
+// store as "-0" and read back as the integer 0. Its raw bytes are always kept so that the
+// sign survives.
+func isNegativeZero(value interface{}) bool {
+	switch v := value.(type) {
+	case float32:
+		return v == 0 && math.Signbit(float64(v))
+	case float64:
+		return v == 0 && math.Signbit(v)
+	}
+	return false
+}
+
+// rawFloat returns the raw bytes of a FLOAT or DOUBLE value recorded with RawFloats, if they
+// have the width of the type.
+func rawFloat(columnEntry mysql.ColumnEntry) ([]byte, bool) {
+	switch {
+	case columnEntry.Type == mysql.FieldTypeFloat && len(columnEntry.Raw) == 4,
+		columnEntry.Type == mysql.FieldTypeDouble && len(columnEntry.Raw) == 8:
+		return columnEntry.Raw, true
+	}
+	return nil, false
+}
+
+// integerWidth returns the number of bytes an integer field type takes in the binary protocol.
+func integerWidth(ft mysql.FieldType) int {
+	switch ft {
+	case mysql.FieldTypeTiny:
+		return 1
+	case mysql.FieldTypeShort, mysql.FieldTypeYear:
+		return 2
+	case mysql.FieldTypeInt24, mysql.FieldTypeLong:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// putInteger writes value into b as a little-endian integer of len(b) bytes. The value is
+// read as signed or unsigned depending on the column and must fit into the width of b.
+func putInteger(b []byte, value interface{}, unsigned bool) error {
+	bits := uint(len(b)) * 8
+
+	var u uint64
+	if unsigned {
+		v, err := uintFromValue(value)
+		if err != nil {
+			return err
+		}
+		if bits < 64 && v > 1<<bits-1 {
+			return fmt.Errorf("value %d overflows %d-bit unsigned integer", v, bits)
+		}
+		u = v
+	} else {
+		v, err := intFromValue(value)
+		if err != nil {
+			return err
+		}
+		if bits < 64 && (v < -(1<<(bits-1)) || v > 1<<(bits-1)-1) {
+			return fmt.Errorf("value %d overflows %d-bit integer", v, bits)
 		}
+		u = uint64(v)
 	}
 
-	return buf.Bytes(), nil
+	for i := range b {
+		b[i] = byte(u >> (8 * i))
+	}
+	return nil
+}
+
+// intFromValue converts an integer column value to int64. Besides the native integer
+// types, values from hand-edited or JSON sourced mocks may be strings, json.Number or float64.
+func intFromValue(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return intFromValue(uint64(v))
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows 64-bit integer", v)
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("float value %v is not a valid integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return intFromValue(string(v))
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse integer from %q: %w", v, err)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("unexpected type %T", value)
+	}
 }
 
-func encodeBinaryDateTime(fieldType mysql.FieldType, value interface{}) ([]byte, error) {
+// uintFromValue is the unsigned counterpart of intFromValue, it keeps values above
+// math.MaxInt64 intact.
+func uintFromValue(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v), nil
+	case uint8:
+		return uint64(v), nil
+	case uint16:
+		return uint64(v), nil
+	case uint32:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v >= math.MaxUint64 {
+			return 0, fmt.Errorf("float value %v is not a valid unsigned integer", v)
+		}
+		return uint64(v), nil
+	case json.Number:
+		return uintFromValue(string(v))
+	case string:
+		u, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse unsigned integer from %q: %w", v, err)
+		}
+		return u, nil
+	}
+	i, err := intFromValue(value)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("negative value %d for unsigned field", i)
+	}
+	return uint64(i), nil
+}
+
+// bytesFromValue returns the raw bytes of a binary column value. Values read back
+// from a yaml mock arrive as a sequence of integers instead of a []byte.
+func bytesFromValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case []interface{}:
+		b := make([]byte, len(v))
+		for i, e := range v {
+			n, ok := e.(int)
+			if !ok || n < 0 || n > 0xff {
+				return nil, fmt.Errorf("invalid byte value %v at index %d", e, i)
+			}
+			b[i] = byte(n)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unexpected type %T", value)
+	}
+}
+
+func encodeBinaryDateTime(buf *bytes.Buffer, fieldType mysql.FieldType, value interface{}) error {
 	switch fieldType {
 	case mysql.FieldTypeDate, mysql.FieldTypeNewDate:
 		// Date format: YYYY-MM-DD
-		return encodeDate(value)
+		return encodeDate(buf, value)
 	case mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime:
 		// DateTime format: YYYY-MM-DD HH:MM:SS[.ffffff]
-		return encodeDateTime(value)
+		return encodeDateTime(buf, value)
 	case mysql.FieldTypeTime:
 		// Time format: [-]HH:MM:SS[.ffffff]
-		return encodeTime(value)
+		return encodeTime(buf, value)
 	default:
-		return nil, fmt.Errorf("unsupported date/time field type: %v", fieldType)
+		return fmt.Errorf("unsupported date/time field type: %v", fieldType)
 	}
 }
 
-func encodeDate(value interface{}) ([]byte, error) {
+// writeUint16 and writeUint32 write the little-endian value through a stack array, unlike
+// binary.Write which boxes its argument.
+func writeUint16(buf *bytes.Buffer, v uint16) error {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	_, err := buf.Write(b[:])
+	return err
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) error {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	_, err := buf.Write(b[:])
+	return err
+}
+
+// scanTemporal reads the numbers of a date or time value into fields following layout, in
+// which a digit stands for a number of at most that many digits (0 for any) and any other
+// byte for a separator, like fmt.Sscanf with "%04d-%02d-%02d" but without its allocations.
+// Spaces before a number are skipped and text after the last one is ignored.
+func scanTemporal(s, layout string, fields ...*int) error {
+	field := 0
+	for i := 0; i < len(layout); i++ {
+		c := layout[i]
+		if c < '0' || c > '9' {
+			if c == ' ' {
+				// the number after it skips the spaces
+				continue
+			}
+			if s == "" || s[0] != c {
+				return fmt.Errorf("expected %q at %q", c, s)
+			}
+			s = s[1:]
+			continue
+		}
+
+		width := int(c - '0')
+		if width == 0 {
+			// days or hours of a TIME, keep clear of overflowing
+			width = 9
+		}
+		s = strings.TrimLeft(s, " ")
+		negative := false
+		if s != "" && (s[0] == '-' || s[0] == '+') {
+			negative = s[0] == '-'
+			s = s[1:]
+		}
+		n, digits := 0, 0
+		for digits < len(s) && digits < width && s[digits] >= '0' && s[digits] <= '9' {
+			n = n*10 + int(s[digits]-'0')
+			digits++
+		}
+		if digits == 0 {
+			return fmt.Errorf("expected a number at %q", s)
+		}
+		s = s[digits:]
+		if negative {
+			n = -n
+		}
+		*fields[field] = n
+		field++
+	}
+	return nil
+}
+
+func encodeDate(buf *bytes.Buffer, value interface{}) error {
 	dateStr, ok := value.(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid value type for date field")
+		return fmt.Errorf("invalid value type for date field")
 	}
 	var year, month, day int
-	_, err := fmt.Sscanf(dateStr, "%04d-%02d-%02d", &year, &month, &day)
+	err := scanTemporal(dateStr, "4-2-2", &year, &month, &day)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse date string: %w", err)
+		return fmt.Errorf("failed to parse date string: %w", err)
+	}
+	if year == 0 && month == 0 && day == 0 {
+		// zero date is sent without any date parts
+		return buf.WriteByte(0)
 	}
-	buf := new(bytes.Buffer)
 	err = buf.WriteByte(byte(4))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write date length: %w", err)
+		return fmt.Errorf("failed to write date length: %w", err)
 	}
-	err = binary.Write(buf, binary.LittleEndian, uint16(year))
+	err = writeUint16(buf, uint16(year))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write year: %w", err)
+		return fmt.Errorf("failed to write year: %w", err)
 	}
 	err = buf.WriteByte(byte(month))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write month: %w", err)
+		return fmt.Errorf("failed to write month: %w", err)
 	}
 	err = buf.WriteByte(byte(day))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write day: %w", err)
+		return fmt.Errorf("failed to write day: %w", err)
 	}
-	return buf.Bytes(), nil
+	return nil
 }
 
-func encodeDateTime(value interface{}) ([]byte, error) {
+func encodeDateTime(buf *bytes.Buffer, value interface{}) error {
 	dateTimeStr, ok := value.(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid value type for datetime field")
+		return fmt.Errorf("invalid value type for datetime field")
 	}
 	var (
 		year, month, day, hour, minute, second, microsecond int
 		length                                              byte
 	)
-	if strings.Contains(dateTimeStr, ".") {
-		_, err := fmt.Sscanf(dateTimeStr, "%04d-%02d-%02d %02d:%02d:%02d.%06d",
-			&year, &month, &day, &hour, &minute, &second, &microsecond)
+	// accept the ISO 8601 separator as well, e.g. 2023-01-02T03:04:05.123456
+	dateTimeStr = strings.Replace(dateTimeStr, "T", " ", 1)
+	dateTimeStr, frac, hasFrac := strings.Cut(dateTimeStr, ".")
+	err := scanTemporal(dateTimeStr, "4-2-2 2:2:2", &year, &month, &day, &hour, &minute, &second)
+	if err != nil {
+		return fmt.Errorf("failed to parse datetime string: %w", err)
+	}
+	if year < 0 || year > 9999 || month < 0 || month > 12 || day < 0 || day > 31 ||
+		hour < 0 || hour > 23 || minute < 0 || minute > 59 || second < 0 || second > 59 {
+		return fmt.Errorf("datetime value %q is out of range", value)
+	}
+	if !hasFrac && year == 0 && month == 0 && day == 0 && hour == 0 && minute == 0 && second == 0 {
+		// zero datetime is sent without any date/time parts
+		return buf.WriteByte(0)
+	}
+	length = 7
+	if hasFrac {
+		// the 11 byte form carries the microseconds
+		microsecond, err = parseFraction(frac)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse datetime string: %w", err)
+			return fmt.Errorf("failed to parse datetime string: %w", err)
 		}
 		length = 11
-	} else {
-		_, err := fmt.Sscanf(dateTimeStr, "%04d-%02d-%02d %02d:%02d:%02d",
-			&year, &month, &day, &hour, &minute, &second)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse datetime string: %w", err)
-		}
-		length = 7
 	}
-	buf := new(bytes.Buffer)
-	err := buf.WriteByte(length)
+	if hour == 0 && minute == 0 && second == 0 && microsecond == 0 {
+		// like the server, send midnight in the 4 byte date only form
+		length = 4
+	}
+	err = buf.WriteByte(length)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write datetime length: %w", err)
+		return fmt.Errorf("failed to write datetime length: %w", err)
 	}
-	err = binary.Write(buf, binary.LittleEndian, uint16(year))
+	err = writeUint16(buf, uint16(year))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write year: %w", err)
+		return fmt.Errorf("failed to write year: %w", err)
 	}
 	err = buf.WriteByte(byte(month))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write month: %w", err)
+		return fmt.Errorf("failed to write month: %w", err)
 	}
 	err = buf.WriteByte(byte(day))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write day: %w", err)
+		return fmt.Errorf("failed to write day: %w", err)
+	}
+	if length == 4 {
+		return nil
 	}
 	err = buf.WriteByte(byte(hour))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write hour: %w", err)
+		return fmt.Errorf("failed to write hour: %w", err)
 	}
 	err = buf.WriteByte(byte(minute))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write minute: %w", err)
+		return fmt.Errorf("failed to write minute: %w", err)
 	}
 	err = buf.WriteByte(byte(second))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write second: %w", err)
+		return fmt.Errorf("failed to write second: %w", err)
 	}
 	if length == 11 {
-		err = binary.Write(buf, binary.LittleEndian, uint32(microsecond))
+		err = writeUint32(buf, uint32(microsecond))
 		if err != nil {
-			return nil, fmt.Errorf("failed to write microseconds: %w", err)
+			return fmt.Errorf("failed to write microseconds: %w", err)
 		}
 	}
-	return buf.Bytes(), nil
+	return nil
 }
 
-func encodeTime(value interface{}) ([]byte, error) {
+func encodeTime(buf *bytes.Buffer, value interface{}) error {
 	timeStr, ok := value.(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid value type for time field")
+		return fmt.Errorf("invalid value type for time field")
 	}
 	var (
 		isNegative                                  bool
 		days, hours, minutes, seconds, microseconds int
 		length                                      byte
 	)
+	if len(timeStr) == 0 {
+		return fmt.Errorf("empty value for time field")
+	}
 	if timeStr[0] == '-' {
 		isNegative = true
 		timeStr = timeStr[1:]
 	}
-	if strings.Contains(timeStr, ".") {
-		_, err := fmt.Sscanf(timeStr, "%d %02d:%02d:%02d.%06d",
-			&days, &hours, &minutes, &seconds, &microseconds)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse time string: %w", err)
-		}
-		length = 12
+	timeStr, frac, hasFrac := strings.Cut(timeStr, ".")
+	// The canonical form produced by parseBinaryTime is "[-]DAYS HH:MM:SS[.ffffff]", the
+	// "[-]HHH:MM:SS" form used by MySQL (e.g. -838:59:59) is accepted as well.
+	var err error
+	if strings.Contains(timeStr, " ") {
+		err = scanTemporal(timeStr, "0 2:2:2", &days, &hours, &minutes, &seconds)
 	} else {
-		_, err := fmt.Sscanf(timeStr, "%d %02d:%02d:%02d",
-			&days, &hours, &minutes, &seconds)
+		err = scanTemporal(timeStr, "0:2:2", &hours, &minutes, &seconds)
+		days, hours = hours/24, hours%24
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse time string: %w", err)
+	}
+	if days < 0 || hours < 0 || hours > 23 || minutes < 0 || minutes > 59 || seconds < 0 || seconds > 59 {
+		return fmt.Errorf("time value %q is out of range", value)
+	}
+	if !hasFrac && days == 0 && hours == 0 && minutes == 0 && seconds == 0 {
+		return buf.WriteByte(0)
+	}
+	length = 8
+	if hasFrac {
+		microseconds, err = parseFraction(frac)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse time string: %w", err)
+			return fmt.Errorf("failed to parse time string: %w", err)
 		}
-		length = 8
+		length = 12
 	}
-	buf := new(bytes.Buffer)
-	err := buf.WriteByte(length)
+	err = buf.WriteByte(length)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write time length: %w", err)
+		return fmt.Errorf("failed to write time length: %w", err)
 	}
 	if isNegative {
 		buf.WriteByte(1)
 	} else {
 		buf.WriteByte(0)
 	}
-	err = binary.Write(buf, binary.LittleEndian, uint32(days))
+	err = writeUint32(buf, uint32(days))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write days: %w", err)
+		return fmt.Errorf("failed to write days: %w", err)
 	}
 	err = buf.WriteByte(byte(hours))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write hours: %w", err)
+		return fmt.Errorf("failed to write hours: %w", err)
 	}
 	err = buf.WriteByte(byte(minutes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write minutes: %w", err)
+		return fmt.Errorf("failed to write minutes: %w", err)
 	}
 	err = buf.WriteByte(byte(seconds))
 	if err != nil {
-		return nil, fmt.Errorf("failed to write seconds: %w", err)
+		return fmt.Errorf("failed to write seconds: %w", err)
 	}
 	if length == 12 {
-		err = binary.Write(buf, binary.LittleEndian, uint32(microseconds))
+		err = writeUint32(buf, uint32(microseconds))
 		if err != nil {
-			return nil, fmt.Errorf("failed to write microseconds: %w", err)
+			return fmt.Errorf("failed to write microseconds: %w", err)
 		}
 	}
-	return buf.Bytes(), nil
+	return nil
 }
@@ -0,0 +1,1374 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// rowPacket returns a binary row packet with the given sequence id and payload.
+func rowPacket(seq byte, payload ...byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+func column(name string, ft mysql.FieldType) *mysql.ColumnDefinition41 {
+	return &mysql.ColumnDefinition41{Name: name, Type: byte(ft)}
+}
+
+func TestExcludeColumnsKeepsFixedWidthValues(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("secret", mysql.FieldTypeVarString),
+		column("score", mysql.FieldTypeDouble),
+	}
+	payload := []byte{0x00, 0x00}
+	payload = binary.LittleEndian.AppendUint32(payload, 7)
+	payload = append(payload, 3, 'a', 'b', 'c')
+	payload = binary.LittleEndian.AppendUint64(payload, math.Float64bits(1.5))
+	packet := rowPacket(1, payload...)
+
+	opts := DecodeOptions{ExcludeColumns: []string{"ID", "secret", "score"}}
+	row, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, opts)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if row.Values[0].Omitted || row.Values[0].Value != int32(7) {
+		t.Errorf("id = %#v, want the kept value 7", row.Values[0])
+	}
+	if row.Values[2].Omitted || row.Values[2].Value != 1.5 {
+		t.Errorf("score = %#v, want the kept value 1.5", row.Values[2])
+	}
+	secret := row.Values[1]
+	if !secret.Omitted || !strings.HasPrefix(secret.Value.(string), "sha256:") {
+		t.Fatalf("secret = %#v, want an omitted digest", secret)
+	}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	replayed, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), encoded, columns)
+	if err != nil {
+		t.Fatalf("decode replayed row: %v", err)
+	}
+	if got := replayed.Values[1].Value; got != secret.Value {
+		t.Errorf("replayed secret = %v, want the digest %v", got, secret.Value)
+	}
+	if replayed.Values[0].Value != int32(7) || replayed.Values[2].Value != 1.5 {
+		t.Errorf("replayed row = %v", replayed)
+	}
+}
+
+func TestEncodeOmittedFixedWidthValueFails(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	row := &mysql.BinaryRow{
+		Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeLong, Name: "id", Value: "sha256:00", Omitted: true}},
+	}
+	if _, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, EncodeOptions{RecomputeNullBitmap: true}); err == nil {
+		t.Fatal("expected an error for an omitted INT value")
+	}
+}
+
+// mixedRowColumns and mixedRow are a typical row of a replayed resultset.
+var mixedRowColumns = []*mysql.ColumnDefinition41{
+	column("id", mysql.FieldTypeLong),
+	column("total", mysql.FieldTypeLongLong),
+	column("price", mysql.FieldTypeDouble),
+	column("name", mysql.FieldTypeVarString),
+	column("created_at", mysql.FieldTypeDateTime),
+	column("birthday", mysql.FieldTypeDate),
+	column("duration", mysql.FieldTypeTime),
+}
+
+func mixedRow() *mysql.BinaryRow {
+	values := []interface{}{int32(42), int64(1) << 40, 9.99, "keploy", "2024-02-29 13:45:06.123456", "1990-05-17", "1 02:03:04"}
+	row := &mysql.BinaryRow{Header: mysql.Header{SequenceID: 1}}
+	for i, col := range mixedRowColumns {
+		row.Values = append(row.Values, mysql.ColumnEntry{Type: mysql.FieldType(col.Type), Name: col.Name, Value: values[i]})
+	}
+	row.RowNullBuffer = nullBitmapFromValues(row.Values)
+	return row
+}
+
+func BenchmarkEncodeBinaryRow(b *testing.B) {
+	ctx, logger, row := context.Background(), zap.NewNop(), mixedRow()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeBinaryRow(ctx, logger, row, mixedRowColumns, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestEncodeTemporalValuesDoesNotAllocate(t *testing.T) {
+	buf := bytes.NewBuffer(make([]byte, 0, 64))
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		if err := encodeDate(buf, "1990-05-17"); err != nil {
+			t.Fatal(err)
+		}
+		if err := encodeDateTime(buf, "2024-02-29 13:45:06.123456"); err != nil {
+			t.Fatal(err)
+		}
+		if err := encodeTime(buf, "-1 02:03:04.5"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("encoding date and time values allocated %v times", allocs)
+	}
+}
+
+func FuzzDecodeBinaryRow(f *testing.F) {
+	packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), mixedRow(), mixedRowColumns, false)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(packet, byte(mysql.FieldTypeLong))
+	f.Add(rowPacket(1, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00), byte(mysql.FieldTypeLong))
+	f.Add(rowPacket(1, 0x00, 0x04), byte(mysql.FieldTypeBLOB))
+	f.Add(rowPacket(1, 0x00, 0x00, 0x03, 'a', 'b', 'c'), byte(mysql.FieldTypeVarString))
+	f.Add(rowPacket(1, 0x00, 0x00, 0x07, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06), byte(mysql.FieldTypeDateTime))
+	f.Add(rowPacket(1, 0x00, 0x00, 0x08, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04), byte(mysql.FieldTypeTime))
+
+	f.Fuzz(func(t *testing.T, data []byte, ft byte) {
+		for _, columns := range [][]*mysql.ColumnDefinition41{mixedRowColumns, {column("v", mysql.FieldType(ft))}} {
+			row, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), data, columns)
+			if err == nil && (row == nil || n > len(data)) {
+				t.Fatalf("decode returned row %v after reading %d of %d bytes", row, n, len(data))
+			}
+			// ValidateBinaryRow never accepts what the decoder rejects
+			if ValidateBinaryRow(data, columns) == nil && err != nil {
+				t.Fatalf("ValidateBinaryRow accepted a row that fails to decode: %v", err)
+			}
+		}
+	})
+}
+
+func TestEmptyAndNullValuesStayDistinct(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("name", mysql.FieldTypeVarChar),
+		column("data", mysql.FieldTypeBLOB),
+		column("extra", mysql.FieldTypeBLOB),
+	}
+	// empty VARCHAR, empty BLOB, NULL BLOB flagged by bit 2+2 of the bitmap
+	packet := rowPacket(1, 0x00, 0x10, 0x00, 0x00)
+
+	row, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, empty := range []bool{true, true, false} {
+		value := row.Values[i].Value
+		if s, ok := value.(string); empty && (!ok || s != "") {
+			t.Errorf("column %s decoded as %#v, want an empty value", columns[i].Name, value)
+		}
+		if !empty && value != nil {
+			t.Errorf("column %s decoded as %#v, want nil", columns[i].Name, value)
+		}
+	}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+
+	// the same row as read back from a mock, with the bitmap rebuilt from the values
+	mock := &mysql.BinaryRow{
+		Header: mysql.Header{SequenceID: 1},
+		Values: []mysql.ColumnEntry{
+			{Type: mysql.FieldTypeVarChar, Name: "name", Value: ""},
+			{Type: mysql.FieldTypeBLOB, Name: "data", Value: ""},
+			{Type: mysql.FieldTypeBLOB, Name: "extra", Value: nil},
+		},
+	}
+	encoded, err = EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), mock, columns, EncodeOptions{RecomputeNullBitmap: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("mock row encoded % x, want % x", encoded, packet)
+	}
+}
+
+func TestSpecialFloatsSurviveRoundTrip(t *testing.T) {
+	float32s := map[string]float32{
+		"-0":           float32(math.Copysign(0, -1)),
+		"max":          math.MaxFloat32,
+		"subnormal":    math.SmallestNonzeroFloat32,
+		"+inf":         float32(math.Inf(1)),
+		"-inf":         float32(math.Inf(-1)),
+		"nan":          float32(math.NaN()),
+		"max negative": -math.MaxFloat32,
+	}
+	float64s := map[string]float64{
+		"-0":        math.Copysign(0, -1),
+		"max":       math.MaxFloat64,
+		"subnormal": math.SmallestNonzeroFloat64,
+		"+inf":      math.Inf(1),
+		"-inf":      math.Inf(-1),
+		"nan":       math.NaN(),
+	}
+
+	type floatPacket struct {
+		name   string
+		ft     mysql.FieldType
+		packet []byte
+	}
+	var packets []floatPacket
+	for name, v := range float32s {
+		packet := rowPacket(1, 0x00, 0x00, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(packet[6:], math.Float32bits(v))
+		packets = append(packets, floatPacket{"float " + name, mysql.FieldTypeFloat, packet})
+	}
+	for name, v := range float64s {
+		packet := rowPacket(1, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(packet[6:], math.Float64bits(v))
+		packets = append(packets, floatPacket{"double " + name, mysql.FieldTypeDouble, packet})
+	}
+
+	for _, p := range packets {
+		t.Run(p.name, func(t *testing.T) {
+			columns := []*mysql.ColumnDefinition41{column("v", p.ft)}
+			row, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), p.packet, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, p.packet) {
+				t.Errorf("encoded % x, want % x", encoded, p.packet)
+			}
+
+			// and through the yaml form of a mock
+			data, err := yaml.Marshal(row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var mock mysql.BinaryRow
+			if err := yaml.Unmarshal(data, &mock); err != nil {
+				t.Fatal(err)
+			}
+			encoded, err = EncodeBinaryRow(context.Background(), zap.NewNop(), &mock, columns, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, p.packet) {
+				t.Errorf("mock\n%s\nencoded % x, want % x", data, encoded, p.packet)
+			}
+		})
+	}
+}
+
+func TestDecodeAllNullRow(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("name", mysql.FieldTypeVarString),
+		column("created_at", mysql.FieldTypeDateTime),
+	}
+	// bits 2 to 4 flag the three columns, the bytes after the packet must stay unread
+	packet := append(rowPacket(1, 0x00, 0x1c), 0xff, 0xff)
+
+	row, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("read %d bytes, want the 6 of the header and bitmap", n)
+	}
+	if !row.AllNull() {
+		t.Errorf("AllNull() = false for %+v", row.Values)
+	}
+	if len(row.Values) != len(columns) {
+		t.Errorf("decoded %d values, want %d", len(row.Values), len(columns))
+	}
+}
+
+func TestDecimalRowRoundTrip(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("zero", mysql.FieldTypeNewDecimal),
+		column("amount", mysql.FieldTypeNewDecimal),
+		column("precise", mysql.FieldTypeDecimal),
+	}
+	values := []string{"0.00", "-123.4500", "12345678901234567890123456789012345.123456789012345678901234567890"}
+	payload := []byte{0x00, 0x00}
+	for _, v := range values {
+		payload = append(append(payload, byte(len(v))), v...)
+	}
+	packet := rowPacket(1, payload...)
+
+	row, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		if row.Values[i].Value != v {
+			t.Errorf("column %s decoded as %#v, want %q", columns[i].Name, row.Values[i].Value, v)
+		}
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+}
+
+func TestReadLengthEncodedNullMarker(t *testing.T) {
+	for _, ft := range []mysql.FieldType{mysql.FieldTypeNewDecimal, mysql.FieldTypeDecimal, mysql.FieldTypeBit, mysql.FieldTypeGeometry} {
+		res, n, err := readBinaryValue([]byte{0xfb}, column("v", ft))
+		if err != nil {
+			t.Errorf("%v: %v", ft, err)
+			continue
+		}
+		if res.value != nil || n != 1 {
+			t.Errorf("%v: read %#v from %d bytes, want nil from 1", ft, res.value, n)
+		}
+	}
+}
+
+func BenchmarkDecodeBinaryRow(b *testing.B) {
+	intColumns := make([]*mysql.ColumnDefinition41, 10)
+	intPayload := []byte{0x00, 0x00, 0x00}
+	for i := range intColumns {
+		intColumns[i] = column("c", mysql.FieldTypeLong)
+		intPayload = append(intPayload, byte(i), 0x00, 0x00, 0x00)
+	}
+	mixed, err := EncodeBinaryRow(context.Background(), zap.NewNop(), mixedRow(), mixedRowColumns, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bm := range []struct {
+		name    string
+		packet  []byte
+		columns []*mysql.ColumnDefinition41
+	}{
+		{"int", rowPacket(1, intPayload...), intColumns},
+		{"mixed", mixed, mixedRowColumns},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx, logger := context.Background(), zap.NewNop()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := DecodeBinaryRow(ctx, logger, bm.packet, bm.columns); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestZeroCopyValuesAliasThePacket(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("name", mysql.FieldTypeVarString),
+		column("data", mysql.FieldTypeBLOB),
+		column("id", mysql.FieldTypeLong),
+	}
+	packet := rowPacket(1, 0x00, 0x00, 0x03, 'a', 'b', 'c', 0x02, 0x01, 0x02, 0x2a, 0x00, 0x00, 0x00)
+	original := bytes.Clone(packet)
+
+	row, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{ZeroCopy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, ok := row.Values[0].Value.([]byte)
+	if !ok {
+		t.Fatalf("name decoded as %T, want a []byte view", row.Values[0].Value)
+	}
+	if row.Values[2].Value != int32(42) {
+		t.Errorf("id decoded as %#v, want int32(42)", row.Values[2].Value)
+	}
+
+	// the view shares its bytes with the packet
+	packet[7] = 'x'
+	if string(name) != "xbc" {
+		t.Errorf("name is %q after changing the packet, want the view to see the change", name)
+	}
+	packet[7] = 'a'
+
+	// but appending to it doesn't write into the packet
+	_ = append(name, 'z')
+	if !bytes.Equal(packet, original) {
+		t.Errorf("appending to a view changed the packet to % x", packet)
+	}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, original) {
+		t.Errorf("encoded % x, want % x", encoded, original)
+	}
+}
+
+func BenchmarkDecodeBinaryRowZeroCopy(b *testing.B) {
+	columns := make([]*mysql.ColumnDefinition41, 8)
+	values := make([]mysql.ColumnEntry, len(columns))
+	for i := range columns {
+		columns[i] = column("c", mysql.FieldTypeBLOB)
+		values[i] = mysql.ColumnEntry{Type: mysql.FieldTypeBLOB, Name: "c", Value: strings.Repeat("keploy", 200)}
+	}
+	packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), &mysql.BinaryRow{Values: values}, columns, true)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, zeroCopy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("ZeroCopy=%t", zeroCopy), func(b *testing.B) {
+			ctx, logger, opts := context.Background(), zap.NewNop(), DecodeOptions{ZeroCopy: zeroCopy}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := DecodeBinaryRowWithOptions(ctx, logger, packet, columns, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// assertRoundTrip decodes the packet, checks that the row re-encodes to the same bytes and
+// returns it.
+func assertRoundTrip(t *testing.T, packet []byte, columns []*mysql.ColumnDefinition41) *mysql.BinaryRow {
+	t.Helper()
+	row, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n != len(packet) {
+		t.Errorf("decoded %d bytes of the %d byte packet", n, len(packet))
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+	return row
+}
+
+func TestMediumIntBoundaries(t *testing.T) {
+	signed := column("signed", mysql.FieldTypeInt24)
+	unsigned := column("unsigned", mysql.FieldTypeInt24)
+	unsigned.Flags |= mysql.UNSIGNED_FLAG
+	columns := []*mysql.ColumnDefinition41{signed, unsigned}
+
+	// MEDIUMINT takes 4 bytes on the wire
+	row := assertRoundTrip(t, rowPacket(1, 0x00, 0x00, 0xff, 0xff, 0x7f, 0x00, 0xff, 0xff, 0xff, 0x00), columns)
+	if row.Values[0].Value != int32(8388607) || row.Values[1].Value != uint32(16777215) {
+		t.Errorf("decoded %#v and %#v, want the signed and unsigned maximums", row.Values[0].Value, row.Values[1].Value)
+	}
+	row = assertRoundTrip(t, rowPacket(1, 0x00, 0x00, 0x00, 0x00, 0x80, 0xff, 0x00, 0x00, 0x00, 0x00), columns)
+	if row.Values[0].Value != int32(-8388608) || row.Values[1].Value != uint32(0) {
+		t.Errorf("decoded %#v and %#v, want the signed and unsigned minimums", row.Values[0].Value, row.Values[1].Value)
+	}
+
+	if _, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), rowPacket(1, 0x00, 0x00, 0xff, 0xff, 0x7f), columns[:1]); err == nil {
+		t.Error("decoding a 3 byte MEDIUMINT value succeeded")
+	}
+}
+
+func TestYearKeepsFollowingColumnAligned(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("year", mysql.FieldTypeYear), column("name", mysql.FieldTypeVarChar)}
+	// YEAR is sent as int<2>, the VARCHAR starts right after it
+	row := assertRoundTrip(t, rowPacket(1, 0x00, 0x00, 0xe8, 0x07, 0x02, 'o', 'k'), columns)
+	if row.Values[0].Value != int16(2024) || row.Values[1].Value != "ok" {
+		t.Errorf("decoded %#v and %#v, want 2024 and \"ok\"", row.Values[0].Value, row.Values[1].Value)
+	}
+}
+
+func TestSelectNullAndOne(t *testing.T) {
+	// SELECT NULL, 1: a NULL typed column flagged in the bitmap and a BIGINT
+	columns := []*mysql.ColumnDefinition41{column("NULL", mysql.FieldTypeNULL), column("1", mysql.FieldTypeLongLong)}
+	row := assertRoundTrip(t, rowPacket(1, 0x00, 0x04, 0x01, 0, 0, 0, 0, 0, 0, 0), columns)
+	if row.Values[0].Value != nil || row.Values[1].Value != int64(1) {
+		t.Errorf("decoded %#v and %#v, want nil and 1", row.Values[0].Value, row.Values[1].Value)
+	}
+}
+
+func TestBitRoundTrip(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("b", mysql.FieldTypeBit)}
+	// BIT(1) holding 0 and BIT(64) with the highest and lowest bits set, sent as their
+	// big-endian bytes
+	for _, value := range [][]byte{
+		{0x00},
+		{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+	} {
+		payload := append([]byte{0x00, 0x00, byte(len(value))}, value...)
+		row := assertRoundTrip(t, rowPacket(1, payload...), columns)
+		if got, ok := row.Values[0].Value.([]byte); !ok || !bytes.Equal(got, value) {
+			t.Errorf("decoded %#v, want % x", row.Values[0].Value, value)
+		}
+	}
+}
+
+func TestGeometryKeepsSRID(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("g", mysql.FieldTypeGeometry)}
+	// POINT(1 2) in SRID 4326: the little-endian SRID followed by the WKB of the point
+	value := binary.LittleEndian.AppendUint32(nil, 4326)
+	value = append(value, 0x01)
+	value = binary.LittleEndian.AppendUint32(value, 1)
+	value = binary.LittleEndian.AppendUint64(value, math.Float64bits(1))
+	value = binary.LittleEndian.AppendUint64(value, math.Float64bits(2))
+	payload := append([]byte{0x00, 0x00, byte(len(value))}, value...)
+
+	row := assertRoundTrip(t, rowPacket(1, payload...), columns)
+	got, ok := row.Values[0].Value.([]byte)
+	if !ok || !bytes.Equal(got, value) {
+		t.Fatalf("decoded %#v, want % x", row.Values[0].Value, value)
+	}
+	if srid := binary.LittleEndian.Uint32(got[:4]); srid != 4326 {
+		t.Errorf("SRID = %d, want 4326", srid)
+	}
+}
+
+func TestParseTruncatedDate(t *testing.T) {
+	// the value claims 4 bytes but only 2 follow
+	if _, _, err := parseBinaryDate([]byte{0x04, 0xe8, 0x07}); err == nil {
+		t.Error("parsing a truncated DATE value succeeded")
+	}
+	columns := []*mysql.ColumnDefinition41{column("d", mysql.FieldTypeDate)}
+	if _, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), rowPacket(1, 0x00, 0x00, 0x04, 0xe8, 0x07), columns); err == nil {
+		t.Error("decoding a row with a truncated DATE value succeeded")
+	}
+}
+
+func TestDateTimeLengths(t *testing.T) {
+	col := column("t", mysql.FieldTypeDateTime)
+	col.Decimals = 6
+	columns := []*mysql.ColumnDefinition41{col}
+	// the shorter forms leave out the zero parts and are decoded without them, so that they
+	// are encoded back into the same length
+	for _, c := range []struct {
+		value []byte
+		want  string
+	}{
+		{[]byte{0x00}, "0000-00-00 00:00:00"},
+		{[]byte{0x04, 0xe8, 0x07, 0x02, 0x1d}, "2024-02-29 00:00:00"},
+		{[]byte{0x07, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06}, "2024-02-29 13:45:06"},
+		{[]byte{0x0b, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06, 0x40, 0xe2, 0x01, 0x00}, "2024-02-29 13:45:06.123456"},
+	} {
+		row := assertRoundTrip(t, rowPacket(1, append([]byte{0x00, 0x00}, c.value...)...), columns)
+		if row.Values[0].Value != c.want {
+			t.Errorf("length %d decoded as %#v, want %q", c.value[0], row.Values[0].Value, c.want)
+		}
+		if c.value[0] > 0 {
+			if _, _, err := parseBinaryDateTime(c.value[:len(c.value)-1], col.Decimals); err == nil {
+				t.Errorf("parsing a truncated DATETIME value of length %d succeeded", c.value[0])
+			}
+		}
+	}
+}
+
+func TestTimeLengths(t *testing.T) {
+	col := column("t", mysql.FieldTypeTime)
+	col.Decimals = 6
+	columns := []*mysql.ColumnDefinition41{col}
+	for _, c := range []struct {
+		value []byte
+		want  string
+	}{
+		{[]byte{0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04}, "1 02:03:04"},
+		{[]byte{0x0c, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04, 0x40, 0xe2, 0x01, 0x00}, "-1 02:03:04.123456"},
+	} {
+		row := assertRoundTrip(t, rowPacket(1, append([]byte{0x00, 0x00}, c.value...)...), columns)
+		if row.Values[0].Value != c.want {
+			t.Errorf("length %d decoded as %#v, want %q", c.value[0], row.Values[0].Value, c.want)
+		}
+		// the microseconds of the 12 byte form must be there
+		if _, _, err := parseBinaryTime(c.value[:len(c.value)-1], col.Decimals); err == nil {
+			t.Errorf("parsing a truncated TIME value of length %d succeeded", c.value[0])
+		}
+	}
+}
+
+func TestEncodeRecomputesPayloadLength(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("name", mysql.FieldTypeVarString)}
+	packet := rowPacket(1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 'a', 'b', 'c')
+	row, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// e.g. a noise replacement while templating a test
+	row.Values[1].Value = "a longer name"
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length := utils.ReadUint24(encoded[:3]); int(length) != len(encoded)-4 || length == row.Header.PayloadLength {
+		t.Errorf("header payload length is %d for a %d byte payload", length, len(encoded)-4)
+	}
+}
+
+func TestEncodeColumnValueCountMismatch(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("a", mysql.FieldTypeLong), column("b", mysql.FieldTypeLong), column("c", mysql.FieldTypeLong)}
+	row := &mysql.BinaryRow{Values: []mysql.ColumnEntry{
+		{Type: mysql.FieldTypeLong, Name: "a", Value: int32(1)},
+		{Type: mysql.FieldTypeLong, Name: "b", Value: int32(2)},
+	}}
+	_, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	if err == nil || !strings.Contains(err.Error(), "2 values") || !strings.Contains(err.Error(), "3 columns") {
+		t.Errorf("encoding 2 values for 3 columns returned %v", err)
+	}
+}
+
+func TestBlobNullMarker(t *testing.T) {
+	// a BLOB value sent as the 0xfb NULL marker rather than flagged in the bitmap
+	columns := []*mysql.ColumnDefinition41{column("data", mysql.FieldTypeBLOB), column("id", mysql.FieldTypeLong)}
+	row, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), rowPacket(1, 0x00, 0x00, 0xfb, 0x07, 0x00, 0x00, 0x00), columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Values[0].Value != nil {
+		t.Errorf("decoded %#v, want nil", row.Values[0].Value)
+	}
+	if row.Values[1].Value != int32(7) {
+		t.Errorf("the column after the marker decoded as %#v, want 7", row.Values[1].Value)
+	}
+}
+
+func TestFractionalSecondPrecision(t *testing.T) {
+	for _, c := range []struct {
+		decimals byte
+		payload  []byte
+		datetime string
+		time     string
+	}{
+		// the server leaves the microseconds out of the values of columns without them
+		{0, []byte{0x07, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04},
+			"2024-02-29 13:45:06", "0 02:03:04"},
+		{3, []byte{0x0b, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06, 0x78, 0xe0, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04, 0x78, 0xe0, 0x01, 0x00},
+			"2024-02-29 13:45:06.123", "0 02:03:04.123"},
+		{6, []byte{0x0b, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06, 0x40, 0xe2, 0x01, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04, 0x40, 0xe2, 0x01, 0x00},
+			"2024-02-29 13:45:06.123456", "0 02:03:04.123456"},
+	} {
+		dt := column("dt", mysql.FieldTypeDateTime)
+		tm := column("tm", mysql.FieldTypeTime)
+		dt.Decimals, tm.Decimals = c.decimals, c.decimals
+
+		row := assertRoundTrip(t, rowPacket(1, append([]byte{0x00, 0x00}, c.payload...)...), []*mysql.ColumnDefinition41{dt, tm})
+		if row.Values[0].Value != c.datetime || row.Values[1].Value != c.time {
+			t.Errorf("precision %d decoded as %#v and %#v, want %q and %q", c.decimals, row.Values[0].Value, row.Values[1].Value, c.datetime, c.time)
+		}
+	}
+}
+
+func TestTimeRangeExtremes(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("t", mysql.FieldTypeTime)}
+	for _, c := range []struct {
+		hours  string
+		packet []byte
+		want   string
+	}{
+		// 838 hours are 34 days and 22 hours
+		{"838:59:59", rowPacket(1, 0x00, 0x00, 0x08, 0x00, 0x22, 0x00, 0x00, 0x00, 0x16, 0x3b, 0x3b), "34 22:59:59"},
+		{"-838:59:59", rowPacket(1, 0x00, 0x00, 0x08, 0x01, 0x22, 0x00, 0x00, 0x00, 0x16, 0x3b, 0x3b), "-34 22:59:59"},
+	} {
+		row := assertRoundTrip(t, c.packet, columns)
+		if row.Values[0].Value != c.want {
+			t.Errorf("decoded %#v, want %q", row.Values[0].Value, c.want)
+		}
+
+		// the hours form a mock may be edited to
+		row.Values[0].Value = c.hours
+		encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, c.packet) {
+			t.Errorf("%s encoded % x, want % x", c.hours, encoded, c.packet)
+		}
+	}
+}
+
+func TestDateTimeMicrosecondsRoundTrip(t *testing.T) {
+	col := column("created_at", mysql.FieldTypeDateTime)
+	col.Decimals = 6
+	columns := []*mysql.ColumnDefinition41{col}
+	row := &mysql.BinaryRow{Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeDateTime, Name: "created_at", Value: "2024-02-29 13:45:06.000042"}}}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rowPacket(0, 0x00, 0x00, 0x0b, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06, 0x2a, 0x00, 0x00, 0x00)
+	if !bytes.Equal(encoded, want) {
+		t.Errorf("encoded % x, want % x", encoded, want)
+	}
+	decoded := assertRoundTrip(t, encoded, columns)
+	if decoded.Values[0].Value != row.Values[0].Value {
+		t.Errorf("decoded %#v, want %q", decoded.Values[0].Value, row.Values[0].Value)
+	}
+}
+
+func TestEncodeIntegerRepresentations(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	want := rowPacket(1, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00)
+	encode := func(value interface{}) ([]byte, error) {
+		row := &mysql.BinaryRow{Header: mysql.Header{SequenceID: 1}, Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeLong, Name: "id", Value: value}}}
+		return EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	}
+
+	for _, value := range []interface{}{"42", float64(42), 42, int8(42), int16(42), int32(42), int64(42), uint(42), uint8(42), uint16(42), uint32(42), uint64(42)} {
+		encoded, err := encode(value)
+		if err != nil {
+			t.Errorf("%T: %v", value, err)
+			continue
+		}
+		if !bytes.Equal(encoded, want) {
+			t.Errorf("%T encoded % x, want % x", value, encoded, want)
+		}
+	}
+	for _, value := range []interface{}{"4x2", 42.5, true} {
+		if _, err := encode(value); err == nil {
+			t.Errorf("encoding %#v into an INT succeeded", value)
+		}
+	}
+}
+
+func TestEncodeSignedBigIntRejectsUnsignedOverflow(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLongLong)}
+	encode := func(value interface{}) ([]byte, error) {
+		row := &mysql.BinaryRow{Header: mysql.Header{SequenceID: 1}, Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeLongLong, Name: "id", Value: value}}}
+		return EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	}
+
+	// would wrap around to math.MinInt64 in a signed BIGINT
+	for _, value := range []interface{}{uint64(math.MaxInt64 + 1), uint(math.MaxInt64 + 1), uint64(math.MaxUint64)} {
+		if _, err := encode(value); err == nil {
+			t.Errorf("encoding %T %v into a signed BIGINT succeeded", value, value)
+		}
+	}
+	if _, err := encode(uint64(math.MaxInt64)); err != nil {
+		t.Errorf("encoding the signed BIGINT maximum as uint64: %v", err)
+	}
+}
+
+func TestRowFollowedByTerminator(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	for name, terminator := range map[string][]byte{
+		"EOF": rowPacket(3, mysql.EOF, 0x00, 0x00, 0x02, 0x00),
+		// the OK packet sent instead with CLIENT_DEPRECATE_EOF
+		"OK": rowPacket(3, mysql.EOF, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00),
+	} {
+		data := append(rowPacket(2, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00), terminator...)
+
+		row, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), data, columns)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if row.Values[0].Value != int32(1) {
+			t.Errorf("%s: decoded %#v, want 1", name, row.Values[0].Value)
+		}
+		if _, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), data[n:], columns); !errors.Is(err, ErrResultSetEnd) {
+			t.Errorf("%s: decoding the terminator returned %v, want ErrResultSetEnd", name, err)
+		}
+	}
+}
+
+// compressedPacket wraps packet in a compressed protocol packet, deflated or sent raw.
+func compressedPacket(t *testing.T, packet []byte, deflate bool) []byte {
+	t.Helper()
+	payload, uncompressedLength := packet, 0
+	if deflate {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		payload, uncompressedLength = buf.Bytes(), len(packet)
+	}
+	n := len(payload)
+	header := []byte{byte(n), byte(n >> 8), byte(n >> 16), 0x00, byte(uncompressedLength), byte(uncompressedLength >> 8), byte(uncompressedLength >> 16)}
+	return append(header, payload...)
+}
+
+func TestDecodeCompressedBinaryRow(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("name", mysql.FieldTypeVarString)}
+	packet := rowPacket(1, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x06, 'k', 'e', 'p', 'l', 'o', 'y')
+
+	for _, deflate := range []bool{true, false} {
+		data := compressedPacket(t, packet, deflate)
+		row, n, err := DecodeCompressedBinaryRow(context.Background(), zap.NewNop(), append(data, 0xff), columns)
+		if err != nil {
+			t.Fatalf("deflate %t: %v", deflate, err)
+		}
+		if n != len(data) {
+			t.Errorf("deflate %t: consumed %d of the %d byte compressed packet", deflate, n, len(data))
+		}
+		if row.Values[0].Value != int32(42) || row.Values[1].Value != "keploy" {
+			t.Errorf("deflate %t: decoded %+v", deflate, row.Values)
+		}
+	}
+}
+
+func TestNullBitmapAroundByteBoundaries(t *testing.T) {
+	for _, count := range []int{6, 7, 8, 14, 15} {
+		columns := make([]*mysql.ColumnDefinition41, count)
+		for i := range columns {
+			columns[i] = column("c", mysql.FieldTypeTiny)
+		}
+		for null := 0; null < count; null++ {
+			isNull := make([]bool, count)
+			isNull[null] = true
+			bitmap := BuildNullBitmap(isNull)
+			if want := (count + 7 + 2) / 8; len(bitmap) != want {
+				t.Fatalf("%d columns: bitmap of %d bytes, want %d", count, len(bitmap), want)
+			}
+
+			payload := append([]byte{0x00}, bitmap...)
+			for i := 0; i < count-1; i++ {
+				payload = append(payload, byte(i))
+			}
+			row := assertRoundTrip(t, rowPacket(1, payload...), columns)
+			for i, v := range row.Values {
+				if isNullAt(bitmap, i, resultSetNullBitmapOffset) != (i == null) || (v.Value == nil) != (i == null) {
+					t.Errorf("%d columns with column %d NULL: column %d decoded as %#v", count, null, i, v.Value)
+				}
+			}
+		}
+	}
+}
+
+func TestGoTypeFor(t *testing.T) {
+	var (
+		str = reflect.TypeOf("")
+		raw = reflect.TypeOf([]byte(nil))
+	)
+	tests := []struct {
+		ft               mysql.FieldType
+		signed, unsigned reflect.Type
+	}{
+		{mysql.FieldTypeTiny, reflect.TypeOf(int8(0)), reflect.TypeOf(uint8(0))},
+		{mysql.FieldTypeShort, reflect.TypeOf(int16(0)), reflect.TypeOf(uint16(0))},
+		{mysql.FieldTypeYear, reflect.TypeOf(int16(0)), reflect.TypeOf(uint16(0))},
+		{mysql.FieldTypeInt24, reflect.TypeOf(int32(0)), reflect.TypeOf(uint32(0))},
+		{mysql.FieldTypeLong, reflect.TypeOf(int32(0)), reflect.TypeOf(uint32(0))},
+		{mysql.FieldTypeLongLong, reflect.TypeOf(int64(0)), reflect.TypeOf(uint64(0))},
+		{mysql.FieldTypeFloat, reflect.TypeOf(float32(0)), reflect.TypeOf(float32(0))},
+		{mysql.FieldTypeDouble, reflect.TypeOf(float64(0)), reflect.TypeOf(float64(0))},
+		{mysql.FieldTypeDecimal, str, str},
+		{mysql.FieldTypeNewDecimal, str, str},
+		{mysql.FieldTypeString, str, str},
+		{mysql.FieldTypeVarString, str, str},
+		{mysql.FieldTypeVarChar, str, str},
+		{mysql.FieldTypeBLOB, str, str},
+		{mysql.FieldTypeTinyBLOB, str, str},
+		{mysql.FieldTypeMediumBLOB, str, str},
+		{mysql.FieldTypeLongBLOB, str, str},
+		{mysql.FieldTypeJSON, str, str},
+		{mysql.FieldTypeEnum, str, str},
+		{mysql.FieldTypeSet, str, str},
+		{mysql.FieldTypeDate, str, str},
+		{mysql.FieldTypeNewDate, str, str},
+		{mysql.FieldTypeTimestamp, str, str},
+		{mysql.FieldTypeDateTime, str, str},
+		{mysql.FieldTypeTime, str, str},
+		{fieldTypeTimestamp2, str, str},
+		{fieldTypeDateTime2, str, str},
+		{fieldTypeTime2, str, str},
+		{mysql.FieldTypeBit, raw, raw},
+		{mysql.FieldTypeGeometry, raw, raw},
+		{mysql.FieldTypeNULL, nil, nil},
+	}
+
+	listed := map[mysql.FieldType]bool{}
+	for _, tt := range tests {
+		listed[tt.ft] = true
+		if got := GoTypeFor(tt.ft, false); got != tt.signed {
+			t.Errorf("GoTypeFor(%v, false) = %v, want %v", tt.ft, got, tt.signed)
+		}
+		if got := GoTypeFor(tt.ft, true); got != tt.unsigned {
+			t.Errorf("GoTypeFor(%v, true) = %v, want %v", tt.ft, got, tt.unsigned)
+		}
+	}
+	for ft := 0; ft <= 0xff; ft++ {
+		if !listed[mysql.FieldType(ft)] && GoTypeFor(mysql.FieldType(ft), false) != nil {
+			t.Errorf("GoTypeFor(%v) is missing from the table", mysql.FieldType(ft))
+		}
+	}
+}
+
+// unsignedColumn returns a column of the field type flagged UNSIGNED_FLAG.
+func unsignedColumn(name string, ft mysql.FieldType) *mysql.ColumnDefinition41 {
+	col := column(name, ft)
+	col.Flags |= mysql.UNSIGNED_FLAG
+	return col
+}
+
+func TestBigIntUnsignedMax(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{unsignedColumn("id", mysql.FieldTypeLongLong)}
+	packet := rowPacket(1, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	row := assertRoundTrip(t, packet, columns)
+	if row.Values[0].Value != uint64(math.MaxUint64) {
+		t.Errorf("decoded %#v, want %d", row.Values[0].Value, uint64(math.MaxUint64))
+	}
+
+	// as read back from a yaml mock
+	data, err := yaml.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mock mysql.BinaryRow
+	if err := yaml.Unmarshal(data, &mock); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), &mock, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("mock\n%s\nencoded % x, want % x", data, encoded, packet)
+	}
+}
+
+func TestUnsignedIntegerMaximums(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		unsignedColumn("tiny", mysql.FieldTypeTiny),
+		unsignedColumn("small", mysql.FieldTypeShort),
+		unsignedColumn("int", mysql.FieldTypeLong),
+	}
+	packet := rowPacket(1, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	row := assertRoundTrip(t, packet, columns)
+	if row.Values[0].Value != uint8(255) || row.Values[1].Value != uint16(65535) || row.Values[2].Value != uint32(4294967295) {
+		t.Errorf("decoded %+v", row.Values)
+	}
+
+	// ints read back from a mock whose entries don't record the unsigned flag
+	mock := &mysql.BinaryRow{Header: mysql.Header{SequenceID: 1}}
+	for i, v := range []int{255, 65535, 4294967295} {
+		mock.Values = append(mock.Values, mysql.ColumnEntry{Type: mysql.FieldType(columns[i].Type), Name: columns[i].Name, Value: v})
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), mock, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+}
+
+func TestJSONKeepsServerFormatting(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("doc", mysql.FieldTypeJSON)}
+	doc := `{"b": 1, "a": 2}`
+	row := assertRoundTrip(t, rowPacket(1, append([]byte{0x00, 0x00, byte(len(doc))}, doc...)...), columns)
+	if row.Values[0].Value != doc {
+		t.Errorf("decoded %#v, want %q unchanged", row.Values[0].Value, doc)
+	}
+}
+
+func TestDecodeErrorLocatesCorruptValue(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("name", mysql.FieldTypeVarString)}
+	// the name claims 10 bytes but only 2 follow
+	packet := rowPacket(1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0a, 'a', 'b')
+
+	_, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("decoding returned %v, want a *DecodeError", err)
+	}
+	// the 4 byte header, the OK byte, the bitmap and the id come before the name
+	if decodeErr.Offset != 10 || decodeErr.Column != 1 {
+		t.Errorf("error at offset %d column %d, want offset 10 column 1: %v", decodeErr.Offset, decodeErr.Column, err)
+	}
+}
+
+func TestZeroTimestamp(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("updated_at", mysql.FieldTypeTimestamp)}
+	// not NULL in the bitmap, the zero value is sent with length 0
+	packet := rowPacket(1, 0x00, 0x00, 0x00)
+	row := assertRoundTrip(t, packet, columns)
+	if row.Values[0].Value != "0000-00-00 00:00:00" {
+		t.Errorf("decoded %#v, want the zero date", row.Values[0].Value)
+	}
+	if row.AllNull() {
+		t.Error("the zero date was decoded as NULL")
+	}
+}
+
+func TestEncodeNullBitmapModes(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("name", mysql.FieldTypeVarString)}
+	row := &mysql.BinaryRow{
+		Header: mysql.Header{SequenceID: 1},
+		// the name was set to NULL by hand, the stored bitmap still marks nothing
+		RowNullBuffer: []byte{0x00},
+		Values: []mysql.ColumnEntry{
+			{Type: mysql.FieldTypeLong, Name: "id", Value: int32(1)},
+			{Type: mysql.FieldTypeVarString, Name: "name", Value: nil},
+		},
+	}
+
+	if _, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false); err == nil {
+		t.Error("encoding a row whose bitmap doesn't match its values succeeded")
+	}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := rowPacket(1, 0x00, 0x08, 0x01, 0x00, 0x00, 0x00); !bytes.Equal(encoded, want) {
+		t.Errorf("encoded % x, want % x", encoded, want)
+	}
+}
+
+func TestDecodeWithAndWithoutHeader(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("name", mysql.FieldTypeVarString)}
+	packet := rowPacket(1, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x02, 'o', 'k')
+
+	withHeader, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(packet) {
+		t.Errorf("DecodeBinaryRow read %d of %d bytes", n, len(packet))
+	}
+
+	payload := packet[4:]
+	withoutHeader, n, err := DecodeBinaryRowPayload(context.Background(), zap.NewNop(), payload, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(payload) {
+		t.Errorf("DecodeBinaryRowPayload read %d of %d bytes", n, len(payload))
+	}
+	if ok, diff := withHeader.Equal(withoutHeader, columns); !ok {
+		t.Errorf("the entry points decoded different rows: %s", diff)
+	}
+}
+
+func TestLatin1ValueKeepsItsBytes(t *testing.T) {
+	col := column("name", mysql.FieldTypeVarString)
+	col.CharacterSet = 8 // latin1_swedish_ci
+	columns := []*mysql.ColumnDefinition41{col}
+	// é in latin1, which isn't valid UTF-8
+	packet := rowPacket(1, 0x00, 0x00, 0x01, 0xe9)
+
+	row := assertRoundTrip(t, packet, columns)
+	if value, ok := row.Values[0].Value.([]byte); !ok || !bytes.Equal(value, []byte{0xe9}) {
+		t.Errorf("decoded %#v, want the raw byte 0xe9", row.Values[0].Value)
+	}
+
+	data, err := yaml.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mock mysql.BinaryRow
+	if err := yaml.Unmarshal(data, &mock); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), &mock, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("mock\n%s\nencoded % x, want % x", data, encoded, packet)
+	}
+}
+
+func TestBuildNullBitmapMatchesDecodedRows(t *testing.T) {
+	values := mixedRow().Values
+	for mask := 0; mask < 1<<len(values); mask += 5 {
+		row := &mysql.BinaryRow{Header: mysql.Header{SequenceID: 1}}
+		isNull := make([]bool, len(values))
+		for i, v := range values {
+			if mask&(1<<i) != 0 {
+				v.Value = nil
+				isNull[i] = true
+			}
+			row.Values = append(row.Values, v)
+		}
+		packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, mixedRowColumns, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, mixedRowColumns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bitmap := BuildNullBitmap(isNull); !bytes.Equal(bitmap, decoded.RowNullBuffer) {
+			t.Errorf("NULL columns %07b: built % x, decoded % x", mask, bitmap, decoded.RowNullBuffer)
+		}
+	}
+}
+
+func TestConformDecimalScale(t *testing.T) {
+	col := column("total", mysql.FieldTypeNewDecimal)
+	col.Decimals = 2
+	columns := []*mysql.ColumnDefinition41{col}
+	encode := func(value interface{}) ([]byte, error) {
+		row := &mysql.BinaryRow{Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeNewDecimal, Name: "total", Value: value}}}
+		return EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, EncodeOptions{RecomputeNullBitmap: true, ConformDecimalScale: true})
+	}
+
+	for value, want := range map[string]string{"10": "10.00", "-10.5": "-10.50", "10.500": "10.50"} {
+		encoded, err := encode(value)
+		if err != nil {
+			t.Errorf("%s: %v", value, err)
+			continue
+		}
+		if packet := rowPacket(0, append([]byte{0x00, 0x00, byte(len(want))}, want...)...); !bytes.Equal(encoded, packet) {
+			t.Errorf("%s encoded % x, want %s", value, encoded, want)
+		}
+	}
+	for _, value := range []string{"10.123", "1e3", "."} {
+		if _, err := encode(value); err == nil {
+			t.Errorf("encoding %q into a DECIMAL(_,2) succeeded", value)
+		}
+	}
+}
+
+func TestBinaryJSONKeepsItsBytes(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("doc", mysql.FieldTypeJSON)}
+	// the binary JSON form of {"a": 1} as sent in some modes, it contains 0x00 bytes
+	doc := []byte{0x00, 0x01, 0x00, 0x0c, 0x00, 0x0b, 0x00, 0x01, 0x00, 0x05, 0x01, 0x00, 0x61}
+	row := assertRoundTrip(t, rowPacket(1, append([]byte{0x00, 0x00, byte(len(doc))}, doc...)...), columns)
+	if value, ok := row.Values[0].Value.([]byte); !ok || !bytes.Equal(value, doc) {
+		t.Errorf("decoded %#v, want the raw bytes", row.Values[0].Value)
+	}
+}
+
+func TestTransformerUppercasesColumn(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("email", mysql.FieldTypeVarString)}
+	packet := rowPacket(1, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x05, 'a', '@', 'b', '.', 'c')
+	upper := func(col *mysql.ColumnDefinition41, value interface{}) interface{} {
+		if s, ok := value.(string); ok && col.Name == "email" {
+			return strings.ToUpper(s)
+		}
+		return value
+	}
+
+	row, _, err := DecodeBinaryRowWithTransformer(context.Background(), zap.NewNop(), packet, columns, upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := rowPacket(1, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x05, 'A', '@', 'B', '.', 'C'); !bytes.Equal(encoded, want) {
+		t.Errorf("encoded % x, want % x", encoded, want)
+	}
+}
+
+func TestDecodeOptionsCombined(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("price", mysql.FieldTypeDouble), column("name", mysql.FieldTypeVarString)}
+	payload := []byte{0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0x02, 'o', 'k'}
+	binary.LittleEndian.PutUint64(payload[2:], math.Float64bits(0.1))
+	packet := rowPacket(1, payload...)
+	opts := DecodeOptions{RawFloats: true, ZeroCopy: true, VerifyLength: true}
+
+	row, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(row.Values[0].Raw, payload[2:10]) {
+		t.Errorf("raw float bytes % x, want % x", row.Values[0].Raw, payload[2:10])
+	}
+	if _, ok := row.Values[1].Value.([]byte); !ok {
+		t.Errorf("name decoded as %T, want a []byte view", row.Values[1].Value)
+	}
+
+	// a payload length that disagrees with the columns fails with VerifyLength
+	long := rowPacket(1, append(payload, 0x00)...)
+	if _, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), long, columns, opts); err == nil {
+		t.Error("a payload with a byte left over was accepted")
+	}
+}
+
+func TestNullBitmapOffsets(t *testing.T) {
+	isNull := []bool{true, false, false, false, false, false, true, true}
+	for _, c := range []struct {
+		offset int
+		bitmap []byte
+	}{
+		// resultset rows reserve the first 2 bits
+		{resultSetNullBitmapOffset, []byte{0x04, 0x03}},
+		// COM_STMT_EXECUTE parameters reserve none
+		{paramsNullBitmapOffset, []byte{0xc1}},
+	} {
+		bitmap := buildNullBitmapAt(isNull, c.offset)
+		if !bytes.Equal(bitmap, c.bitmap) {
+			t.Errorf("offset %d: built % x, want % x", c.offset, bitmap, c.bitmap)
+		}
+		for i, null := range isNull {
+			if isNullAt(bitmap, i, c.offset) != null {
+				t.Errorf("offset %d: isNullAt(%d) = %t, want %t", c.offset, i, !null, null)
+			}
+		}
+	}
+}
+
+func TestMidnightDateTimeUsesDateOnlyForm(t *testing.T) {
+	dateOnly := []byte{0x04, 0xe8, 0x07, 0x02, 0x1d}
+	for _, ft := range []mysql.FieldType{mysql.FieldTypeDateTime, mysql.FieldTypeTimestamp} {
+		for _, value := range []string{"2024-02-29 00:00:00", "2024-02-29 00:00:00.000000"} {
+			var buf bytes.Buffer
+			if err := EncodeColumnValue(&buf, value, ft, false); err != nil {
+				t.Fatalf("encoding %q as %v: %v", value, ft, err)
+			}
+			if !bytes.Equal(buf.Bytes(), dateOnly) {
+				t.Errorf("%q encoded as %v to % x, want % x", value, ft, buf.Bytes(), dateOnly)
+			}
+		}
+
+		columns := []*mysql.ColumnDefinition41{column("t", ft)}
+		row := assertRoundTrip(t, rowPacket(1, append([]byte{0x00, 0x00}, dateOnly...)...), columns)
+		if row.Values[0].Value != "2024-02-29 00:00:00" {
+			t.Errorf("4 byte %v decoded as %#v, want midnight", ft, row.Values[0].Value)
+		}
+	}
+
+	// a single microsecond past midnight needs the full form
+	var buf bytes.Buffer
+	if err := EncodeColumnValue(&buf, "2024-02-29 00:00:00.000001", mysql.FieldTypeDateTime, false); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 12 {
+		t.Errorf("encoded to % x, want the 11 byte form", buf.Bytes())
+	}
+}
+
+func TestVerifyLengthRejectsDisagreeingLength(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	row := []byte{0x00, 0x00, 0x2a, 0x00, 0x00, 0x00}
+
+	// the header claims one byte less than the id takes, the last id byte is read from
+	// past the payload unless the length is verified
+	short := append([]byte{byte(len(row) - 1), 0x00, 0x00, 0x01}, row...)
+	if _, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), short, columns); err != nil || n != len(short) {
+		t.Errorf("decoding without VerifyLength consumed %d bytes: %v", n, err)
+	}
+	var decodeErr *DecodeError
+	if _, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), short, columns, DecodeOptions{VerifyLength: true}); !errors.As(err, &decodeErr) {
+		t.Errorf("decoding a row overrunning its payload returned %v, want a *DecodeError", err)
+	}
+
+	// the header claims two bytes more than the id takes
+	long := append([]byte{byte(len(row) + 2), 0x00, 0x00, 0x01}, append(row, 0x00, 0x00)...)
+	for _, opts := range []DecodeOptions{{}, {VerifyLength: true}} {
+		_, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), long, columns, opts)
+		if !errors.As(err, &decodeErr) || decodeErr.Offset != 4+len(row) {
+			t.Errorf("VerifyLength %v: decoding a row with bytes left over returned %v, want a *DecodeError at offset %d", opts.VerifyLength, err, 4+len(row))
+		}
+	}
+}
+
+func TestRawFloatsSurviveYAMLBitForBit(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("ratio", mysql.FieldTypeFloat), column("price", mysql.FieldTypeDouble)}
+	packet := rowPacket(1, 0x00, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	// 0.1 has no exact binary representation, the NaN carries a payload its text form loses
+	binary.LittleEndian.PutUint32(packet[6:], math.Float32bits(0.1))
+	binary.LittleEndian.PutUint64(packet[10:], 0x7ff8000000000123)
+
+	row, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{RawFloats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := yaml.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mock mysql.BinaryRow
+	if err := yaml.Unmarshal(data, &mock); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), &mock, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("mock\n%s\nencoded % x, want % x", data, encoded, packet)
+	}
+
+	// without the raw bytes only the NaN payload is lost
+	mock.Values[1].Raw = nil
+	encoded, err = EncodeBinaryRow(context.Background(), zap.NewNop(), &mock, columns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bits := binary.LittleEndian.Uint64(encoded[10:]); bits == 0x7ff8000000000123 || !math.IsNaN(math.Float64frombits(bits)) {
+		t.Errorf("NaN encoded without its raw bytes as %#x", bits)
+	}
+}
+
+func TestEncodeUsesCanonicalLengthPrefix(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("bio", mysql.FieldTypeVarString)}
+	value := strings.Repeat("b", 250)
+	// the server may send 250 with the valid but longer 0xfc prefix
+	recorded := rowPacket(1, append([]byte{0x00, 0x00, 0xfc, 0xfa, 0x00}, value...)...)
+
+	row, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), recorded, columns, DecodeOptions{VerifyLength: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rowPacket(1, append([]byte{0x00, 0x00, 0xfa}, value...)...)
+	if !bytes.Equal(encoded, want) {
+		t.Errorf("encoded with prefix % x, want the 1 byte prefix fa", encoded[6:9])
+	}
+}
+
+func TestNewDateDecodesLikeDate(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("d", mysql.FieldTypeNewDate)}
+	row := assertRoundTrip(t, rowPacket(1, 0x00, 0x00, 0x04, 0xe8, 0x07, 0x02, 0x1d), columns)
+	if row.Values[0].Value != "2024-02-29" {
+		t.Errorf("decoded %#v, want 2024-02-29", row.Values[0].Value)
+	}
+
+	// the packed storage form of 2024-02-29, year<<9 | month<<5 | day, isn't a valid
+	// protocol value and must not be misread as a date
+	packed := rowPacket(1, 0x00, 0x00, 0x5d, 0xd0, 0x0f)
+	if _, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packed, columns); err == nil {
+		t.Error("decoding a packed NEWDATE value succeeded")
+	}
+}
+
+func TestFewerColumnsThanTheRow(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("age", mysql.FieldTypeLong),
+		column("name", mysql.FieldTypeVarString),
+	}
+	packet := rowPacket(1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00, 0x03, 'a', 'n', 'n')
+	assertRoundTrip(t, packet, columns)
+
+	_, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns[:2])
+	if !errors.Is(err, ErrColumnCountMismatch) {
+		t.Fatalf("decoding with 2 columns failed with %v, want a column count mismatch", err)
+	}
+	var de *DecodeError
+	// the left over bytes start after the second value
+	if !errors.As(err, &de) || de.Offset != 4+10 {
+		t.Errorf("error %v, want a DecodeError at offset 14", err)
+	}
+}
@@ -0,0 +1,106 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// DecodeBinaryResultSet lazily decodes the consecutive binary row packets in data, yielding
+// one row at a time until the EOF/OK packet terminating the resultset is reached. This avoids
+// holding every row of a large resultset in memory at once. Decoding stops with the context
+// error as soon as ctx is cancelled.
+func DecodeBinaryResultSet(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) iter.Seq2[*mysql.BinaryRow, error] {
+	return DecodeBinaryResultSetWithOptions(ctx, logger, data, columns, DecodeOptions{})
+}
+
+// DecodeBinaryResultSetWithOptions lazily decodes the row packets in data like
+// DecodeBinaryResultSet, decoding each row according to opts. With opts.SkipCorruptRows a
+// row that fails to decode is skipped, see ResyncToNextPacket.
+func DecodeBinaryResultSetWithOptions(ctx context.Context, logger *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41, opts DecodeOptions) iter.Seq2[*mysql.BinaryRow, error] {
+	return func(yield func(*mysql.BinaryRow, error) bool) {
+		if opts.WithoutHeader {
+			yield(nil, errors.New("the rows of a resultset must include the packet header"))
+			return
+		}
+
+		offset := 0
+		// the sequence id expected for the next row, once a row has been decoded
+		var nextSeq byte
+		haveSeq := false
+		for offset < len(data) {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			packet := data[offset:]
+			payload, n, err := readMultiPacketPayload(packet, opts.maxRowBytes())
+			if err != nil {
+				err = fmt.Errorf("malformed binary resultset: packet at offset %d: %w", offset, err)
+			} else {
+				packet = packet[:n]
+
+				if len(payload) > 0 && payload[0] == mysql.ERR {
+					yield(nil, fmt.Errorf("binary resultset terminated by an ERR packet at offset %d", offset))
+					return
+				}
+
+				var row *mysql.BinaryRow
+				row, _, err = DecodeBinaryRowWithOptions(ctx, logger, packet, columns, opts)
+				if errors.Is(err, ErrResultSetEnd) {
+					return
+				}
+				if err == nil {
+					if !yield(row, nil) {
+						return
+					}
+					// a row split into several packets takes a sequence id for each of them
+					nextSeq, haveSeq = row.Header.SequenceID+byte(packetCount(len(payload))), true
+					offset += len(packet)
+					continue
+				}
+			}
+
+			if !opts.SkipCorruptRows {
+				yield(nil, err)
+				return
+			}
+			skip, ok := resyncResultSet(data[offset:], nextSeq, haveSeq)
+			if !ok {
+				yield(nil, fmt.Errorf("no packet to resume decoding at after the corrupt row: %w", err))
+				return
+			}
+			logger.Warn("skipped a corrupt row of the binary resultset",
+				zap.Int("offset", offset), zap.Int("skippedBytes", skip),
+				zap.String("skipped", hex.EncodeToString(data[offset:offset+skip])), zap.Error(err))
+			offset += skip
+		}
+		yield(nil, fmt.Errorf("binary resultset is not terminated by an EOF/OK packet: %w", io.ErrUnexpectedEOF))
+	}
+}
+
+// resyncResultSet finds the packet to resume decoding the resultset at after the corrupt
+// packet at the start of data. Garbage between two rows is followed by the row with the next
+// sequence id, while a corrupt row with an intact header is followed by the one after it.
+func resyncResultSet(data []byte, nextSeq byte, haveSeq bool) (int, bool) {
+	if !haveSeq {
+		if len(data) < 4 {
+			return 0, false
+		}
+		// trust the header of the corrupt packet when no row has been decoded yet
+		return ResyncToNextPacket(data, data[3]+1)
+	}
+	if offset, ok := ResyncToNextPacket(data, nextSeq); ok {
+		return offset, true
+	}
+	return ResyncToNextPacket(data, nextSeq+1)
+}
@@ -0,0 +1,99 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestDecodeBinaryResultSet(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	var data []byte
+	for seq := byte(1); seq <= 3; seq++ {
+		data = append(data, rowPacket(seq, 0x00, 0x00, seq, 0x00, 0x00, 0x00)...)
+	}
+	data = append(data, rowPacket(4, mysql.EOF, 0x00, 0x00, 0x02, 0x00)...)
+
+	var ids []interface{}
+	for row, err := range DecodeBinaryResultSet(context.Background(), zap.NewNop(), data, columns) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, row.Values[0].Value)
+	}
+	if len(ids) != 3 || ids[0] != int32(1) || ids[1] != int32(2) || ids[2] != int32(3) {
+		t.Errorf("decoded ids %v, want [1 2 3]", ids)
+	}
+}
+
+func TestSkipCorruptRowAfterMultiPacketRow(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("data", mysql.FieldTypeLongBLOB)}
+	encode := func(seq byte, value string) []byte {
+		row := &mysql.BinaryRow{
+			Header: mysql.Header{SequenceID: seq},
+			Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeLongBLOB, Name: "data", Value: value}},
+		}
+		packet, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, EncodeOptions{RecomputeNullBitmap: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return packet
+	}
+
+	var data []byte
+	data = append(data, encode(1, "first")...)
+	// split into the packets 2 and 3
+	data = append(data, encode(2, strings.Repeat("a", maxPacketPayload))...)
+	// a corrupt row with an intact header, its value is cut short
+	data = append(data, rowPacket(4, 0x00, 0x00, 0x05, 'a')...)
+	data = append(data, encode(5, "last")...)
+	data = append(data, rowPacket(6, mysql.EOF, 0x00, 0x00, 0x02, 0x00)...)
+
+	var sizes []int
+	for row, err := range DecodeBinaryResultSetWithOptions(context.Background(), zap.NewNop(), data, columns, DecodeOptions{SkipCorruptRows: true}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, len(row.Values[0].Value.(string)))
+	}
+	if len(sizes) != 3 || sizes[0] != 5 || sizes[1] != maxPacketPayload || sizes[2] != 4 {
+		t.Errorf("decoded values of %v bytes, want [5 %d 4]", sizes, maxPacketPayload)
+	}
+}
+
+func TestDecodeBinaryResultSetStopsWhenCancelled(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	var data []byte
+	for seq := byte(1); seq <= 3; seq++ {
+		data = append(data, rowPacket(seq, 0x00, 0x00, seq, 0x00, 0x00, 0x00)...)
+	}
+	data = append(data, rowPacket(4, mysql.EOF, 0x00, 0x00, 0x02, 0x00)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows := 0
+	var lastErr error
+	for row, err := range DecodeBinaryResultSet(ctx, zap.NewNop(), data, columns) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rows++
+		if row.Values[0].Value != int32(1) {
+			t.Errorf("decoded %#v, want the first row", row.Values[0].Value)
+		}
+		cancel()
+	}
+	if rows != 1 {
+		t.Errorf("decoded %d rows after cancelling, want 1", rows)
+	}
+	if !errors.Is(lastErr, context.Canceled) {
+		t.Errorf("decoding ended with %v, want context.Canceled", lastErr)
+	}
+}
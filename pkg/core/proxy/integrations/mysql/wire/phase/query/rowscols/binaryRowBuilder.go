@@ -0,0 +1,54 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// NewBinaryRow builds a binary resultset row from values keyed by column name, ordering them
+// like columns. Columns missing from values, or mapped to nil, are NULL. Each value is checked
+// to be encodable for the type of its column. The header is a placeholder, the payload length
+// is computed by EncodeBinaryRow and the sequence id can be set with RewriteSequenceIDs.
+func NewBinaryRow(columns []*mysql.ColumnDefinition41, values map[string]interface{}) (*mysql.BinaryRow, error) {
+	known := make(map[string]struct{}, len(columns))
+	for _, col := range columns {
+		known[col.Name] = struct{}{}
+	}
+	for name := range values {
+		if _, ok := known[name]; !ok {
+			return nil, fmt.Errorf("no column named %q in the resultset", name)
+		}
+	}
+
+	row := &mysql.BinaryRow{
+		Values:     make([]mysql.ColumnEntry, len(columns)),
+		OkAfterRow: true,
+	}
+
+	var scratch bytes.Buffer
+	nulls := make([]bool, len(columns))
+	for i, col := range columns {
+		entry := mysql.ColumnEntry{
+			Type:     mysql.FieldType(col.Type),
+			Name:     col.Name,
+			Value:    values[col.Name],
+			Unsigned: col.Flags&mysql.UNSIGNED_FLAG != 0,
+		}
+		if entry.Value == nil {
+			nulls[i] = true
+		} else {
+			scratch.Reset()
+			if err := writeBinaryValue(&scratch, col, entry, &EncodeOptions{}); err != nil {
+				return nil, fmt.Errorf("invalid value for column %s: %w", col.Name, err)
+			}
+		}
+		row.Values[i] = entry
+	}
+	row.RowNullBuffer = BuildNullBitmap(nulls)
+
+	return row, nil
+}
@@ -0,0 +1,54 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestNewBinaryRowEncodes(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("name", mysql.FieldTypeVarString),
+		column("email", mysql.FieldTypeVarString),
+	}
+	row, err := NewBinaryRow(columns, map[string]interface{}{"name": "ann", "id": int32(42)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	// email, the third column, is NULL
+	want := rowPacket(0, 0x00, 0x10, 0x2a, 0x00, 0x00, 0x00, 0x03, 'a', 'n', 'n')
+	if !bytes.Equal(packet, want) {
+		t.Errorf("encoded % x, want % x", packet, want)
+	}
+
+	decoded, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if ok, diff := row.Equal(decoded, columns); !ok {
+		t.Errorf("decoded row differs: %s", diff)
+	}
+}
+
+func TestNewBinaryRowRejectsInvalidValues(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	for name, values := range map[string]map[string]interface{}{
+		"unknown column": {"id": 1, "nope": 2},
+		"wrong type":     {"id": []string{"1"}},
+	} {
+		if _, err := NewBinaryRow(columns, values); err == nil {
+			t.Errorf("%s: building the row succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// DecodeBinaryRowFrom reads a single binary row packet from r and decodes it like
+// DecodeBinaryRow, so that callers reading off a connection don't have to buffer the packet
+// themselves. It reads the 4 byte header and then exactly the payload it announces (and the
+// continuation packets of a row split across several packets), nothing past the row is
+// consumed. The returned length is the number of bytes read from r.
+func DecodeBinaryRowFrom(ctx context.Context, logger *zap.Logger, r io.Reader, columns []*mysql.ColumnDefinition41) (*mysql.BinaryRow, int, error) {
+	return DecodeBinaryRowFromWithOptions(ctx, logger, r, columns, DecodeOptions{})
+}
+
+// DecodeBinaryRowFromWithOptions reads a single binary row packet from r like
+// DecodeBinaryRowFrom and decodes it according to opts. The payload length announced by the
+// headers is checked against opts.MaxRowBytes before the payload is read.
+func DecodeBinaryRowFromWithOptions(ctx context.Context, logger *zap.Logger, r io.Reader, columns []*mysql.ColumnDefinition41, opts DecodeOptions) (*mysql.BinaryRow, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if opts.WithoutHeader {
+		return nil, 0, errors.New("rows read from a stream must include the packet header")
+	}
+
+	packet, err := readRowPacket(r, opts.maxRowBytes())
+	if err != nil {
+		return nil, len(packet), err
+	}
+
+	row, _, err := DecodeBinaryRowWithOptions(ctx, logger, packet, columns, opts)
+	if err != nil {
+		return nil, len(packet), err
+	}
+	return row, len(packet), nil
+}
+
+// readRowPacket reads the packet at the start of r, including its continuation packets when
+// the payload was split at maxPacketPayload, and returns it with its headers. A payload of
+// more than limit bytes fails with ErrRowTooLarge before it is read.
+func readRowPacket(r io.Reader, limit int) ([]byte, error) {
+	var packet []byte
+	payloadTotal := 0
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if len(packet) == 0 && errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return packet, fmt.Errorf("failed to read packet header: %w", unexpectedEOF(err))
+		}
+		payloadLength := int(utils.ReadUint24(header[:3]))
+		payloadTotal += payloadLength
+		if err := checkRowSize(payloadTotal, limit, len(packet)); err != nil {
+			return append(packet, header[:]...), err
+		}
+
+		start := len(packet)
+		packet = append(packet, header[:]...)
+		packet = append(packet, make([]byte, payloadLength)...)
+		if _, err := io.ReadFull(r, packet[start+4:]); err != nil {
+			return packet[:start+4], fmt.Errorf("failed to read packet payload of %d bytes: %w", payloadLength, unexpectedEOF(err))
+		}
+		if payloadLength < maxPacketPayload {
+			return packet, nil
+		}
+	}
+}
+
+// unexpectedEOF turns the io.EOF of a packet that ended before its header or payload was
+// complete into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
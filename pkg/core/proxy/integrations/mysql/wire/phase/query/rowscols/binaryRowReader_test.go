@@ -0,0 +1,82 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestDecodeBinaryRowFromOneByteReader(t *testing.T) {
+	first, err := EncodeBinaryRow(context.Background(), zap.NewNop(), mixedRow(), mixedRowColumns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := mixedRow()
+	second.Header.SequenceID = 2
+	second.Values[0].Value = int32(43)
+	secondPacket, err := EncodeBinaryRow(context.Background(), zap.NewNop(), second, mixedRowColumns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := iotest.OneByteReader(bytes.NewReader(append(first, secondPacket...)))
+	for i, want := range []*mysql.BinaryRow{mixedRow(), second} {
+		row, n, err := DecodeBinaryRowFrom(context.Background(), zap.NewNop(), r, mixedRowColumns)
+		if err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+		if n != len(first) {
+			t.Errorf("row %d: read %d bytes, want %d", i, n, len(first))
+		}
+		if ok, diff := want.Equal(row, mixedRowColumns); !ok || row.Header.SequenceID != want.Header.SequenceID {
+			t.Errorf("row %d differs: %s", i, diff)
+		}
+	}
+
+	if _, _, err := DecodeBinaryRowFrom(context.Background(), zap.NewNop(), r, mixedRowColumns); err != io.EOF {
+		t.Errorf("reading past the last row returned %v, want io.EOF", err)
+	}
+}
+
+func TestDecodeBinaryRowFromTruncatedPacket(t *testing.T) {
+	packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), mixedRow(), mixedRowColumns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := iotest.OneByteReader(bytes.NewReader(packet[:len(packet)-3]))
+	if _, _, err := DecodeBinaryRowFrom(context.Background(), zap.NewNop(), r, mixedRowColumns); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a truncated row returned %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestMaxRowBytes(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("doc", mysql.FieldTypeLongBLOB)}
+	// the header claims a payload of almost 16MB, nothing of it follows
+	header := []byte{0xfe, 0xff, 0xff, 0x01}
+	opts := DecodeOptions{MaxRowBytes: 1 << 20}
+
+	_, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), append(header, 0x00, 0x00), columns, opts)
+	if !errors.Is(err, ErrRowTooLarge) {
+		t.Errorf("decoding returned %v, want ErrRowTooLarge", err)
+	}
+
+	// the reader fails if the payload is read, the limit must be checked before
+	r := io.MultiReader(bytes.NewReader(header), iotest.ErrReader(errors.New("payload read")))
+	if _, _, err := DecodeBinaryRowFromWithOptions(context.Background(), zap.NewNop(), r, columns, opts); !errors.Is(err, ErrRowTooLarge) {
+		t.Errorf("decoding from a reader returned %v, want ErrRowTooLarge", err)
+	}
+
+	// a payload within the limit is decoded
+	packet := rowPacket(1, 0x00, 0x00, 0x03, 'a', 'b', 'c')
+	if _, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{MaxRowBytes: 6}); err != nil {
+		t.Errorf("decoding a row of exactly MaxRowBytes: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// RowWriter carries a binary row together with the column definitions it is encoded with,
+// which the row itself doesn't hold, so that it can be written straight to a connection as
+// an io.WriterTo.
+type RowWriter struct {
+	Row     *mysql.BinaryRow
+	Columns []*mysql.ColumnDefinition41
+	Options EncodeOptions
+	// Logger is passed to EncodeBinaryRowWithOptions, a no-op logger is used when it is nil.
+	Logger *zap.Logger
+}
+
+// NewRowWriter returns a RowWriter for the row and its columns with the default options.
+func NewRowWriter(row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41) *RowWriter {
+	return &RowWriter{Row: row, Columns: columns}
+}
+
+// ReadRowWriter reads a single binary row packet from r like DecodeBinaryRowFrom and returns
+// it as a RowWriter over columns, with the number of bytes read from r.
+func ReadRowWriter(ctx context.Context, logger *zap.Logger, r io.Reader, columns []*mysql.ColumnDefinition41) (*RowWriter, int64, error) {
+	row, n, err := DecodeBinaryRowFrom(ctx, logger, r, columns)
+	if err != nil {
+		return nil, int64(n), err
+	}
+	return &RowWriter{Row: row, Columns: columns, Logger: logger}, int64(n), nil
+}
+
+// WriteTo implements io.WriterTo, it encodes the row as a binary resultset row packet and
+// writes it to dst. The returned count is the number of bytes written, which is the length of
+// the packet unless the write failed.
+func (w *RowWriter) WriteTo(dst io.Writer) (int64, error) {
+	logger := w.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	packet, err := EncodeBinaryRowWithOptions(context.Background(), logger, w.Row, w.Columns, w.Options)
+	if err != nil {
+		return 0, err
+	}
+	n, err := dst.Write(packet)
+	if err != nil {
+		return int64(n), fmt.Errorf("failed to write binary row packet: %w", err)
+	}
+	if n != len(packet) {
+		return int64(n), io.ErrShortWrite
+	}
+	return int64(n), nil
+}
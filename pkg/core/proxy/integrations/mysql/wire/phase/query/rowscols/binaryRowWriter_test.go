@@ -0,0 +1,57 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRowWriterReturnsEncodedLength(t *testing.T) {
+	row := mixedRow()
+	packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, mixedRowColumns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conn bytes.Buffer
+	n, err := NewRowWriter(row, mixedRowColumns).WriteTo(&conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(packet)) || !bytes.Equal(conn.Bytes(), packet) {
+		t.Errorf("wrote %d bytes % x, want %d bytes % x", n, conn.Bytes(), len(packet), packet)
+	}
+
+	// the packet reads back into a RowWriter that writes it again
+	w, read, err := ReadRowWriter(context.Background(), zap.NewNop(), bytes.NewReader(packet), mixedRowColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != int64(len(packet)) {
+		t.Errorf("read %d bytes, want %d", read, len(packet))
+	}
+	conn.Reset()
+	if n, err := w.WriteTo(&conn); err != nil || n != int64(len(packet)) || !bytes.Equal(conn.Bytes(), packet) {
+		t.Errorf("rewrote %d bytes % x (%v), want % x", n, conn.Bytes(), err, packet)
+	}
+}
+
+// shortWriter accepts at most limit bytes per write.
+type shortWriter struct{ limit int }
+
+func (w shortWriter) Write(p []byte) (int, error) {
+	return min(len(p), w.limit), nil
+}
+
+func TestRowWriterShortWrite(t *testing.T) {
+	n, err := NewRowWriter(mixedRow(), mixedRowColumns).WriteTo(shortWriter{limit: 3})
+	if n != 3 || !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("short write returned %d, %v, want 3, io.ErrShortWrite", n, err)
+	}
+}
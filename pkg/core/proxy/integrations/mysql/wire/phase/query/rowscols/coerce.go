@@ -0,0 +1,176 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// CoerceValue converts v into the Go type a binary protocol value of the column is decoded
+// into (see GoTypeFor), which is the type EncodeBinaryRow expects. Mock values loaded from
+// yaml or json often have a different type, e.g. int or json.Number for an INT column or
+// float64 for a FLOAT column. Numbers are range checked against the column type, nil (NULL)
+// and values of unsupported field types are returned unchanged.
+func CoerceValue(col *mysql.ColumnDefinition41, v interface{}) (interface{}, error) {
+	return coerceValue(mysql.FieldType(col.Type), col.Flags&mysql.UNSIGNED_FLAG != 0, v)
+}
+
+func coerceValue(ft mysql.FieldType, unsigned bool, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch ft = baseFieldType(ft); ft {
+	case mysql.FieldTypeTiny, mysql.FieldTypeShort, mysql.FieldTypeYear, mysql.FieldTypeInt24, mysql.FieldTypeLong, mysql.FieldTypeLongLong:
+		return coerceInteger(ft, unsigned, v)
+
+	case mysql.FieldTypeFloat:
+		f, err := floatFromValue(v)
+		if err != nil {
+			return nil, err
+		}
+		// the shortest text of a float32 read back as float64 can be just above MaxFloat32,
+		// only values that round to infinity overflow
+		if f32 := float32(f); math.IsInf(float64(f32), 0) && !math.IsInf(f, 0) {
+			return nil, fmt.Errorf("value %v overflows float", f)
+		}
+		return float32(f), nil
+
+	case mysql.FieldTypeDouble:
+		return floatFromValue(v)
+
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeJSON, mysql.FieldTypeEnum, mysql.FieldTypeSet:
+		switch s := v.(type) {
+		case string, []byte:
+			return s, nil
+		case []interface{}:
+			return bytesFromValue(s)
+		}
+		return textFromValue(v)
+
+	case mysql.FieldTypeDecimal, mysql.FieldTypeNewDecimal:
+		return textFromValue(v)
+
+	case mysql.FieldTypeDate, mysql.FieldTypeNewDate:
+		if t, ok := v.(time.Time); ok {
+			return t.Format("2006-01-02"), nil
+		}
+		return textFromValue(v)
+
+	case mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime:
+		if t, ok := v.(time.Time); ok {
+			return t.Format("2006-01-02 15:04:05.999999"), nil
+		}
+		return textFromValue(v)
+
+	case mysql.FieldTypeTime:
+		return textFromValue(v)
+
+	case mysql.FieldTypeBit, mysql.FieldTypeGeometry:
+		return bytesFromValue(v)
+
+	default:
+		return v, nil
+	}
+}
+
+// coerceInteger converts v into the signed or unsigned integer type of the width of ft.
+func coerceInteger(ft mysql.FieldType, unsigned bool, v interface{}) (interface{}, error) {
+	bits := uint(integerWidth(ft)) * 8
+
+	if unsigned {
+		u, err := uintFromValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if bits < 64 && u > 1<<bits-1 {
+			return nil, fmt.Errorf("value %d overflows %d-bit unsigned integer", u, bits)
+		}
+		switch bits {
+		case 8:
+			return uint8(u), nil
+		case 16:
+			return uint16(u), nil
+		case 32:
+			return uint32(u), nil
+		default:
+			return u, nil
+		}
+	}
+
+	i, err := intFromValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if bits < 64 && (i < -(1<<(bits-1)) || i > 1<<(bits-1)-1) {
+		return nil, fmt.Errorf("value %d overflows %d-bit integer", i, bits)
+	}
+	switch bits {
+	case 8:
+		return int8(i), nil
+	case 16:
+		return int16(i), nil
+	case 32:
+		return int32(i), nil
+	default:
+		return i, nil
+	}
+}
+
+// floatFromValue converts a FLOAT or DOUBLE column value to float64.
+func floatFromValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case json.Number:
+		return parseFloat(string(v))
+	case string:
+		return parseFloat(v)
+	}
+	if u, ok := value.(uint64); ok {
+		return float64(u), nil
+	}
+	i, err := intFromValue(value)
+	if err != nil {
+		return 0, err
+	}
+	return float64(i), nil
+}
+
+func parseFloat(s string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse float from %q: %w", s, err)
+	}
+	return f, nil
+}
+
+// textFromValue converts a value sent as text (decimals, temporal values, strings) to a
+// string, numbers are formatted without exponent so that decimals keep their digits.
+func textFromValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case json.Number:
+		return string(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unexpected type %T", value)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestCoerceIntoLong(t *testing.T) {
+	col := column("n", mysql.FieldTypeLong)
+	for _, v := range []interface{}{int(-42), int64(-42), json.Number("-42"), "-42"} {
+		got, err := CoerceValue(col, v)
+		if err != nil {
+			t.Errorf("coercing %#v: %v", v, err)
+			continue
+		}
+		if got != int32(-42) {
+			t.Errorf("%#v coerced into %#v, want int32(-42)", v, got)
+		}
+
+		var buf bytes.Buffer
+		if err := EncodeColumnValue(&buf, v, mysql.FieldTypeLong, false); err != nil {
+			t.Errorf("encoding %#v: %v", v, err)
+		} else if want := []byte{0xd6, 0xff, 0xff, 0xff}; !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%#v encoded to % x, want % x", v, buf.Bytes(), want)
+		}
+	}
+
+	for _, v := range []interface{}{int64(1) << 31, json.Number("-2147483649"), "4.5", "forty-two", []int{1}} {
+		if got, err := CoerceValue(col, v); err == nil {
+			t.Errorf("%#v coerced into %#v, want an error", v, got)
+		}
+	}
+
+	unsignedCol := unsignedColumn("n", mysql.FieldTypeLong)
+	if got, err := CoerceValue(unsignedCol, json.Number("4294967295")); err != nil || got != uint32(4294967295) {
+		t.Errorf("coerced into %#v (%v), want uint32(4294967295)", got, err)
+	}
+	if _, err := CoerceValue(unsignedCol, -1); err == nil {
+		t.Error("coercing -1 into an unsigned column succeeded")
+	}
+}
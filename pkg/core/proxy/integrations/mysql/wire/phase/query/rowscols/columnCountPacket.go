@@ -4,29 +4,55 @@ package rowscols
 
 import (
 	"context"
-	"errors"
+	"fmt"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
 	"go.uber.org/zap"
 )
 
+//ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_text_resultset.html
+
+// DecodeColumnCount decodes the column count of a resultset from the payload of its first
+// packet, the packet header having already been stripped by the caller.
 func DecodeColumnCount(_ context.Context, _ *zap.Logger, data []byte) (uint64, error) {
+	columnCount, _, err := readColumnCount(data)
+	return columnCount, err
+}
 
-	// offset := 0
-	// pkt := &mysql.ColumnCount{
-	// 	Header: mysql.Header{
-	// 		PayloadLength: utils.GetPayloadLength(data[:3]),
-	// 		SequenceID:    data[3],
-	// 	},
-	// }
-	// offset += 4
-	// data = data[offset:]
-
-	// Parse the column count packet
-	columnCount, _, n := utils.ReadLengthEncodedInteger(data)
-	if n == 0 {
-		return 0, errors.New("invalid column count")
+// DecodeColumnCountPacket decodes the packet starting a resultset, including its 4 byte
+// header, like DecodeColumn and DecodeBinaryRow do for the packets following it. It returns
+// the column count and the number of bytes consumed. The payload must hold exactly the
+// length-encoded column count, the metadata_follows flag that follows it with
+// CLIENT_OPTIONAL_RESULTSET_METADATA isn't supported.
+func DecodeColumnCountPacket(_ context.Context, _ *zap.Logger, data []byte) (uint64, int, error) {
+	if len(data) < 5 {
+		return 0, 0, fmt.Errorf("column count packet too short: %d bytes", len(data))
+	}
+	payloadLength := int(utils.ReadUint24(data[:3]))
+	if len(data)-4 < payloadLength {
+		return 0, 4, fmt.Errorf("column count packet payload needs %d bytes, got %d", payloadLength, len(data)-4)
 	}
 
-	return columnCount, nil
+	columnCount, n, err := readColumnCount(data[4 : 4+payloadLength])
+	if err != nil {
+		return 0, 4, err
+	}
+	if n != payloadLength {
+		return 0, 4 + n, fmt.Errorf("column count packet has %d bytes left over after the column count", payloadLength-n)
+	}
+	return columnCount, 4 + payloadLength, nil
+}
+
+// readColumnCount reads the length-encoded column count at the start of payload and returns
+// it with the number of bytes it takes. A resultset has at least one column, and the count
+// can't be NULL.
+func readColumnCount(payload []byte) (uint64, int, error) {
+	columnCount, isNull, n, err := utils.ReadLengthEncodedInteger(payload)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid column count: %w", err)
+	}
+	if isNull || columnCount == 0 {
+		return 0, n, fmt.Errorf("invalid column count: 0x%02x is not the start of a resultset", payload[0])
+	}
+	return columnCount, n, nil
 }
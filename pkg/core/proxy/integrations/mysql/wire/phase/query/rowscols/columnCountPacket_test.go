@@ -0,0 +1,49 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestDecodeColumnCountPacket(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		packet []byte
+		want   uint64
+	}{
+		{"one byte", rowPacket(1, 0x03), 3},
+		{"largest one byte", rowPacket(1, 0xfa), 250},
+		// 300 columns need the 0xfc prefix and 2 bytes
+		{"two bytes", rowPacket(1, 0xfc, 0x2c, 0x01), 300},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// the first column definition follows the column count
+			data := append(append([]byte{}, tt.packet...), 0x1e, 0x00, 0x00, 0x02)
+			count, n, err := DecodeColumnCountPacket(context.Background(), zap.NewNop(), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.want || n != len(tt.packet) {
+				t.Errorf("decoded %d columns from %d bytes, want %d from %d", count, n, tt.want, len(tt.packet))
+			}
+		})
+	}
+}
+
+func TestDecodeColumnCountPacketRejectsInvalidPackets(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"header only":       {0x01, 0x00, 0x00, 0x01},
+		"truncated payload": {0x03, 0x00, 0x00, 0x01, 0xfc, 0x2c},
+		"zero columns":      rowPacket(1, 0x00),
+		"null":              rowPacket(1, 0xfb),
+		"bytes left over":   rowPacket(1, 0x03, 0x01),
+	} {
+		if _, _, err := DecodeColumnCountPacket(context.Background(), zap.NewNop(), data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
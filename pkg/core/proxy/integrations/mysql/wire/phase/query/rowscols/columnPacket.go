@@ -77,8 +77,14 @@ func DecodeColumn(_ context.Context, _ *zap.Logger, b []byte) (*mysql.ColumnDefi
 	packet.OrgName = string(orgName)
 	pos += n
 
-	// skip [0x0c] (length of fixed-length fields)
-	packet.FixedLength = 0x0c
+	// [0x0c] length of fixed-length fields, followed by the 12 bytes of fixed-length fields
+	if len(b) < pos+13 {
+		return nil, pos, fmt.Errorf("malformed column definition packet: expected 13 bytes of fixed-length fields, got %d", len(b)-pos)
+	}
+	packet.FixedLength = b[pos]
+	if packet.FixedLength != 0x0c {
+		return nil, pos, fmt.Errorf("malformed column definition packet: invalid length of fixed-length fields %#x", packet.FixedLength)
+	}
 	pos++
 
 	//character_set
@@ -102,23 +108,20 @@ func DecodeColumn(_ context.Context, _ *zap.Logger, b []byte) (*mysql.ColumnDefi
 	pos++
 
 	//filler [0x00][0x00]
-	packet.Filler = b[pos : pos+2]
+	packet.Filler = []byte{b[pos], b[pos+1]}
 	pos += 2
 
-	//if more data, command was field list
-	if packet.Header.PayloadLength > uint32(pos) {
-		//length of default value lenenc-int
-		defaultValueLength, _, n := utils.ReadLengthEncodedInteger(b[pos:])
-		pos += n
-
-		if pos+int(defaultValueLength) > len(b) {
-
-			return nil, pos, fmt.Errorf("malformed packet: %v", err)
+	// if there is more data in the payload, the definition carries the default values
+	// trailer (COM_FIELD_LIST responses), anything else would be misread as the next column
+	if pos < 4+int(packet.Header.PayloadLength) {
+		//default values string<lenenc>
+		defaultValue, _, n, err := utils.ReadLengthEncodedString(b[pos:])
+		if err != nil {
+			return nil, pos, fmt.Errorf("malformed column definition packet: failed to read default values: %w", err)
 		}
-
-		//default value string[$len]
-		packet.DefaultValue = string(b[pos:(pos + int(defaultValueLength))])
-		pos--
+		packet.HasDefaultValue = true
+		packet.DefaultValue = string(defaultValue)
+		pos += n
 	}
 
 	return packet, pos, nil
@@ -198,8 +201,8 @@ func EncodeColumn(_ context.Context, _ *zap.Logger, packet *mysql.ColumnDefiniti
 		return nil, fmt.Errorf("failed to write Filler: %w", err)
 	}
 
-	// Write DefaultValue if it exists
-	if packet.DefaultValue != "" {
+	// Write the default values trailer if it was present
+	if packet.HasDefaultValue || packet.DefaultValue != "" {
 		if err := utils.WriteLengthEncodedString(buf, packet.DefaultValue); err != nil {
 			return nil, fmt.Errorf("failed to write DefaultValue: %w", err)
 		}
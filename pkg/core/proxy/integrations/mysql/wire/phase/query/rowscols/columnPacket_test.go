@@ -0,0 +1,88 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// usersIDColumn is the definition of `id INT NOT NULL AUTO_INCREMENT PRIMARY KEY` as sent by
+// MySQL 8.0 for SELECT id FROM test.users.
+var usersIDColumn = []byte{
+	0x03, 'd', 'e', 'f',
+	0x04, 't', 'e', 's', 't',
+	0x05, 'u', 's', 'e', 'r', 's',
+	0x05, 'u', 's', 'e', 'r', 's',
+	0x02, 'i', 'd',
+	0x02, 'i', 'd',
+	0x0c,
+	0x3f, 0x00, // binary
+	0x0b, 0x00, 0x00, 0x00,
+	0x03,       // LONG
+	0x03, 0x42, // NOT_NULL, PRI_KEY, AUTO_INCREMENT, PART_KEY
+	0x00,
+	0x00, 0x00,
+}
+
+func TestDecodeCapturedColumnDefinition(t *testing.T) {
+	packet := rowPacket(2, usersIDColumn...)
+	col, n, err := DecodeColumn(context.Background(), zap.NewNop(), packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(packet) {
+		t.Errorf("decoded %d bytes of the %d byte packet", n, len(packet))
+	}
+	if col.Catalog != "def" || col.Schema != "test" || col.Table != "users" || col.OrgTable != "users" || col.Name != "id" || col.OrgName != "id" {
+		t.Errorf("decoded names %+v", col)
+	}
+	if col.CharacterSet != 0x3f || col.ColumnLength != 11 || mysql.FieldType(col.Type) != mysql.FieldTypeLong || col.Flags != 0x4203 || col.Decimals != 0 {
+		t.Errorf("decoded fixed-length fields %+v", col)
+	}
+	if col.HasDefaultValue {
+		t.Error("a resultset column definition was decoded with a default value")
+	}
+
+	encoded, err := EncodeColumn(context.Background(), zap.NewNop(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+
+	// every truncation fails instead of reading past the packet
+	for i := 0; i < len(packet); i++ {
+		if _, _, err := DecodeColumn(context.Background(), zap.NewNop(), packet[:i]); err == nil {
+			t.Errorf("decoding the first %d bytes succeeded", i)
+		}
+	}
+}
+
+func TestColumnDefinitionWithDefaultValues(t *testing.T) {
+	// COM_FIELD_LIST responses end the definition with the default value of the column
+	packet := rowPacket(2, append(bytes.Clone(usersIDColumn), 0x01, '0')...)
+	col, n, err := DecodeColumn(context.Background(), zap.NewNop(), packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(packet) {
+		t.Errorf("decoded %d bytes of the %d byte packet", n, len(packet))
+	}
+	if !col.HasDefaultValue || col.DefaultValue != "0" {
+		t.Errorf("decoded default value %q (present %t), want \"0\"", col.DefaultValue, col.HasDefaultValue)
+	}
+
+	encoded, err := EncodeColumn(context.Background(), zap.NewNop(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+}
@@ -0,0 +1,130 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestDecodeColumnValue(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		col  *mysql.ColumnDefinition41
+		data []byte
+		want interface{}
+		n    int
+	}{
+		{"tiny", column("v", mysql.FieldTypeTiny), []byte{0xff}, int8(-1), 1},
+		{"unsigned tiny", unsignedColumn("v", mysql.FieldTypeTiny), []byte{0xff}, uint8(255), 1},
+		{"short", column("v", mysql.FieldTypeShort), []byte{0x00, 0x80}, int16(-32768), 2},
+		{"year", column("v", mysql.FieldTypeYear), []byte{0xe8, 0x07}, int16(2024), 2},
+		{"int24", column("v", mysql.FieldTypeInt24), []byte{0xff, 0xff, 0x7f, 0x00}, int32(8388607), 4},
+		{"long", column("v", mysql.FieldTypeLong), []byte{0x2a, 0x00, 0x00, 0x00}, int32(42), 4},
+		{"unsigned longlong", unsignedColumn("v", mysql.FieldTypeLongLong), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, uint64(1<<64 - 1), 8},
+		{"float", column("v", mysql.FieldTypeFloat), []byte{0x00, 0x00, 0xc0, 0x3f}, float32(1.5), 4},
+		{"double", column("v", mysql.FieldTypeDouble), []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0xc0}, -2.5, 8},
+		{"decimal", column("v", mysql.FieldTypeNewDecimal), []byte{0x05, '1', '2', '.', '5', '0'}, "12.50", 6},
+		{"varstring", column("v", mysql.FieldTypeVarString), []byte{0x03, 'a', 'b', 'c'}, "abc", 4},
+		{"blob", column("v", mysql.FieldTypeBLOB), []byte{0x02, 0x00, 0xff}, []byte{0x00, 0xff}, 3},
+		{"bit", column("v", mysql.FieldTypeBit), []byte{0x01, 0x05}, []byte{0x05}, 2},
+		{"date", column("v", mysql.FieldTypeDate), []byte{0x04, 0xe8, 0x07, 0x02, 0x1d}, "2024-02-29", 5},
+		{"datetime", column("v", mysql.FieldTypeDateTime), []byte{0x07, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06}, "2024-02-29 13:45:06", 8},
+		{"time", column("v", mysql.FieldTypeTime), []byte{0x08, 0x01, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04}, "-0 02:03:04", 9},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			// the bytes of the next value aren't consumed
+			value, n, err := DecodeColumnValue(append(c.data, 0xaa, 0xbb), c.col)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(value, c.want) || n != c.n {
+				t.Errorf("decoded %#v using %d bytes, want %#v using %d", value, n, c.want, c.n)
+			}
+			// blobs that aren't valid UTF-8 are kept as bytes, see GoTypeFor
+			goType := GoTypeFor(mysql.FieldType(c.col.Type), c.col.Flags&mysql.UNSIGNED_FLAG != 0)
+			if _, isBytes := value.([]byte); !isBytes && reflect.TypeOf(value) != goType {
+				t.Errorf("decoded a %T, GoTypeFor reports %v", value, goType)
+			}
+
+			if _, _, err := DecodeColumnValue(c.data[:len(c.data)-1], c.col); err == nil {
+				t.Error("decoding a truncated value succeeded")
+			}
+		})
+	}
+
+	if _, _, err := DecodeColumnValue([]byte{0x01, 0x02}, column("v", mysql.FieldType(0xf0))); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("decoding a value of an unknown type returned %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestEncodeColumnValue(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		ft       mysql.FieldType
+		unsigned bool
+		value    interface{}
+		want     []byte
+	}{
+		{"tiny", mysql.FieldTypeTiny, false, int8(-1), []byte{0xff}},
+		{"unsigned tiny", mysql.FieldTypeTiny, true, uint8(255), []byte{0xff}},
+		{"short", mysql.FieldTypeShort, false, int16(-32768), []byte{0x00, 0x80}},
+		{"year", mysql.FieldTypeYear, false, int16(2024), []byte{0xe8, 0x07}},
+		{"int24", mysql.FieldTypeInt24, false, int32(8388607), []byte{0xff, 0xff, 0x7f, 0x00}},
+		{"long", mysql.FieldTypeLong, false, int32(42), []byte{0x2a, 0x00, 0x00, 0x00}},
+		{"longlong", mysql.FieldTypeLongLong, false, int64(-2), []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"unsigned longlong", mysql.FieldTypeLongLong, true, uint64(1<<64 - 1), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"float", mysql.FieldTypeFloat, false, float32(1.5), []byte{0x00, 0x00, 0xc0, 0x3f}},
+		{"double", mysql.FieldTypeDouble, false, -2.5, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0xc0}},
+		{"decimal", mysql.FieldTypeNewDecimal, false, "12.50", []byte{0x05, '1', '2', '.', '5', '0'}},
+		{"varstring", mysql.FieldTypeVarString, false, "abc", []byte{0x03, 'a', 'b', 'c'}},
+		{"blob", mysql.FieldTypeBLOB, false, []byte{0x00, 0xff}, []byte{0x02, 0x00, 0xff}},
+		{"bit", mysql.FieldTypeBit, false, []byte{0x05}, []byte{0x01, 0x05}},
+		{"date", mysql.FieldTypeDate, false, "2024-02-29", []byte{0x04, 0xe8, 0x07, 0x02, 0x1d}},
+		{"datetime", mysql.FieldTypeDateTime, false, "2024-02-29 13:45:06", []byte{0x07, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06}},
+		{"time", mysql.FieldTypeTime, false, "-0 02:03:04", []byte{0x08, 0x01, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeColumnValue(&buf, c.value, c.ft, c.unsigned); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), c.want) {
+				t.Errorf("encoded % x, want % x", buf.Bytes(), c.want)
+			}
+		})
+	}
+}
+
+func TestEncodeColumnValueWrongGoType(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		ft    mysql.FieldType
+		value interface{}
+	}{
+		{"slice as long", mysql.FieldTypeLong, []string{"1"}},
+		{"word as long", mysql.FieldTypeLong, "forty-two"},
+		{"overflowing tiny", mysql.FieldTypeTiny, 300},
+		{"bool as double", mysql.FieldTypeDouble, true},
+		{"map as varstring", mysql.FieldTypeVarString, map[string]int{"a": 1}},
+		{"bool as blob", mysql.FieldTypeBLOB, true},
+		{"bool as decimal", mysql.FieldTypeNewDecimal, false},
+		{"word as date", mysql.FieldTypeDate, "yesterday"},
+		{"struct as datetime", mysql.FieldTypeDateTime, struct{}{}},
+		{"word as time", mysql.FieldTypeTime, "noon"},
+	} {
+		var buf bytes.Buffer
+		if err := EncodeColumnValue(&buf, c.value, c.ft, false); err == nil {
+			t.Errorf("%s: encoding %#v succeeded with % x", c.name, c.value, buf.Bytes())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeColumnValue(&buf, []byte{0x01}, mysql.FieldType(0xf0), false); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("encoding a value of an unknown type returned %v, want ErrUnsupportedType", err)
+	}
+}
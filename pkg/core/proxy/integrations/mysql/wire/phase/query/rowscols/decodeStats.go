@@ -0,0 +1,80 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"fmt"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// DecodeStats accumulates counters about the rows decoded with DecodeBinaryRowWithOptions
+// when set in DecodeOptions.Stats. It is safe to share between connections, the counters
+// are read with Snapshot.
+type DecodeStats struct {
+	mu          sync.Mutex
+	rows        int64
+	bytes       int64
+	types       map[mysql.FieldType]int64
+	unsupported int64
+}
+
+// DecodeStatsSnapshot holds the counters of a DecodeStats at the time Snapshot was called.
+type DecodeStatsSnapshot struct {
+	// Rows is the number of rows decoded successfully
+	Rows int64
+	// Bytes is the number of packet bytes consumed by those rows
+	Bytes int64
+	// Types counts the non-NULL values decoded per field type
+	Types map[mysql.FieldType]int64
+	// Unsupported counts the values that couldn't be decoded because of their field type
+	Unsupported int64
+}
+
+// Snapshot returns a copy of the counters that later decodes don't change.
+func (s *DecodeStats) Snapshot() DecodeStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	types := make(map[mysql.FieldType]int64, len(s.types))
+	for ft, count := range s.types {
+		types[ft] = count
+	}
+	return DecodeStatsSnapshot{Rows: s.rows, Bytes: s.bytes, Types: types, Unsupported: s.unsupported}
+}
+
+func (s *DecodeStats) addRow(row *mysql.BinaryRow, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows++
+	s.bytes += int64(n)
+	if s.types == nil {
+		s.types = make(map[mysql.FieldType]int64)
+	}
+	for _, v := range row.Values {
+		if v.Value != nil {
+			s.types[v.Type]++
+		}
+	}
+}
+
+func (s *DecodeStats) addUnsupported() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsupported++
+}
+
+// LogSummary logs the accumulated counters, e.g. at the end of a recording session.
+func (s *DecodeStats) LogSummary(logger *zap.Logger) {
+	snapshot := s.Snapshot()
+	types := make(map[string]int64, len(snapshot.Types))
+	for ft, count := range snapshot.Types {
+		types[fmt.Sprintf("%#x", byte(ft))] = count
+	}
+	logger.Info("mysql binary row decode summary",
+		zap.Int64("rows", snapshot.Rows),
+		zap.Int64("bytes", snapshot.Bytes),
+		zap.Any("valuesPerType", types),
+		zap.Int64("unsupportedTypes", snapshot.Unsupported))
+}
@@ -0,0 +1,73 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestDecodeStatsCountsMixedResultSet(t *testing.T) {
+	packet, err := EncodeBinaryRow(context.Background(), zap.NewNop(), mixedRow(), mixedRowColumns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 7 columns of the mixed row all NULL, flagged by bits 2 to 8
+	nullPacket := rowPacket(2, 0x00, 0xfc, 0x01)
+	unsupportedColumns := []*mysql.ColumnDefinition41{column("v", mysql.FieldType(0x14))}
+	unsupportedPacket := rowPacket(3, 0x00, 0x00, 0x01)
+
+	stats := &DecodeStats{}
+	opts := DecodeOptions{Stats: stats}
+	const connections = 4
+	var wg sync.WaitGroup
+	for c := 0; c < connections; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, p := range [][]byte{packet, nullPacket} {
+				if _, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), p, mixedRowColumns, opts); err != nil {
+					t.Error(err)
+				}
+			}
+			if _, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), unsupportedPacket, unsupportedColumns, opts); !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("decoding field type 0x14 returned %v, want ErrUnsupportedType", err)
+			}
+			// read while the other connections decode
+			_ = stats.Snapshot()
+		}()
+	}
+	wg.Wait()
+
+	snapshot := stats.Snapshot()
+	if snapshot.Rows != 2*connections {
+		t.Errorf("Rows = %d, want %d", snapshot.Rows, 2*connections)
+	}
+	if want := int64(connections * (len(packet) + len(nullPacket))); snapshot.Bytes != want {
+		t.Errorf("Bytes = %d, want %d", snapshot.Bytes, want)
+	}
+	if snapshot.Unsupported != connections {
+		t.Errorf("Unsupported = %d, want %d", snapshot.Unsupported, connections)
+	}
+	for _, col := range mixedRowColumns {
+		if count := snapshot.Types[mysql.FieldType(col.Type)]; count != connections {
+			t.Errorf("Types[%v] = %d, want %d", mysql.FieldType(col.Type), count, connections)
+		}
+	}
+	if len(snapshot.Types) != len(mixedRowColumns) {
+		t.Errorf("Types has %d field types, want %d", len(snapshot.Types), len(mixedRowColumns))
+	}
+
+	// a snapshot doesn't change with later decodes
+	if _, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, mixedRowColumns, opts); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Rows != 2*connections || snapshot.Types[mysql.FieldTypeLong] != connections {
+		t.Errorf("snapshot changed to %+v", snapshot)
+	}
+}
@@ -0,0 +1,106 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// DumpResultSet writes the rows as an ASCII table, the way the mysql client prints a
+// resultset: one column per column definition, numbers right aligned, NULL values shown
+// as NULL and binary values as 0x prefixed hex, followed by the number of rows.
+func DumpResultSet(w io.Writer, rows []*mysql.BinaryRow, columns []*mysql.ColumnDefinition41) error {
+	header := make([]string, len(columns))
+	alignment := make([]int, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+		alignment[i] = tablewriter.ALIGN_LEFT
+		if isNumericType(mysql.FieldType(col.Type)) {
+			alignment[i] = tablewriter.ALIGN_RIGHT
+		}
+	}
+
+	// the table writer doesn't report write errors, keep the first one
+	ew := &errWriter{w: w}
+
+	table := tablewriter.NewWriter(ew)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader(header)
+	table.SetColumnAlignment(alignment)
+
+	for i, row := range rows {
+		if len(row.Values) != len(columns) {
+			return fmt.Errorf("row %d has %d values but the resultset has %d columns", i, len(row.Values), len(columns))
+		}
+		cells := make([]string, len(row.Values))
+		for j, v := range row.Values {
+			cells[j] = formatDumpValue(v.Value)
+		}
+		table.Append(cells)
+	}
+	table.Render()
+
+	switch len(rows) {
+	case 0:
+		fmt.Fprintln(ew, "Empty set")
+	case 1:
+		fmt.Fprintln(ew, "1 row in set")
+	default:
+		fmt.Fprintf(ew, "%d rows in set\n", len(rows))
+	}
+	return ew.err
+}
+
+// formatDumpValue renders a decoded value the way the mysql client prints it.
+func formatDumpValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case []interface{}:
+		// binary values read back from a yaml mock
+		if b, err := bytesFromValue(v); err == nil {
+			return "0x" + hex.EncodeToString(b)
+		}
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func isNumericType(ft mysql.FieldType) bool {
+	switch ft {
+	case mysql.FieldTypeTiny, mysql.FieldTypeShort, mysql.FieldTypeInt24, mysql.FieldTypeLong, mysql.FieldTypeLongLong, mysql.FieldTypeYear,
+		mysql.FieldTypeFloat, mysql.FieldTypeDouble, mysql.FieldTypeDecimal, mysql.FieldTypeNewDecimal:
+		return true
+	}
+	return false
+}
+
+// errWriter records the first error of the writes to w and drops the writes after it.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestDumpResultSetGolden(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("name", mysql.FieldTypeVarString),
+		column("price", mysql.FieldTypeNewDecimal),
+		column("score", mysql.FieldTypeDouble),
+		column("avatar", mysql.FieldTypeBLOB),
+		column("created_at", mysql.FieldTypeDateTime),
+	}
+	row := func(values ...interface{}) *mysql.BinaryRow {
+		r := &mysql.BinaryRow{}
+		for i, col := range columns {
+			r.Values = append(r.Values, mysql.ColumnEntry{Type: mysql.FieldType(col.Type), Name: col.Name, Value: values[i]})
+		}
+		return r
+	}
+	rows := []*mysql.BinaryRow{
+		row(int32(1), "ann", "9.99", 0.5, []byte{0xca, 0xfe}, "2024-02-29 13:45:06"),
+		row(int32(1042), nil, "10.00", nil, nil, "2024-03-01 00:00:00"),
+		// binary values as read back from a yaml mock
+		row(int32(-7), "", "0.01", 1e-07, []interface{}{0, 255}, nil),
+	}
+
+	var out bytes.Buffer
+	if err := DumpResultSet(&out, rows, columns); err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "dump", "mixed.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(golden) {
+		t.Errorf("dumped\n%s\nwant\n%s", out.String(), golden)
+	}
+
+	out.Reset()
+	if err := DumpResultSet(&out, nil, columns); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(out.Bytes(), []byte("Empty set\n")) {
+		t.Errorf("empty resultset dumped as\n%s", out.String())
+	}
+}
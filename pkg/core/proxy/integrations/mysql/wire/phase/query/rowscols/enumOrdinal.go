@@ -0,0 +1,116 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// enumLabels returns the labels given for the column if it is an ENUM or SET column, the
+// server sends them as strings flagged ENUM_FLAG or SET_FLAG.
+func enumLabels(labels map[string][]string, col *mysql.ColumnDefinition41) ([]string, bool) {
+	if len(labels) == 0 || !isEnumColumn(col) {
+		return nil, false
+	}
+	for name, l := range labels {
+		if strings.EqualFold(name, col.Name) {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+func isEnumColumn(col *mysql.ColumnDefinition41) bool {
+	switch mysql.FieldType(col.Type) {
+	case mysql.FieldTypeEnum, mysql.FieldTypeSet:
+		return true
+	}
+	return col.Flags&(mysql.ENUM_FLAG|mysql.SET_FLAG) != 0
+}
+
+func isSetColumn(col *mysql.ColumnDefinition41) bool {
+	return mysql.FieldType(col.Type) == mysql.FieldTypeSet || col.Flags&mysql.SET_FLAG != 0
+}
+
+// enumOrdinal returns the ordinal of the ENUM or SET value: the 1-based index of the label
+// of an ENUM, 0 for the empty string stored for an invalid value, and the bitmask of the
+// members of a SET, bit i for the label i.
+func enumOrdinal(col *mysql.ColumnDefinition41, labels []string, value interface{}) (uint64, error) {
+	text, err := textFromValue(value)
+	if err != nil {
+		return 0, err
+	}
+	if !isSetColumn(col) {
+		if text == "" {
+			return 0, nil
+		}
+		i := labelIndex(labels, text)
+		if i < 0 {
+			return 0, fmt.Errorf("value %q is not a label of ENUM column %s", text, col.Name)
+		}
+		return uint64(i + 1), nil
+	}
+
+	var mask uint64
+	if text == "" {
+		return mask, nil
+	}
+	for _, member := range strings.Split(text, ",") {
+		i := labelIndex(labels, member)
+		if i < 0 || i >= 64 {
+			return 0, fmt.Errorf("value %q is not a member of SET column %s", member, col.Name)
+		}
+		mask |= 1 << i
+	}
+	return mask, nil
+}
+
+// enumValue is the inverse of enumOrdinal, it returns the string the server sends for the
+// ordinal.
+func enumValue(col *mysql.ColumnDefinition41, labels []string, ordinal uint64) (string, error) {
+	if !isSetColumn(col) {
+		if ordinal == 0 {
+			return "", nil
+		}
+		if ordinal > uint64(len(labels)) {
+			return "", fmt.Errorf("ordinal %d is out of the %d labels of ENUM column %s", ordinal, len(labels), col.Name)
+		}
+		return labels[ordinal-1], nil
+	}
+
+	if bits.Len64(ordinal) > len(labels) {
+		return "", fmt.Errorf("bitmask %#x has members beyond the %d labels of SET column %s", ordinal, len(labels), col.Name)
+	}
+	members := make([]string, 0, bits.OnesCount64(ordinal))
+	for i := range labels {
+		if ordinal&(1<<i) != 0 {
+			members = append(members, labels[i])
+		}
+	}
+	return strings.Join(members, ","), nil
+}
+
+// labelIndex returns the index of the label, compared case-insensitively like the default
+// collations do, or -1.
+func labelIndex(labels []string, label string) int {
+	for i, l := range labels {
+		if strings.EqualFold(l, label) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isTextValue reports whether the value is a label rather than an ordinal, binary values
+// read back from a yaml mock are lists of ints.
+func isTextValue(value interface{}) bool {
+	switch value.(type) {
+	case string, []byte, []interface{}:
+		return true
+	}
+	return false
+}
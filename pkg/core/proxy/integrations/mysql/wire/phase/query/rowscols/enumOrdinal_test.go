@@ -0,0 +1,93 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestEnumColumnLabelsAndOrdinals(t *testing.T) {
+	// the server sends ENUM values as strings flagged ENUM_FLAG
+	col := column("color", mysql.FieldTypeString)
+	col.Flags |= mysql.ENUM_FLAG
+	columns := []*mysql.ColumnDefinition41{col}
+	labels := map[string][]string{"COLOR": {"red", "green", "blue"}}
+	packet := rowPacket(1, 0x00, 0x00, 0x05, 'g', 'r', 'e', 'e', 'n')
+
+	row, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Values[0].Value != "green" {
+		t.Errorf("decoded %#v, want the label", row.Values[0].Value)
+	}
+
+	ordinalRow, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{EnumLabels: labels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ordinalRow.Values[0].Value != uint64(2) {
+		t.Errorf("decoded %#v with EnumLabels, want the ordinal 2", ordinalRow.Values[0].Value)
+	}
+
+	for _, value := range []interface{}{"green", uint64(2), 2} {
+		row := &mysql.BinaryRow{
+			Header: mysql.Header{SequenceID: 1},
+			Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeString, Name: "color", Value: value}},
+		}
+		encoded, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, EncodeOptions{EnumLabels: labels, RecomputeNullBitmap: true})
+		if err != nil {
+			t.Fatalf("encode %#v: %v", value, err)
+		}
+		if !bytes.Equal(encoded, packet) {
+			t.Errorf("%#v encoded % x, want % x", value, encoded, packet)
+		}
+	}
+
+	// an ordinal can't be written back without the labels
+	if _, err := EncodeBinaryRow(context.Background(), zap.NewNop(), ordinalRow, columns, false); err == nil {
+		t.Error("encoding an ordinal without EnumLabels succeeded")
+	}
+}
+
+func TestSetColumnMembers(t *testing.T) {
+	// SET('a','b','c') sent as a string flagged SET_FLAG
+	col := column("flags", mysql.FieldTypeString)
+	col.Flags |= mysql.SET_FLAG
+	columns := []*mysql.ColumnDefinition41{col}
+	labels := map[string][]string{"flags": {"a", "b", "c"}}
+
+	for _, c := range []struct {
+		packet  []byte
+		value   string
+		ordinal uint64
+	}{
+		{rowPacket(1, 0x00, 0x00, 0x03, 'a', ',', 'c'), "a,c", 0b101},
+		{rowPacket(1, 0x00, 0x00, 0x00), "", 0},
+	} {
+		row := assertRoundTrip(t, c.packet, columns)
+		if row.Values[0].Value != c.value {
+			t.Errorf("decoded %#v, want %q", row.Values[0].Value, c.value)
+		}
+
+		ordinalRow, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), c.packet, columns, DecodeOptions{EnumLabels: labels})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ordinalRow.Values[0].Value != c.ordinal {
+			t.Errorf("%q decoded as %#v with EnumLabels, want %#b", c.value, ordinalRow.Values[0].Value, c.ordinal)
+		}
+		encoded, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), ordinalRow, columns, EncodeOptions{EnumLabels: labels})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, c.packet) {
+			t.Errorf("bitmask %#b encoded % x, want % x", c.ordinal, encoded, c.packet)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// LintResultSetMock checks the recorded rows of a binary resultset against its columns so
+// that a malformed mock is caught before it is replayed. Every row is checked for the number
+// of its values, the agreement of its NULL bitmap with them and the support of their types,
+// then re-encoded: the payload length recorded in its header must match the encoded one and
+// the encoded packet must decode back into the same values. All the problems found are
+// returned, each prefixed with the index of its row, nil when there are none.
+func LintResultSetMock(rows []*mysql.BinaryRow, columns []*mysql.ColumnDefinition41) []error {
+	var problems []error
+	for i, row := range rows {
+		for _, err := range lintRow(row, columns) {
+			problems = append(problems, fmt.Errorf("row %d: %w", i, err))
+		}
+	}
+	return problems
+}
+
+func lintRow(row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41) []error {
+	if row == nil {
+		return []error{errors.New("row is nil")}
+	}
+	if len(row.Values) != len(columns) {
+		return []error{fmt.Errorf("row has %d values but the resultset has %d columns", len(row.Values), len(columns))}
+	}
+
+	var problems []error
+	if expected := nullBitmapFromValues(row.Values); !bytes.Equal(expected, row.RowNullBuffer) {
+		problems = append(problems, fmt.Errorf("null bitmap %#v doesn't match the row values, expected %#v", row.RowNullBuffer, expected))
+	}
+	for i, col := range columns {
+		entry := row.Values[i]
+		if entry.Type != mysql.FieldType(col.Type) {
+			problems = append(problems, fmt.Errorf("column %s: value has type %v but the column has type %v", col.Name, entry.Type, mysql.FieldType(col.Type)))
+		}
+		if !entry.Opaque && baseFieldType(mysql.FieldType(col.Type)) != mysql.FieldTypeNULL && GoTypeFor(mysql.FieldType(col.Type), false) == nil {
+			problems = append(problems, fmt.Errorf("column %s: %w: %v", col.Name, ErrUnsupportedType, mysql.FieldType(col.Type)))
+		}
+	}
+
+	// the bitmap was checked above, rebuild it so that the values are checked regardless
+	packet, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, EncodeOptions{RecomputeNullBitmap: true})
+	if err != nil {
+		return append(problems, fmt.Errorf("failed to encode row: %w", err))
+	}
+	if payloadLength := utils.ReadUint24(packet[:3]); payloadLength < maxPacketPayload && row.Header.PayloadLength != payloadLength {
+		problems = append(problems, fmt.Errorf("header payload length %d doesn't match the %d bytes the row encodes to", row.Header.PayloadLength, payloadLength))
+	}
+	decoded, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{CaptureUnsupported: true})
+	if err != nil {
+		return append(problems, fmt.Errorf("failed to decode the encoded row: %w", err))
+	}
+	if ok, diff := row.Equal(decoded, columns); !ok {
+		problems = append(problems, fmt.Errorf("row doesn't survive a round trip: %s", diff))
+	}
+	return problems
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestLintResultSetMock(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("name", mysql.FieldTypeVarString),
+		column("created_at", mysql.FieldTypeDateTime),
+	}
+	good, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(),
+		rowPacket(1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x03, 'a', 'n', 'n', 0x04, 0xe8, 0x07, 0x02, 0x1d), columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the name was edited to NULL by hand without updating the bitmap and the header
+	nulledName := good.Clone()
+	nulledName.Values[1].Value = nil
+	// the id column was recorded as BIGINT
+	wrongType := good.Clone()
+	wrongType.Values[0].Type = mysql.FieldTypeLongLong
+	// created_at was dropped from the row
+	missingValue := good.Clone()
+	missingValue.Values = missingValue.Values[:2]
+	// created_at was replaced by a value that isn't a datetime
+	badDate := good.Clone()
+	badDate.Values[2].Value = "yesterday"
+
+	problems := LintResultSetMock([]*mysql.BinaryRow{good, nulledName, wrongType, missingValue, badDate}, columns)
+	var messages []string
+	for _, err := range problems {
+		messages = append(messages, err.Error())
+	}
+	for _, want := range []string{
+		"row 1: null bitmap",
+		"row 1: header payload length 15",
+		"row 2: column id: value has type",
+		"row 3: row has 2 values but the resultset has 3 columns",
+		"row 4: failed to encode row",
+	} {
+		found := false
+		for _, msg := range messages {
+			found = found || strings.HasPrefix(msg, want)
+		}
+		if !found {
+			t.Errorf("no problem reported as %q in:\n%s", want, strings.Join(messages, "\n"))
+		}
+	}
+	for _, msg := range messages {
+		if strings.HasPrefix(msg, "row 0:") {
+			t.Errorf("problem reported for the valid row: %s", msg)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+)
+
+// maxPacketPayload is the largest payload a single MySQL packet can carry. Larger payloads
+// are split into packets of exactly this size followed by a shorter one (possibly empty).
+const maxPacketPayload = 0xFFFFFF
+
+// packetCount returns the number of packets a payload of n bytes is sent in, counting the
+// empty packet after a payload that is an exact multiple of maxPacketPayload.
+func packetCount(n int) int {
+	return n/maxPacketPayload + 1
+}
+
+// readMultiPacketPayload reassembles the payload of the logical packet at the start of data,
+// following the continuation packets of payloads that were split at maxPacketPayload. It
+// returns the payload and the number of bytes consumed. Payloads of more than limit bytes
+// fail with ErrRowTooLarge before they are reassembled.
+func readMultiPacketPayload(data []byte, limit int) ([]byte, int, error) {
+	var payload []byte
+	offset := 0
+	for {
+		if len(data)-offset < 4 {
+			return nil, offset, fmt.Errorf("truncated packet header at offset %d", offset)
+		}
+		payloadLength := int(utils.ReadUint24(data[offset : offset+3]))
+		if len(payload)+payloadLength > limit {
+			return nil, offset, fmt.Errorf("%w: payload of more than %d bytes", ErrRowTooLarge, limit)
+		}
+		offset += 4
+		if len(data)-offset < payloadLength {
+			return nil, offset, fmt.Errorf("packet payload needs %d bytes, got %d", payloadLength, len(data)-offset)
+		}
+
+		chunk := data[offset : offset+payloadLength]
+		offset += payloadLength
+		if payload == nil && payloadLength < maxPacketPayload {
+			// the common single packet case doesn't need a copy
+			return chunk, offset, nil
+		}
+		payload = append(payload, chunk...)
+		if payloadLength < maxPacketPayload {
+			return payload, offset, nil
+		}
+	}
+}
+
+// splitPayload frames payload into packets of at most maxPacketPayload bytes with consecutive
+// sequence ids starting at sequenceID. A payload that is an exact multiple of maxPacketPayload
+// is followed by an empty packet so that the reader knows it has ended.
+func splitPayload(payload []byte, sequenceID byte) []byte {
+	packets := make([]byte, 0, len(payload)+4*packetCount(len(payload)))
+	for {
+		n := min(len(payload), maxPacketPayload)
+		packets = append(packets, byte(n), byte(n>>8), byte(n>>16), sequenceID)
+		packets = append(packets, payload[:n]...)
+		payload = payload[n:]
+		sequenceID++
+		if n < maxPacketPayload {
+			return packets
+		}
+	}
+}
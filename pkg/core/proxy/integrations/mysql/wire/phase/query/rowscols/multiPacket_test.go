@@ -0,0 +1,53 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestRowLargerThanOnePacket(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("data", mysql.FieldTypeLongBLOB)}
+	blob := bytes.Repeat([]byte{0x00, 0xff}, 9<<20)
+	row := &mysql.BinaryRow{
+		Header: mysql.Header{SequenceID: 3},
+		Values: []mysql.ColumnEntry{
+			{Type: mysql.FieldTypeLong, Name: "id", Value: int32(1)},
+			{Type: mysql.FieldTypeLongBLOB, Name: "data", Value: blob},
+		},
+	}
+
+	packets, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OK byte, bitmap, id and the 9 byte length prefix of the blob
+	payloadLength := 1 + 1 + 4 + 9 + len(blob)
+	if len(packets) != payloadLength+8 {
+		t.Fatalf("encoded %d bytes, want the %d byte payload in 2 packets", len(packets), payloadLength)
+	}
+	if utils.ReadUint24(packets[:3]) != maxPacketPayload || packets[3] != 3 {
+		t.Errorf("first packet has length %d and sequence id %d", utils.ReadUint24(packets[:3]), packets[3])
+	}
+	second := packets[4+maxPacketPayload:]
+	if int(utils.ReadUint24(second[:3])) != payloadLength-maxPacketPayload || second[3] != 4 {
+		t.Errorf("second packet has length %d and sequence id %d", utils.ReadUint24(second[:3]), second[3])
+	}
+
+	decoded, n, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packets, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(packets) {
+		t.Errorf("decoded %d of the %d bytes", n, len(packets))
+	}
+	if ok, diff := row.Equal(decoded, columns); !ok {
+		t.Errorf("decoded row differs: %s", diff)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// Field types outside of the ones the server usually reports. The _2 temporal types are the
+// server's internal storage types, some capture points report them instead of their base
+// type and their values are decoded like the base type's (see baseFieldType). Rows recorded
+// before they were supported hold them as opaque bytes, like VECTOR values whose framing is
+// known but which have no dedicated support and are captured as opaque bytes when
+// DecodeOptions.CaptureUnsupported is set.
+const (
+	fieldTypeTimestamp2 mysql.FieldType = 0x11
+	fieldTypeDateTime2  mysql.FieldType = 0x12
+	fieldTypeTime2      mysql.FieldType = 0x13
+	fieldTypeVector     mysql.FieldType = 0xf2
+)
+
+// baseFieldType maps the internal _2 temporal types onto the type their values are sent as
+// in the binary protocol, other types are returned unchanged.
+func baseFieldType(ft mysql.FieldType) mysql.FieldType {
+	switch ft {
+	case fieldTypeTimestamp2:
+		return mysql.FieldTypeTimestamp
+	case fieldTypeDateTime2:
+		return mysql.FieldTypeDateTime
+	case fieldTypeTime2:
+		return mysql.FieldTypeTime
+	default:
+		return ft
+	}
+}
+
+// readOpaqueValue reads the raw bytes of a value of an unsupported field type. Temporal types
+// are framed by a single length byte like DATETIME and TIME, the others by a length-encoded
+// integer like strings. An error wrapping ErrUnsupportedType is returned when the framing of
+// the type is unknown.
+func readOpaqueValue(data []byte, ft mysql.FieldType) ([]byte, int, error) {
+	switch ft {
+	case fieldTypeTimestamp2, fieldTypeDateTime2, fieldTypeTime2:
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return nil, 0, fmt.Errorf("malformed value of field type %#x", byte(ft))
+		}
+		raw := make([]byte, data[0])
+		copy(raw, data[1:1+int(data[0])])
+		return raw, 1 + int(data[0]), nil
+	case fieldTypeVector:
+		value, _, n, err := utils.ReadLengthEncodedString(data)
+		if err != nil {
+			return nil, n, err
+		}
+		raw := make([]byte, len(value))
+		copy(raw, value)
+		return raw, n, nil
+	default:
+		return nil, 0, fmt.Errorf("%w: %#x, the length of its values can't be determined", ErrUnsupportedType, byte(ft))
+	}
+}
+
+// writeOpaqueValue writes back a value captured by readOpaqueValue.
+func writeOpaqueValue(buf *bytes.Buffer, ft mysql.FieldType, value interface{}) error {
+	raw, err := bytesFromValue(value)
+	if err != nil {
+		return fmt.Errorf("invalid value type for opaque field: %w", err)
+	}
+	switch ft {
+	case fieldTypeTimestamp2, fieldTypeDateTime2, fieldTypeTime2:
+		if len(raw) > 0xff {
+			return fmt.Errorf("opaque value of %d bytes is too long for field type %#x", len(raw), byte(ft))
+		}
+		buf.WriteByte(byte(len(raw)))
+	case fieldTypeVector:
+		if err := utils.WriteLengthEncodedInteger(buf, uint64(len(raw))); err != nil {
+			return fmt.Errorf("failed to write length of opaque value: %w", err)
+		}
+	default:
+		return fmt.Errorf("%w: %#x", ErrUnsupportedType, byte(ft))
+	}
+	if _, err := buf.Write(raw); err != nil {
+		return fmt.Errorf("failed to write opaque value: %w", err)
+	}
+	return nil
+}
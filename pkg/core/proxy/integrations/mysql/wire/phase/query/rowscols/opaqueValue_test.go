@@ -0,0 +1,86 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestCaptureUnsupportedNextToSupportedColumn(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("embedding", fieldTypeVector),
+	}
+	vector := []byte{0x00, 0x00, 0x80, 0x3f, 0x00, 0x00, 0x00, 0x40}
+	packet := rowPacket(1, append([]byte{0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, byte(len(vector))}, vector...)...)
+
+	if _, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("decoding without CaptureUnsupported returned %v, want ErrUnsupportedType", err)
+	}
+
+	row, n, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{CaptureUnsupported: true})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n != len(packet) {
+		t.Errorf("decoded %d bytes of the %d byte packet", n, len(packet))
+	}
+	if row.Values[0].Opaque || row.Values[0].Value != int32(42) {
+		t.Errorf("id decoded as %#v, want the int32 42", row.Values[0])
+	}
+	if !row.Values[1].Opaque || !bytes.Equal(row.Values[1].Value.([]byte), vector) {
+		t.Errorf("embedding decoded as %#v, want the opaque bytes % x", row.Values[1], vector)
+	}
+
+	encoded, err := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, false)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+}
+
+func TestCaptureUnsupportedUnknownFraming(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("x", mysql.FieldType(0xf0))}
+	packet := rowPacket(1, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x01, 0x02)
+
+	_, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{CaptureUnsupported: true})
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("decode returned %v, want ErrUnsupportedType", err)
+	}
+	if !strings.Contains(err.Error(), "0xf0") {
+		t.Errorf("error %q doesn't name the field type", err)
+	}
+}
+
+func TestInternalTemporalTypes(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("updated_at", fieldTypeTimestamp2),
+		column("created_at", fieldTypeDateTime2),
+		column("elapsed", fieldTypeTime2),
+	}
+	packet := rowPacket(1,
+		0x00, 0x00,
+		0x07, 0xe8, 0x07, 0x02, 0x1d, 0x0d, 0x2d, 0x06,
+		0x04, 0xe8, 0x07, 0x03, 0x01,
+		0x08, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x03, 0x04,
+	)
+	if mysql.FieldType(columns[0].Type) != 17 {
+		t.Fatalf("FieldTypeTimestamp2 is %d, want 17", columns[0].Type)
+	}
+
+	row := assertRoundTrip(t, packet, columns)
+	for i, want := range []string{"2024-02-29 13:45:06", "2024-03-01 00:00:00", "1 02:03:04"} {
+		if v := row.Values[i]; v.Value != want || v.Opaque || v.Type != mysql.FieldType(columns[i].Type) {
+			t.Errorf("%s decoded as %#v, want %q with its reported type", v.Name, v, want)
+		}
+	}
+}
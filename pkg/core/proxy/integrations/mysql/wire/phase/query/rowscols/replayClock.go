@@ -0,0 +1,54 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// FixedClock returns an EncodeOptions.Clock that always returns t.
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time {
+		return t
+	}
+}
+
+// OffsetClock returns an EncodeOptions.Clock that returns the current time shifted by
+// offset, keeping the values increasing as the replay goes on.
+func OffsetClock(offset time.Duration) func() time.Time {
+	return func() time.Time {
+		return time.Now().Add(offset)
+	}
+}
+
+// isClockColumn reports whether the values of the column are replaced by the time of the
+// Clock of the options.
+func (o *EncodeOptions) isClockColumn(col *mysql.ColumnDefinition41) bool {
+	switch baseFieldType(mysql.FieldType(col.Type)) {
+	case mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime:
+	default:
+		return false
+	}
+	if col.Flags&mysql.ON_UPDATE_NOW_FLAG != 0 {
+		return true
+	}
+	for _, name := range o.ClockColumns {
+		if strings.EqualFold(name, col.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// clockValue formats t as the value of a TIMESTAMP or DATETIME column with the given
+// fractional seconds precision.
+func clockValue(t time.Time, decimals byte) string {
+	value := t.Format("2006-01-02 15:04:05")
+	if decimals > 0 && decimals <= 6 {
+		value += formatFraction(uint32(t.Nanosecond()/1000), decimals)
+	}
+	return value
+}
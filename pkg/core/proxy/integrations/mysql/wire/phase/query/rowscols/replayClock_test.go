@@ -0,0 +1,85 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestClockReplacesCurrentTimestampColumns(t *testing.T) {
+	updatedAt := column("updated_at", mysql.FieldTypeTimestamp)
+	updatedAt.Flags |= mysql.ON_UPDATE_NOW_FLAG
+	createdAt := column("created_at", mysql.FieldTypeDateTime)
+	createdAt.Decimals = 3
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		updatedAt,
+		createdAt,
+		column("birthday", mysql.FieldTypeDateTime),
+	}
+	recorded := "2024-01-02 03:04:05"
+	row := &mysql.BinaryRow{
+		Header: mysql.Header{SequenceID: 1},
+		Values: []mysql.ColumnEntry{
+			{Type: mysql.FieldTypeLong, Name: "id", Value: int32(1)},
+			{Type: mysql.FieldTypeTimestamp, Name: "updated_at", Value: recorded},
+			{Type: mysql.FieldTypeDateTime, Name: "created_at", Value: recorded + ".000"},
+			{Type: mysql.FieldTypeDateTime, Name: "birthday", Value: recorded},
+		},
+	}
+
+	now := time.Date(2026, 10, 15, 9, 30, 0, 250_000_000, time.UTC)
+	opts := EncodeOptions{RecomputeNullBitmap: true, Clock: FixedClock(now), ClockColumns: []string{"CREATED_AT"}}
+	packet, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"2026-10-15 09:30:00", "2026-10-15 09:30:00.250", recorded}
+	for i, w := range want {
+		if got := decoded.Values[i+1].Value; got != w {
+			t.Errorf("%s = %v, want %s", decoded.Values[i+1].Name, got, w)
+		}
+	}
+}
+
+func TestClockKeepsNullValues(t *testing.T) {
+	updatedAt := column("updated_at", mysql.FieldTypeTimestamp)
+	updatedAt.Flags |= mysql.ON_UPDATE_NOW_FLAG
+	columns := []*mysql.ColumnDefinition41{updatedAt}
+	row := &mysql.BinaryRow{
+		Header: mysql.Header{SequenceID: 1},
+		Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeTimestamp, Name: "updated_at", Value: nil}},
+	}
+
+	opts := EncodeOptions{RecomputeNullBitmap: true, Clock: FixedClock(time.Now())}
+	packet, err := EncodeBinaryRowWithOptions(context.Background(), zap.NewNop(), row, columns, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _, err := DecodeBinaryRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Values[0].Value != nil {
+		t.Errorf("updated_at = %v, want NULL", decoded.Values[0].Value)
+	}
+}
+
+func TestOffsetClockTracksCurrentTime(t *testing.T) {
+	clock := OffsetClock(-24 * time.Hour)
+	before := time.Now().Add(-24 * time.Hour)
+	got := clock()
+	if got.Before(before) || got.After(time.Now().Add(-24*time.Hour)) {
+		t.Errorf("offset clock returned %v, want a day before now", got)
+	}
+}
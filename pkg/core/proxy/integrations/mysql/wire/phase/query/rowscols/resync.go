@@ -0,0 +1,45 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+)
+
+// ResyncToNextPacket scans data for the next plausible packet boundary after a corrupt
+// packet at its start. A boundary is plausible when the header there carries expectedSeq and
+// a non-empty payload that fits into data, and the packet either ends exactly at the end of
+// data or is followed by the header of a packet with the next sequence id that fits as well.
+// The returned offset is at least 1 so that the corrupt packet is always skipped, ok is false
+// when no boundary was found.
+func ResyncToNextPacket(data []byte, expectedSeq byte) (offset int, ok bool) {
+	for offset = 1; offset+4 <= len(data); offset++ {
+		if data[offset+3] != expectedSeq {
+			continue
+		}
+		end, ok := packetEnd(data, offset)
+		if !ok {
+			continue
+		}
+		if end == len(data) {
+			return offset, true
+		}
+		if end+4 <= len(data) && data[end+3] == expectedSeq+1 {
+			if _, ok := packetEnd(data, end); ok {
+				return offset, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// packetEnd returns the end of the packet whose header is at offset, if its payload is not
+// empty and fits into data.
+func packetEnd(data []byte, offset int) (int, bool) {
+	payloadLength := int(utils.ReadUint24(data[offset : offset+3]))
+	end := offset + 4 + payloadLength
+	if payloadLength == 0 || end > len(data) {
+		return 0, false
+	}
+	return end, true
+}
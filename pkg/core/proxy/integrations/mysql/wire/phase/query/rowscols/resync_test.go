@@ -0,0 +1,57 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// garbage is a run of bytes that don't start a plausible packet, like the bytes left by a
+// proxy that dropped part of a stream.
+var garbage = []byte{0xde, 0xad, 0xbe, 0xef, 0x13, 0x37, 0x00, 0x07}
+
+func TestResyncToNextPacket(t *testing.T) {
+	next := rowPacket(2, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00)
+	data := append(append([]byte{}, garbage...), next...)
+
+	offset, ok := ResyncToNextPacket(data, 2)
+	if !ok || offset != len(garbage) {
+		t.Errorf("resynced at %d (%v), want %d", offset, ok, len(garbage))
+	}
+	if _, ok := ResyncToNextPacket(garbage, 2); ok {
+		t.Error("resynced in bytes without a packet")
+	}
+}
+
+func TestSkipGarbageBetweenRows(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong)}
+	var data []byte
+	data = append(data, rowPacket(1, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00)...)
+	data = append(data, garbage...)
+	data = append(data, rowPacket(2, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00)...)
+	data = append(data, rowPacket(3, mysql.EOF, 0x00, 0x00, 0x02, 0x00)...)
+
+	var ids []interface{}
+	for row, err := range DecodeBinaryResultSetWithOptions(context.Background(), zap.NewNop(), data, columns, DecodeOptions{SkipCorruptRows: true}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, row.Values[0].Value)
+	}
+	if len(ids) != 2 || ids[0] != int32(1) || ids[1] != int32(2) {
+		t.Errorf("decoded ids %v, want [1 2]", ids)
+	}
+
+	// without SkipCorruptRows the garbage ends the resultset with an error
+	failed := false
+	for _, err := range DecodeBinaryResultSet(context.Background(), zap.NewNop(), data, columns) {
+		failed = failed || err != nil
+	}
+	if !failed {
+		t.Error("expected an error for the garbage without SkipCorruptRows")
+	}
+}
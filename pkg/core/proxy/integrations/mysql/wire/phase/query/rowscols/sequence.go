@@ -0,0 +1,40 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// RewriteSequenceIDs renumbers the rows with consecutive sequence ids starting at start,
+// wrapping around after 255 like the server does, and returns the sequence id that follows
+// the last row. Each row is expected to fit into a single packet.
+func RewriteSequenceIDs(start byte, rows ...*mysql.BinaryRow) byte {
+	seq := start
+	for _, row := range rows {
+		if row == nil {
+			continue
+		}
+		row.Header.SequenceID = seq
+		seq++
+	}
+	return seq
+}
+
+// VerifySequenceIDs checks that the rows carry consecutive sequence ids starting at start
+// and reports the first gap found, clients drop the connection on an unexpected sequence id.
+func VerifySequenceIDs(start byte, rows ...*mysql.BinaryRow) error {
+	seq := start
+	for i, row := range rows {
+		if row == nil {
+			continue
+		}
+		if row.Header.SequenceID != seq {
+			return fmt.Errorf("row %d has sequence id %d, expected %d", i, row.Header.SequenceID, seq)
+		}
+		seq++
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+func TestVerifySequenceIDs(t *testing.T) {
+	rows := make([]*mysql.BinaryRow, 4)
+	for i := range rows {
+		rows[i] = &mysql.BinaryRow{}
+	}
+	if next := RewriteSequenceIDs(254, rows...); next != 2 {
+		t.Errorf("RewriteSequenceIDs returned %d, want 2 after wrapping around", next)
+	}
+	if err := VerifySequenceIDs(254, rows...); err != nil {
+		t.Errorf("consecutive sequence ids: %v", err)
+	}
+
+	// the last two rows swapped
+	rows[2], rows[3] = rows[3], rows[2]
+	if err := VerifySequenceIDs(254, rows...); err == nil {
+		t.Error("out-of-order sequence ids were accepted")
+	}
+}
@@ -5,8 +5,8 @@ package rowscols
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"time"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -15,7 +15,14 @@ import (
 
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_text_resultset_row.html
 
+// In the text protocol every column value, regardless of its type, is sent as a length-encoded
+// string and NULL is sent as 0xfb. The values are kept as-is so that they are written back unchanged.
+
 func DecodeTextRow(_ context.Context, _ *zap.Logger, data []byte, columns []*mysql.ColumnDefinition41) (*mysql.TextRow, int, error) {
+	if len(data) < 4 {
+		return nil, 0, errors.New("malformed text row packet: packet too short")
+	}
+
 	offset := 0
 	row := &mysql.TextRow{
 		Header: mysql.Header{
@@ -27,8 +34,11 @@ func DecodeTextRow(_ context.Context, _ *zap.Logger, data []byte, columns []*mys
 	offset += 4
 
 	for _, col := range columns {
-		dataLength := data[offset]
-		if dataLength == 0xfb { // NULL
+		if offset >= len(data) {
+			return nil, offset, fmt.Errorf("malformed text row packet: missing value for column %s", col.Name)
+		}
+
+		if data[offset] == 0xfb { // NULL
 			row.Values = append(row.Values, mysql.ColumnEntry{
 				Type:  mysql.FieldType(col.Type),
 				Name:  col.Name,
@@ -38,92 +48,30 @@ func DecodeTextRow(_ context.Context, _ *zap.Logger, data []byte, columns []*mys
 			continue
 		}
 
-		switch mysql.FieldType(col.Type) {
-		case mysql.FieldTypeDate:
-			data := data[offset+1:]
-			if dataLength < 10 || len(data) < int(dataLength) {
-				return nil, 0, fmt.Errorf("invalid date data length")
-			}
-			dateStr := string(data[:dataLength])
-			layout := "2006-01-02"
-			t, err := time.Parse(layout, dateStr)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to parse the date string")
-			}
-
-			year, month, day := t.Date()
-			row.Values = append(row.Values, mysql.ColumnEntry{
-				Type:  mysql.FieldType(col.Type),
-				Name:  col.Name,
-				Value: fmt.Sprintf("%04d-%02d-%02d", year, int(month), day),
-			})
-
-			offset += int(dataLength) + 1
-
-		case mysql.FieldTypeTime:
-			data := data[offset+1:]
-			if dataLength < 8 || len(data) < int(dataLength) {
-				return nil, 0, fmt.Errorf("invalid time data length")
-			}
-			timeStr := string(data[:dataLength])
-			layout := "15:04:05"
-			t, err := time.Parse(layout, timeStr)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to parse the time string")
-			}
-
-			hour, minute, second := t.Clock()
-			row.Values = append(row.Values, mysql.ColumnEntry{
-				Type:  mysql.FieldType(col.Type),
-				Name:  col.Name,
-				Value: fmt.Sprintf("%02d:%02d:%02d", hour, minute, second),
-			})
-
-			offset += int(dataLength) + 1
-
-		case mysql.FieldTypeDateTime, mysql.FieldTypeTimestamp:
-			data := data[offset+1:]
-			if dataLength < 19 || len(data) < int(dataLength) {
-				return nil, 0, fmt.Errorf("invalid datetime/timestamp data length")
-			}
-			dateTimeStr := string(data[:dataLength])
-			layout := "2006-01-02 15:04:05"
-			t, err := time.Parse(layout, dateTimeStr)
-			if err != nil {
-				return nil, 0, fmt.Errorf("failed to parse the datetime/timestamp string")
-			}
-
-			year, month, day := t.Date()
-			hour, minute, second := t.Clock()
-			row.Values = append(row.Values, mysql.ColumnEntry{
-				Type:  mysql.FieldType(col.Type),
-				Name:  col.Name,
-				Value: fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", year, int(month), day, hour, minute, second),
-			})
-
-			offset += int(dataLength) + 1
-
-		default:
-			value, _, n, err := utils.ReadLengthEncodedString(data[offset:])
-			if err != nil {
-				return nil, offset, err
-			}
-			row.Values = append(row.Values, mysql.ColumnEntry{
-				Type:  mysql.FieldType(col.Type),
-				Name:  col.Name,
-				Value: string(value),
-			})
-			offset += n
+		value, _, n, err := utils.ReadLengthEncodedString(data[offset:])
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to read value for column %s: %w", col.Name, err)
 		}
+		row.Values = append(row.Values, mysql.ColumnEntry{
+			Type:  mysql.FieldType(col.Type),
+			Name:  col.Name,
+			Value: string(value),
+		})
+		offset += n
 	}
 	return row, offset, nil
 }
 
 func EncodeTextRow(_ context.Context, _ *zap.Logger, row *mysql.TextRow, columns []*mysql.ColumnDefinition41) ([]byte, error) {
+	if len(row.Values) != len(columns) {
+		return nil, fmt.Errorf("text row has %d values but the resultset has %d columns", len(row.Values), len(columns))
+	}
+
 	buf := new(bytes.Buffer)
 
-	// Write the packet header
-	if err := utils.WriteUint24(buf, row.Header.PayloadLength); err != nil {
+	// Write the packet header, the payload length is a placeholder that is filled
+	// once the row is encoded as the stored value may be stale if the row was modified
+	if err := utils.WriteUint24(buf, 0); err != nil {
 		return nil, fmt.Errorf("failed to write PayloadLength: %w", err)
 	}
 	if err := buf.WriteByte(row.Header.SequenceID); err != nil {
@@ -141,66 +89,26 @@ func EncodeTextRow(_ context.Context, _ *zap.Logger, row *mysql.TextRow, columns
 			continue
 		}
 
-		switch row.Values[i].Type {
-		case mysql.FieldTypeDate:
-			dateValue, ok := value.(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for date field")
-			}
-
-			// Write the length of the date value
-			if err := buf.WriteByte(byte(len(dateValue))); err != nil {
-				return nil, fmt.Errorf("failed to write date length: %w", err)
-			}
-
-			// Write the date value
-			if _, err := buf.WriteString(dateValue); err != nil {
-				return nil, fmt.Errorf("failed to write date value: %w", err)
-			}
-
-		case mysql.FieldTypeTime:
-			timeValue, ok := value.(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for time field")
-			}
-
-			// Write the length of the time value
-			if err := buf.WriteByte(byte(len(timeValue))); err != nil {
-				return nil, fmt.Errorf("failed to write time length: %w", err)
-			}
-
-			// Write the time value
-			if _, err := buf.WriteString(timeValue); err != nil {
-				return nil, fmt.Errorf("failed to write time value: %w", err)
-			}
-
-		case mysql.FieldTypeDateTime, mysql.FieldTypeTimestamp:
-			dateTimeValue, ok := value.(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for datetime/timestamp field")
-			}
-
-			// Write the length of the datetime/timestamp value
-			if err := buf.WriteByte(byte(len(dateTimeValue))); err != nil {
-				return nil, fmt.Errorf("failed to write datetime/timestamp length: %w", err)
-			}
-
-			// Write the datetime/timestamp value
-			if _, err := buf.WriteString(dateTimeValue); err != nil {
-				return nil, fmt.Errorf("failed to write datetime/timestamp value: %w", err)
-			}
-
-		default:
-			// Write length-encoded string for other types
-			strValue, ok := value.(string)
-			if !ok {
-				return nil, fmt.Errorf("invalid value type for column %s", col.Name)
-			}
-			if err := utils.WriteLengthEncodedString(buf, strValue); err != nil {
-				return nil, fmt.Errorf("failed to write length-encoded string: %w", err)
-			}
+		strValue, ok := value.(string)
+		if !ok {
+			// values of numeric columns may be read back from the mock as numbers
+			strValue = fmt.Sprint(value)
+		}
+		if err := utils.WriteLengthEncodedString(buf, strValue); err != nil {
+			return nil, fmt.Errorf("failed to write value for column %s: %w", col.Name, err)
 		}
 	}
 
-	return buf.Bytes(), nil
+	// rows that don't fit into a single packet are split into several ones
+	if buf.Len()-4 >= maxPacketPayload {
+		return splitPayload(buf.Bytes()[4:], row.Header.SequenceID), nil
+	}
+
+	packet := buf.Bytes()
+	payloadLength := len(packet) - 4
+	packet[0] = byte(payloadLength)
+	packet[1] = byte(payloadLength >> 8)
+	packet[2] = byte(payloadLength >> 16)
+
+	return packet, nil
 }
@@ -0,0 +1,61 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/utils"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestTextRowRoundTrip(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{
+		column("id", mysql.FieldTypeLong),
+		column("name", mysql.FieldTypeVarString),
+		column("deleted_at", mysql.FieldTypeDateTime),
+	}
+	packet := rowPacket(3, 0x02, '4', '2', 0x06, 'k', 'e', 'p', 'l', 'o', 'y', 0xfb)
+
+	row, n, err := DecodeTextRow(context.Background(), zap.NewNop(), packet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(packet) {
+		t.Errorf("read %d of the %d byte packet", n, len(packet))
+	}
+	if row.Values[0].Value != "42" || row.Values[1].Value != "keploy" || row.Values[2].Value != nil {
+		t.Errorf("decoded %+v", row.Values)
+	}
+
+	encoded, err := EncodeTextRow(context.Background(), zap.NewNop(), row, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, packet) {
+		t.Errorf("encoded % x, want % x", encoded, packet)
+	}
+}
+
+func TestEncodeTextRowWritesActualPayloadLength(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("name", mysql.FieldTypeVarString)}
+	row := &mysql.TextRow{
+		// the length recorded for the value before it was changed
+		Header: mysql.Header{PayloadLength: 3, SequenceID: 2},
+		Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeVarString, Name: "name", Value: "keploy"}},
+	}
+
+	encoded, err := EncodeTextRow(context.Background(), zap.NewNop(), row, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length := utils.ReadUint24(encoded[:3]); int(length) != len(encoded)-4 {
+		t.Errorf("header payload length is %d, want %d", length, len(encoded)-4)
+	}
+	if encoded[3] != 2 {
+		t.Errorf("sequence id is %d, want 2", encoded[3])
+	}
+}
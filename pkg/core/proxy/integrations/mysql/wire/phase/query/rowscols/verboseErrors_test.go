@@ -0,0 +1,40 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func TestVerboseErrorsIncludeHexContext(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("id", mysql.FieldTypeLong), column("name", mysql.FieldTypeVarString)}
+	// the name claims 16 bytes but only 3 follow
+	packet := rowPacket(1, 0x00, 0x00, 0x2a, 0x00, 0x00, 0x00, 0x10, 'a', 'n', 'n')
+
+	_, _, err := DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{})
+	if err == nil {
+		t.Fatal("expected an error for the truncated value")
+	}
+	if strings.Contains(err.Error(), "[bytes") {
+		t.Errorf("error %q has the hex context without VerboseErrors", err)
+	}
+
+	_, _, err = DecodeBinaryRowWithOptions(context.Background(), zap.NewNop(), packet, columns, DecodeOptions{VerboseErrors: true})
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("error %v is not a DecodeError", err)
+	}
+	if len(de.Context) == 0 || de.ContextStart > de.Offset || de.ContextStart+len(de.Context) < de.Offset {
+		t.Fatalf("context % x at %d doesn't surround offset %d", de.Context, de.ContextStart, de.Offset)
+	}
+	// the length byte and the bytes of the value are around the offset
+	if !strings.Contains(err.Error(), "10 61 6e 6e]") {
+		t.Errorf("error %q is missing the hex context", err)
+	}
+}
@@ -0,0 +1,195 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// WireLength returns the number of bytes EncodeBinaryRow produces for the row, headers
+// included, without encoding it. Rows whose payload doesn't fit into a single packet are
+// accounted for with the headers of the continuation packets. An error is returned for
+// rows that EncodeBinaryRow would reject because of the type or range of a value.
+func WireLength(row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41) (int, error) {
+	if len(row.Values) != len(columns) {
+		return 0, fmt.Errorf("binary row has %d values but the resultset has %d columns", len(row.Values), len(columns))
+	}
+
+	nullBitmap := nullBitmapFromValues(row.Values)
+
+	// OK byte and NULL bitmap
+	payloadLength := 1 + len(nullBitmap)
+	for i, col := range columns {
+		if isNullAt(nullBitmap, i, resultSetNullBitmapOffset) {
+			continue
+		}
+		n, err := binaryValueLength(col, row.Values[i])
+		if err != nil {
+			return 0, err
+		}
+		payloadLength += n
+	}
+
+	return payloadLength + 4*packetCount(payloadLength), nil
+}
+
+// binaryValueLength returns the number of bytes writeBinaryValue writes for the non-NULL
+// value of a column with the default EncodeOptions.
+func binaryValueLength(col *mysql.ColumnDefinition41, columnEntry mysql.ColumnEntry) (int, error) {
+	if columnEntry.Opaque {
+		return opaqueValueLength(columnEntry.Type, columnEntry.Value)
+	}
+	if columnEntry.Omitted {
+		if !isLengthEncodedType(columnEntry.Type) {
+			return 0, fmt.Errorf("column %s of type %v can't hold the digest of an omitted value", col.Name, columnEntry.Type)
+		}
+		digest, ok := columnEntry.Value.(string)
+		if !ok {
+			return 0, fmt.Errorf("unexpected type %T for the digest of the omitted value of column %s", columnEntry.Value, col.Name)
+		}
+		return lengthEncodedIntegerSize(uint64(len(digest))) + len(digest), nil
+	}
+	if raw, ok := rawFloat(columnEntry); ok {
+		return len(raw), nil
+	}
+
+	if isEnumColumn(col) && !isTextValue(columnEntry.Value) {
+		return 0, fmt.Errorf("invalid value for column %s: ordinal %v without the labels of the column in EncodeOptions.EnumLabels", col.Name, columnEntry.Value)
+	}
+
+	unsigned := columnEntry.Unsigned || col.Flags&mysql.UNSIGNED_FLAG != 0
+	n, err := columnValueLength(columnEntry.Value, columnEntry.Type, unsigned)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for column %s: %w", col.Name, err)
+	}
+	return n, nil
+}
+
+// columnValueLength returns the number of bytes EncodeColumnValue writes for the value.
+func columnValueLength(value interface{}, fieldType mysql.FieldType, unsigned bool) (int, error) {
+	fieldType = baseFieldType(fieldType)
+
+	// strings and bytes are sized as they are, coercing would copy the values read back
+	// from a yaml mock
+	if !isByteViewType(fieldType) || !isTextValue(value) {
+		var err error
+		if value, err = coerceValue(fieldType, unsigned, value); err != nil {
+			return 0, err
+		}
+	}
+
+	switch fieldType {
+	case mysql.FieldTypeTiny, mysql.FieldTypeShort, mysql.FieldTypeYear, mysql.FieldTypeInt24, mysql.FieldTypeLong, mysql.FieldTypeLongLong:
+		// the value is range checked like it is when encoding
+		var scratch [8]byte
+		width := integerWidth(fieldType)
+		if err := putInteger(scratch[:width], value, unsigned); err != nil {
+			return 0, err
+		}
+		return width, nil
+	case mysql.FieldTypeNULL:
+		return 0, nil
+	case mysql.FieldTypeString, mysql.FieldTypeVarString, mysql.FieldTypeVarChar, mysql.FieldTypeBLOB, mysql.FieldTypeTinyBLOB, mysql.FieldTypeMediumBLOB, mysql.FieldTypeLongBLOB, mysql.FieldTypeEnum, mysql.FieldTypeSet,
+		mysql.FieldTypeJSON:
+		if value == nil {
+			// NULL marker
+			return 1, nil
+		}
+		n, err := bytesValueLength(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value type for %v field: %w", fieldType, err)
+		}
+		return lengthEncodedIntegerSize(uint64(n)) + n, nil
+	case mysql.FieldTypeDecimal, mysql.FieldTypeNewDecimal:
+		decimalValue, ok := value.(string)
+		if !ok {
+			return 0, fmt.Errorf("invalid value type for decimal field")
+		}
+		return lengthEncodedIntegerSize(uint64(len(decimalValue))) + len(decimalValue), nil
+	case mysql.FieldTypeBit, mysql.FieldTypeGeometry:
+		n, err := bytesValueLength(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value type for %v field: %w", fieldType, err)
+		}
+		return lengthEncodedIntegerSize(uint64(n)) + n, nil
+	case mysql.FieldTypeFloat:
+		if _, ok := value.(float32); !ok {
+			return 0, fmt.Errorf("invalid value type for float field")
+		}
+		return 4, nil
+	case mysql.FieldTypeDouble:
+		if _, ok := value.(float64); !ok {
+			return 0, fmt.Errorf("invalid value type for double field")
+		}
+		return 8, nil
+	case mysql.FieldTypeDate, mysql.FieldTypeNewDate, mysql.FieldTypeTimestamp, mysql.FieldTypeDateTime, mysql.FieldTypeTime:
+		// the length of temporal values depends on which parts are zero, encoding them
+		// is the only reliable way to tell and takes at most 13 bytes
+		var scratch [13]byte
+		buf := bytes.NewBuffer(scratch[:0])
+		if err := encodeBinaryDateTime(buf, fieldType, value); err != nil {
+			return 0, fmt.Errorf("failed to encode date/time value: %w", err)
+		}
+		return buf.Len(), nil
+	default:
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedType, fieldType)
+	}
+}
+
+// opaqueValueLength returns the number of bytes writeOpaqueValue writes for the value.
+func opaqueValueLength(ft mysql.FieldType, value interface{}) (int, error) {
+	n, err := bytesValueLength(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value type for opaque field: %w", err)
+	}
+	switch ft {
+	case fieldTypeTimestamp2, fieldTypeDateTime2, fieldTypeTime2:
+		if n > 0xff {
+			return 0, fmt.Errorf("opaque value of %d bytes is too long for field type %#x", n, byte(ft))
+		}
+		return 1 + n, nil
+	case fieldTypeVector:
+		return lengthEncodedIntegerSize(uint64(n)) + n, nil
+	default:
+		return 0, fmt.Errorf("%w: %#x", ErrUnsupportedType, byte(ft))
+	}
+}
+
+// bytesValueLength returns the length of the value bytesFromValue would return, without
+// copying values read back from a yaml mock.
+func bytesValueLength(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case []byte:
+		return len(v), nil
+	case string:
+		return len(v), nil
+	case []interface{}:
+		for i, e := range v {
+			n, ok := e.(int)
+			if !ok || n < 0 || n > 0xff {
+				return 0, fmt.Errorf("invalid byte value %v at index %d", e, i)
+			}
+		}
+		return len(v), nil
+	default:
+		return 0, fmt.Errorf("unexpected type %T", value)
+	}
+}
+
+// lengthEncodedIntegerSize returns the number of bytes utils.WriteLengthEncodedInteger
+// writes for num.
+func lengthEncodedIntegerSize(num uint64) int {
+	switch {
+	case num <= 250:
+		return 1
+	case num <= 0xFFFF:
+		return 3
+	case num <= 0xFFFFFF:
+		return 4
+	default:
+		return 9
+	}
+}
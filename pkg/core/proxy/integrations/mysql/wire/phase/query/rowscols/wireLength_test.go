@@ -0,0 +1,110 @@
+//go:build linux
+
+package rowscols
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+// randomValue returns a random value of the field type as a mock would hold it, or nil.
+func randomValue(r *rand.Rand, ft mysql.FieldType, unsigned bool) interface{} {
+	if r.Intn(5) == 0 {
+		return nil
+	}
+	switch ft {
+	case mysql.FieldTypeTiny:
+		if unsigned {
+			return uint8(r.Intn(256))
+		}
+		return int8(r.Intn(256) - 128)
+	case mysql.FieldTypeLong:
+		return int(r.Int31())
+	case mysql.FieldTypeLongLong:
+		if unsigned {
+			return r.Uint64()
+		}
+		return r.Int63() - math.MaxInt64/2
+	case mysql.FieldTypeDouble:
+		return r.NormFloat64() * 1e6
+	case mysql.FieldTypeNewDecimal:
+		return strings.Repeat("9", r.Intn(30)) + ".05"
+	case mysql.FieldTypeDateTime:
+		// zero parts shorten the value
+		return []string{"0000-00-00 00:00:00", "2024-02-29 00:00:00", "2024-02-29 13:45:06", "2024-02-29 13:45:06.000120"}[r.Intn(4)]
+	case mysql.FieldTypeTime:
+		return []string{"00:00:00", "-838:59:59", "1 02:03:04", "12:00:00.5"}[r.Intn(4)]
+	case mysql.FieldTypeBLOB:
+		// lengths on both sides of the length-encoded integer boundaries
+		n := []int{0, 1, 250, 251, 0xffff, 0x10000}[r.Intn(6)]
+		if r.Intn(2) == 0 {
+			b := make([]interface{}, n)
+			for i := range b {
+				b[i] = r.Intn(256)
+			}
+			return b
+		}
+		return strings.Repeat("x", n)
+	default:
+		return strings.Repeat("é", r.Intn(100))
+	}
+}
+
+func TestWireLengthMatchesEncodedLength(t *testing.T) {
+	types := []mysql.FieldType{mysql.FieldTypeTiny, mysql.FieldTypeLong, mysql.FieldTypeLongLong, mysql.FieldTypeDouble,
+		mysql.FieldTypeNewDecimal, mysql.FieldTypeDateTime, mysql.FieldTypeTime, mysql.FieldTypeBLOB, mysql.FieldTypeVarString}
+	r := rand.New(rand.NewSource(1))
+	encoded := 0
+	for i := 0; i < 500; i++ {
+		var columns []*mysql.ColumnDefinition41
+		row := &mysql.BinaryRow{Header: mysql.Header{SequenceID: 1}}
+		for c := r.Intn(20); c >= 0; c-- {
+			ft := types[r.Intn(len(types))]
+			col := column("c", ft)
+			unsigned := r.Intn(2) == 0
+			if unsigned {
+				col.Flags |= mysql.UNSIGNED_FLAG
+			}
+			columns = append(columns, col)
+			row.Values = append(row.Values, mysql.ColumnEntry{Type: ft, Name: "c", Value: randomValue(r, ft, unsigned), Unsigned: unsigned})
+		}
+		if assertWireLength(t, row, columns) {
+			encoded++
+		}
+	}
+	if encoded < 400 {
+		t.Errorf("only %d of the 500 random rows could be encoded", encoded)
+	}
+}
+
+func TestWireLengthOfSplitRows(t *testing.T) {
+	columns := []*mysql.ColumnDefinition41{column("data", mysql.FieldTypeLongBLOB)}
+	// OK byte, NULL bitmap and the 4 byte length prefix
+	for _, n := range []int{maxPacketPayload - 7, maxPacketPayload - 6, maxPacketPayload} {
+		row := &mysql.BinaryRow{Values: []mysql.ColumnEntry{{Type: mysql.FieldTypeLongBLOB, Name: "data", Value: strings.Repeat("a", n)}}}
+		if !assertWireLength(t, row, columns) {
+			t.Errorf("row of %d bytes failed to encode", n)
+		}
+	}
+}
+
+// assertWireLength checks that WireLength and EncodeBinaryRow agree on the row and reports
+// whether it could be encoded.
+func assertWireLength(t *testing.T, row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41) bool {
+	t.Helper()
+	packet, encodeErr := EncodeBinaryRow(context.Background(), zap.NewNop(), row, columns, true)
+	n, err := WireLength(row, columns)
+	if (err != nil) != (encodeErr != nil) {
+		t.Fatalf("WireLength error %v, EncodeBinaryRow error %v", err, encodeErr)
+	}
+	if err == nil && n != len(packet) {
+		t.Errorf("WireLength = %d, EncodeBinaryRow wrote %d bytes for %+v", n, len(packet), row.Values)
+	}
+	return err == nil
+}
@@ -18,6 +18,7 @@ type DecodeContext struct {
 	Mode               models.Mode
 	LastOp             *LastOperation
 	PreparedStatements map[uint32]*mysql.StmtPrepareOkPacket
+	LongData           map[uint32]map[uint16][]byte // chunks sent via COM_STMT_SEND_LONG_DATA per statement and parameter
 	ServerGreetings    *ServerGreetings
 	ClientCapabilities uint32
 	PluginName         string
@@ -154,3 +155,12 @@ func PrintByteArray(name string, b []byte) {
 	}
 	fmt.Println()
 }
+
+// okPacketCapabilities returns the capabilities to decode OK packets with, the server only sends
+// the session state information when the client asked for CLIENT_SESSION_TRACK as well.
+func okPacketCapabilities(serverCapabilities, clientCapabilities uint32) uint32 {
+	if clientCapabilities&mysql.CLIENT_SESSION_TRACK == 0 {
+		return serverCapabilities &^ mysql.CLIENT_SESSION_TRACK
+	}
+	return serverCapabilities
+}
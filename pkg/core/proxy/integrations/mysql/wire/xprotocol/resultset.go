@@ -0,0 +1,563 @@
+//go:build linux
+
+// Package xprotocol provides decoding and encoding of MySQL X Protocol resultsets. The
+// protobuf framed messages are mapped into the models of the classic protocol so that mocks
+// recorded over either protocol share the same representation.
+package xprotocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/rowscols"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+//ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_mysqlx_protocol_messages.html
+//ref: https://dev.mysql.com/doc/dev/mysql-server/latest/structMysqlx_1_1Resultset_1_1ColumnMetaData.html
+
+// Server message types of the resultset messages
+const (
+	ResultsetColumnMetaData      byte = 12
+	ResultsetRow                 byte = 13
+	ResultsetFetchDone           byte = 14
+	ResultsetFetchDoneMoreResult byte = 16
+)
+
+// Types of the values in X Protocol rows, only the scalar ones are supported for now
+const (
+	fieldTypeSInt     uint64 = 1
+	fieldTypeUInt     uint64 = 2
+	fieldTypeDouble   uint64 = 5
+	fieldTypeFloat    uint64 = 6
+	fieldTypeBytes    uint64 = 7
+	fieldTypeTime     uint64 = 10
+	fieldTypeDateTime uint64 = 12
+	fieldTypeSet      uint64 = 15
+	fieldTypeEnum     uint64 = 16
+	fieldTypeBit      uint64 = 17
+	fieldTypeDecimal  uint64 = 18
+)
+
+// Content types of BYTES columns
+const (
+	contentTypeGeometry uint64 = 1
+	contentTypeJSON     uint64 = 2
+)
+
+// Column flags, the meaning of 0x0001 depends on the type of the column
+const (
+	flagTypeSpecific  uint64 = 0x0001 // UINT: zerofill, DOUBLE/FLOAT: unsigned, BYTES: rightpad
+	flagNotNull       uint64 = 0x0010
+	flagPrimaryKey    uint64 = 0x0020
+	flagUniqueKey     uint64 = 0x0040
+	flagMultipleKey   uint64 = 0x0080
+	flagAutoIncrement uint64 = 0x0100
+)
+
+// ColumnMetaData field numbers
+const (
+	columnFieldType             protowire.Number = 1
+	columnFieldName             protowire.Number = 2
+	columnFieldOriginalName     protowire.Number = 3
+	columnFieldTable            protowire.Number = 4
+	columnFieldOriginalTable    protowire.Number = 5
+	columnFieldSchema           protowire.Number = 6
+	columnFieldCatalog          protowire.Number = 7
+	columnFieldCollation        protowire.Number = 8
+	columnFieldFractionalDigits protowire.Number = 9
+	columnFieldLength           protowire.Number = 10
+	columnFieldFlags            protowire.Number = 11
+	columnFieldContentType      protowire.Number = 12
+)
+
+// rowFieldField is the number of the repeated bytes field holding the values of a Row
+const rowFieldField protowire.Number = 1
+
+// ReadMessage reads the X Protocol message at the start of data, a 4 byte little-endian
+// length (covering the type byte) followed by the message type and the protobuf payload.
+// It returns the message type, the payload and the number of bytes consumed.
+func ReadMessage(data []byte) (byte, []byte, int, error) {
+	if len(data) < 5 {
+		return 0, nil, 0, fmt.Errorf("X Protocol message too short: %d bytes", len(data))
+	}
+	length := binary.LittleEndian.Uint32(data[:4])
+	if length < 1 {
+		return 0, nil, 0, errors.New("X Protocol message without a type")
+	}
+	if uint64(length) > uint64(len(data)-4) {
+		return 0, nil, 0, fmt.Errorf("X Protocol message needs %d bytes, got %d", length, len(data)-4)
+	}
+	end := 4 + int(length)
+	return data[4], data[5:end], end, nil
+}
+
+// AppendMessage appends the framed message of the given type and payload to b.
+func AppendMessage(b []byte, msgType byte, payload []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(payload)+1))
+	b = append(b, msgType)
+	return append(b, payload...)
+}
+
+// DecodeColumnMetaData decodes the payload of a ColumnMetaData message into the classic
+// column definition: signed and unsigned integers are mapped to BIGINT, BYTES to VARCHAR
+// (or CHAR when right padded, JSON and GEOMETRY after the content type) and the collation
+// to the character set. An error wrapping rowscols.ErrUnsupportedType is returned for the
+// non scalar types.
+func DecodeColumnMetaData(payload []byte) (*mysql.ColumnDefinition41, error) {
+	var (
+		col                        = &mysql.ColumnDefinition41{}
+		xType, xFlags, contentType uint64
+	)
+
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return nil, fmt.Errorf("malformed column metadata: %w", protowire.ParseError(n))
+		}
+		payload = payload[n:]
+
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(payload)
+			if n < 0 {
+				return nil, fmt.Errorf("malformed column metadata field %d: %w", num, protowire.ParseError(n))
+			}
+			payload = payload[n:]
+			switch num {
+			case columnFieldType:
+				xType = v
+			case columnFieldCollation:
+				col.CharacterSet = uint16(v)
+			case columnFieldFractionalDigits:
+				col.Decimals = byte(v)
+			case columnFieldLength:
+				col.ColumnLength = uint32(v)
+			case columnFieldFlags:
+				xFlags = v
+			case columnFieldContentType:
+				contentType = v
+			}
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(payload)
+			if n < 0 {
+				return nil, fmt.Errorf("malformed column metadata field %d: %w", num, protowire.ParseError(n))
+			}
+			payload = payload[n:]
+			switch num {
+			case columnFieldName:
+				col.Name = string(v)
+			case columnFieldOriginalName:
+				col.OrgName = string(v)
+			case columnFieldTable:
+				col.Table = string(v)
+			case columnFieldOriginalTable:
+				col.OrgTable = string(v)
+			case columnFieldSchema:
+				col.Schema = string(v)
+			case columnFieldCatalog:
+				col.Catalog = string(v)
+			}
+		default:
+			// fields of other wire types aren't part of the message, skip them
+			n := protowire.ConsumeFieldValue(num, typ, payload)
+			if n < 0 {
+				return nil, fmt.Errorf("malformed column metadata field %d: %w", num, protowire.ParseError(n))
+			}
+			payload = payload[n:]
+		}
+	}
+
+	fieldType, flags, err := classicType(xType, xFlags, contentType)
+	if err != nil {
+		return nil, err
+	}
+	col.Type = byte(fieldType)
+	col.Flags = flags
+	return col, nil
+}
+
+// EncodeColumnMetaData encodes the column definition as the payload of a ColumnMetaData
+// message, it is the inverse of DecodeColumnMetaData.
+func EncodeColumnMetaData(col *mysql.ColumnDefinition41) ([]byte, error) {
+	xType, xFlags, contentType, err := xProtocolType(mysql.FieldType(col.Type), col.Flags)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendVarintField(b, columnFieldType, xType)
+	b = appendStringField(b, columnFieldName, col.Name)
+	b = appendStringField(b, columnFieldOriginalName, col.OrgName)
+	b = appendStringField(b, columnFieldTable, col.Table)
+	b = appendStringField(b, columnFieldOriginalTable, col.OrgTable)
+	b = appendStringField(b, columnFieldSchema, col.Schema)
+	b = appendStringField(b, columnFieldCatalog, col.Catalog)
+	if col.CharacterSet != 0 {
+		b = appendVarintField(b, columnFieldCollation, uint64(col.CharacterSet))
+	}
+	if col.Decimals != 0 {
+		b = appendVarintField(b, columnFieldFractionalDigits, uint64(col.Decimals))
+	}
+	b = appendVarintField(b, columnFieldLength, uint64(col.ColumnLength))
+	if xFlags != 0 {
+		b = appendVarintField(b, columnFieldFlags, xFlags)
+	}
+	if contentType != 0 {
+		b = appendVarintField(b, columnFieldContentType, contentType)
+	}
+	return b, nil
+}
+
+// DecodeRow decodes the payload of a Row message. An empty field is NULL, integers are
+// decoded as int64/uint64, floats as float32/float64 and bytes as a string (or []byte when
+// not valid UTF-8), like the values of the binary protocol of the same column type.
+func DecodeRow(payload []byte, columns []*mysql.ColumnDefinition41) (*mysql.BinaryRow, error) {
+	row := &mysql.BinaryRow{
+		Values: make([]mysql.ColumnEntry, 0, len(columns)),
+	}
+
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return nil, fmt.Errorf("malformed row: %w", protowire.ParseError(n))
+		}
+		payload = payload[n:]
+		if num != rowFieldField || typ != protowire.BytesType {
+			n := protowire.ConsumeFieldValue(num, typ, payload)
+			if n < 0 {
+				return nil, fmt.Errorf("malformed row field %d: %w", num, protowire.ParseError(n))
+			}
+			payload = payload[n:]
+			continue
+		}
+
+		field, n := protowire.ConsumeBytes(payload)
+		if n < 0 {
+			return nil, fmt.Errorf("malformed row field %d: %w", num, protowire.ParseError(n))
+		}
+		payload = payload[n:]
+
+		i := len(row.Values)
+		if i >= len(columns) {
+			return nil, fmt.Errorf("row has more values than the %d columns", len(columns))
+		}
+		col := columns[i]
+		entry := mysql.ColumnEntry{
+			Type:     mysql.FieldType(col.Type),
+			Name:     col.Name,
+			Unsigned: col.Flags&mysql.UNSIGNED_FLAG != 0,
+		}
+		if len(field) > 0 {
+			value, err := decodeValue(field, col)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode value of column %s: %w", col.Name, err)
+			}
+			entry.Value = value
+		}
+		row.Values = append(row.Values, entry)
+	}
+
+	if len(row.Values) != len(columns) {
+		return nil, fmt.Errorf("row has %d values but the resultset has %d columns", len(row.Values), len(columns))
+	}
+
+	isNull := make([]bool, len(row.Values))
+	for i, v := range row.Values {
+		isNull[i] = v.Value == nil
+	}
+	// keep the row encodable with the classic protocol
+	row.RowNullBuffer = rowscols.BuildNullBitmap(isNull)
+	return row, nil
+}
+
+// EncodeRow encodes the row as the payload of a Row message, it is the inverse of DecodeRow.
+func EncodeRow(row *mysql.BinaryRow, columns []*mysql.ColumnDefinition41) ([]byte, error) {
+	if len(row.Values) != len(columns) {
+		return nil, fmt.Errorf("row has %d values but the resultset has %d columns", len(row.Values), len(columns))
+	}
+
+	var b []byte
+	for i, col := range columns {
+		var field []byte
+		if row.Values[i].Value != nil {
+			value, err := rowscols.CoerceValue(col, row.Values[i].Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for column %s: %w", col.Name, err)
+			}
+			field, err = encodeValue(value, col)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode value of column %s: %w", col.Name, err)
+			}
+		}
+		b = protowire.AppendTag(b, rowFieldField, protowire.BytesType)
+		b = protowire.AppendBytes(b, field)
+	}
+	return b, nil
+}
+
+// DecodeResultSet decodes the ColumnMetaData, Row and FetchDone messages of a resultset at
+// the start of data into the model of a binary protocol resultset. The message ending the
+// rows is kept as the final response. It returns the number of bytes consumed.
+func DecodeResultSet(data []byte) (*mysql.BinaryProtocolResultSet, int, error) {
+	resultSet := &mysql.BinaryProtocolResultSet{}
+	offset := 0
+
+	for {
+		msgType, payload, n, err := ReadMessage(data[offset:])
+		if err != nil {
+			return nil, offset, fmt.Errorf("failed to read resultset message at offset %d: %w", offset, err)
+		}
+
+		switch msgType {
+		case ResultsetColumnMetaData:
+			if len(resultSet.Rows) > 0 {
+				return nil, offset, fmt.Errorf("column metadata after the rows at offset %d", offset)
+			}
+			col, err := DecodeColumnMetaData(payload)
+			if err != nil {
+				return nil, offset, err
+			}
+			resultSet.Columns = append(resultSet.Columns, col)
+
+		case ResultsetRow:
+			row, err := DecodeRow(payload, resultSet.Columns)
+			if err != nil {
+				return nil, offset, err
+			}
+			resultSet.Rows = append(resultSet.Rows, row)
+
+		case ResultsetFetchDone, ResultsetFetchDoneMoreResult:
+			resultSet.ColumnCount = uint64(len(resultSet.Columns))
+			resultSet.FinalResponse = &mysql.GenericResponse{
+				Data: bytes.Clone(data[offset : offset+n]),
+				Type: "FetchDone",
+			}
+			return resultSet, offset + n, nil
+
+		default:
+			return nil, offset, fmt.Errorf("unexpected message type %d in resultset at offset %d", msgType, offset)
+		}
+		offset += n
+	}
+}
+
+// EncodeResultSet encodes the resultset decoded by DecodeResultSet back into its messages.
+func EncodeResultSet(resultSet *mysql.BinaryProtocolResultSet) ([]byte, error) {
+	var b []byte
+	for _, col := range resultSet.Columns {
+		payload, err := EncodeColumnMetaData(col)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode column %s: %w", col.Name, err)
+		}
+		b = AppendMessage(b, ResultsetColumnMetaData, payload)
+	}
+	for i, row := range resultSet.Rows {
+		payload, err := EncodeRow(row, resultSet.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode row %d: %w", i, err)
+		}
+		b = AppendMessage(b, ResultsetRow, payload)
+	}
+	if resultSet.FinalResponse != nil && len(resultSet.FinalResponse.Data) != 0 {
+		b = append(b, resultSet.FinalResponse.Data...)
+	} else {
+		b = AppendMessage(b, ResultsetFetchDone, nil)
+	}
+	return b, nil
+}
+
+// classicType maps the type and flags of an X Protocol column to the classic field type and flags.
+func classicType(xType, xFlags, contentType uint64) (mysql.FieldType, uint16, error) {
+	flags := classicFlags(xFlags)
+	typeSpecific := xFlags&flagTypeSpecific != 0
+
+	switch xType {
+	case fieldTypeSInt:
+		return mysql.FieldTypeLongLong, flags, nil
+	case fieldTypeUInt:
+		flags |= mysql.UNSIGNED_FLAG
+		if typeSpecific {
+			flags |= mysql.ZEROFILL_FLAG
+		}
+		return mysql.FieldTypeLongLong, flags, nil
+	case fieldTypeDouble, fieldTypeFloat:
+		if typeSpecific {
+			flags |= mysql.UNSIGNED_FLAG
+		}
+		if xType == fieldTypeFloat {
+			return mysql.FieldTypeFloat, flags, nil
+		}
+		return mysql.FieldTypeDouble, flags, nil
+	case fieldTypeBytes:
+		switch contentType {
+		case 0:
+			if typeSpecific {
+				// fixed length columns are right padded
+				return mysql.FieldTypeString, flags, nil
+			}
+			return mysql.FieldTypeVarString, flags, nil
+		case contentTypeJSON:
+			return mysql.FieldTypeJSON, flags, nil
+		case contentTypeGeometry:
+			return mysql.FieldTypeGeometry, flags, nil
+		}
+		return 0, 0, fmt.Errorf("%w: X Protocol BYTES with content type %d", rowscols.ErrUnsupportedType, contentType)
+	case fieldTypeEnum:
+		return mysql.FieldTypeEnum, flags | mysql.ENUM_FLAG, nil
+	case fieldTypeTime, fieldTypeDateTime, fieldTypeSet, fieldTypeBit, fieldTypeDecimal:
+		return 0, 0, fmt.Errorf("%w: X Protocol type %d", rowscols.ErrUnsupportedType, xType)
+	default:
+		return 0, 0, fmt.Errorf("%w: unknown X Protocol type %d", rowscols.ErrUnsupportedType, xType)
+	}
+}
+
+// xProtocolType is the inverse of classicType.
+func xProtocolType(fieldType mysql.FieldType, flags uint16) (xType, xFlags, contentType uint64, err error) {
+	xFlags = xProtocolFlags(flags)
+
+	switch fieldType {
+	case mysql.FieldTypeLongLong:
+		if flags&mysql.UNSIGNED_FLAG == 0 {
+			return fieldTypeSInt, xFlags, 0, nil
+		}
+		if flags&mysql.ZEROFILL_FLAG != 0 {
+			xFlags |= flagTypeSpecific
+		}
+		return fieldTypeUInt, xFlags, 0, nil
+	case mysql.FieldTypeDouble, mysql.FieldTypeFloat:
+		if flags&mysql.UNSIGNED_FLAG != 0 {
+			xFlags |= flagTypeSpecific
+		}
+		if fieldType == mysql.FieldTypeFloat {
+			return fieldTypeFloat, xFlags, 0, nil
+		}
+		return fieldTypeDouble, xFlags, 0, nil
+	case mysql.FieldTypeVarString:
+		return fieldTypeBytes, xFlags, 0, nil
+	case mysql.FieldTypeString:
+		return fieldTypeBytes, xFlags | flagTypeSpecific, 0, nil
+	case mysql.FieldTypeJSON:
+		return fieldTypeBytes, xFlags, contentTypeJSON, nil
+	case mysql.FieldTypeGeometry:
+		return fieldTypeBytes, xFlags, contentTypeGeometry, nil
+	case mysql.FieldTypeEnum:
+		return fieldTypeEnum, xFlags, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: %v has no X Protocol mapping", rowscols.ErrUnsupportedType, fieldType)
+	}
+}
+
+// classicFlags maps the column flags shared by all X Protocol types to the classic ones.
+func classicFlags(xFlags uint64) uint16 {
+	var flags uint16
+	if xFlags&flagNotNull != 0 {
+		flags |= mysql.NOT_NULL_FLAG
+	}
+	if xFlags&flagPrimaryKey != 0 {
+		flags |= mysql.PRI_KEY_FLAG
+	}
+	if xFlags&flagUniqueKey != 0 {
+		flags |= mysql.UNIQUE_KEY_FLAG
+	}
+	if xFlags&flagMultipleKey != 0 {
+		flags |= mysql.MULTIPLE_KEY_FLAG
+	}
+	if xFlags&flagAutoIncrement != 0 {
+		flags |= mysql.AUTO_INCREMENT_FLAG
+	}
+	return flags
+}
+
+// xProtocolFlags is the inverse of classicFlags.
+func xProtocolFlags(flags uint16) uint64 {
+	var xFlags uint64
+	if flags&mysql.NOT_NULL_FLAG != 0 {
+		xFlags |= flagNotNull
+	}
+	if flags&mysql.PRI_KEY_FLAG != 0 {
+		xFlags |= flagPrimaryKey
+	}
+	if flags&mysql.UNIQUE_KEY_FLAG != 0 {
+		xFlags |= flagUniqueKey
+	}
+	if flags&mysql.MULTIPLE_KEY_FLAG != 0 {
+		xFlags |= flagMultipleKey
+	}
+	if flags&mysql.AUTO_INCREMENT_FLAG != 0 {
+		xFlags |= flagAutoIncrement
+	}
+	return xFlags
+}
+
+// decodeValue decodes the non-NULL value of a column from its field in a Row message.
+func decodeValue(field []byte, col *mysql.ColumnDefinition41) (interface{}, error) {
+	switch mysql.FieldType(col.Type) {
+	case mysql.FieldTypeLongLong:
+		v, n := protowire.ConsumeVarint(field)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		if col.Flags&mysql.UNSIGNED_FLAG != 0 {
+			return v, nil
+		}
+		return protowire.DecodeZigZag(v), nil
+	case mysql.FieldTypeDouble:
+		if len(field) != 8 {
+			return nil, fmt.Errorf("double value has %d bytes", len(field))
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(field)), nil
+	case mysql.FieldTypeFloat:
+		if len(field) != 4 {
+			return nil, fmt.Errorf("float value has %d bytes", len(field))
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(field)), nil
+	case mysql.FieldTypeVarString, mysql.FieldTypeString, mysql.FieldTypeJSON, mysql.FieldTypeEnum, mysql.FieldTypeGeometry:
+		// bytes are followed by a 0x00 so that an empty string isn't mistaken for NULL
+		if field[len(field)-1] != 0x00 {
+			return nil, errors.New("bytes value is not terminated by 0x00")
+		}
+		value := bytes.Clone(field[:len(field)-1])
+		if col.Type == byte(mysql.FieldTypeGeometry) || !utf8.Valid(value) {
+			return value, nil
+		}
+		return string(value), nil
+	default:
+		return nil, fmt.Errorf("%w: %v", rowscols.ErrUnsupportedType, mysql.FieldType(col.Type))
+	}
+}
+
+// encodeValue is the inverse of decodeValue, value must have been coerced to the type of the column.
+func encodeValue(value interface{}, col *mysql.ColumnDefinition41) ([]byte, error) {
+	switch v := value.(type) {
+	case int64:
+		return protowire.AppendVarint(nil, protowire.EncodeZigZag(v)), nil
+	case uint64:
+		return protowire.AppendVarint(nil, v), nil
+	case float64:
+		return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)), nil
+	case float32:
+		return binary.LittleEndian.AppendUint32(nil, math.Float32bits(v)), nil
+	case string:
+		return append([]byte(v), 0x00), nil
+	case []byte:
+		return append(bytes.Clone(v), 0x00), nil
+	default:
+		return nil, fmt.Errorf("%w: %T value for %v", rowscols.ErrUnsupportedType, value, mysql.FieldType(col.Type))
+	}
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package xprotocol
+
+import (
+	"bytes"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// frame prefixes the message type and payload with their little-endian length.
+func frame(msgType byte, payload ...[]byte) []byte {
+	body := append([]byte{msgType}, bytes.Join(payload, nil)...)
+	n := len(body)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}, body...)
+}
+
+// usersResultSet holds the messages the server sends for SELECT id, name FROM shop.users
+// over the X Protocol, encoded by hand from the Mysqlx.Resultset protobuf definitions.
+var usersResultSet = bytes.Join([][]byte{
+	frame(ResultsetColumnMetaData,
+		[]byte{0x08, 0x01}, // SINT
+		[]byte{0x12, 0x02}, []byte("id"), []byte{0x1a, 0x02}, []byte("id"),
+		[]byte{0x22, 0x05}, []byte("users"), []byte{0x2a, 0x05}, []byte("users"),
+		[]byte{0x32, 0x04}, []byte("shop"), []byte{0x3a, 0x03}, []byte("def"),
+		[]byte{0x50, 0x0b},       // length 11
+		[]byte{0x58, 0xb0, 0x02}, // NOT NULL, PRIMARY KEY, AUTO_INCREMENT
+	),
+	frame(ResultsetColumnMetaData,
+		[]byte{0x08, 0x07}, // BYTES
+		[]byte{0x12, 0x04}, []byte("name"), []byte{0x1a, 0x04}, []byte("name"),
+		[]byte{0x22, 0x05}, []byte("users"), []byte{0x2a, 0x05}, []byte("users"),
+		[]byte{0x32, 0x04}, []byte("shop"), []byte{0x3a, 0x03}, []byte("def"),
+		[]byte{0x40, 0xff, 0x01}, // utf8mb4_0900_ai_ci
+		[]byte{0x50, 0x50},       // length 80
+	),
+	// id 1 (zigzag 2) and "ann"
+	frame(ResultsetRow, []byte{0x0a, 0x01, 0x02, 0x0a, 0x04}, []byte("ann\x00")),
+	// id -3 (zigzag 5) and NULL
+	frame(ResultsetRow, []byte{0x0a, 0x01, 0x05, 0x0a, 0x00}),
+	frame(ResultsetFetchDone),
+}, nil)
+
+func TestResultSetRoundTrip(t *testing.T) {
+	resultSet, n, err := DecodeResultSet(usersResultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(usersResultSet) {
+		t.Errorf("decoded %d bytes of %d", n, len(usersResultSet))
+	}
+
+	id, name := resultSet.Columns[0], resultSet.Columns[1]
+	if id.Name != "id" || mysql.FieldType(id.Type) != mysql.FieldTypeLongLong || id.Flags&mysql.PRI_KEY_FLAG == 0 {
+		t.Errorf("id column decoded as %+v", id)
+	}
+	if name.Name != "name" || mysql.FieldType(name.Type) != mysql.FieldTypeVarString || name.CharacterSet != 255 || name.Table != "users" {
+		t.Errorf("name column decoded as %+v", name)
+	}
+
+	if len(resultSet.Rows) != 2 {
+		t.Fatalf("decoded %d rows, want 2", len(resultSet.Rows))
+	}
+	for i, want := range [][]interface{}{{int64(1), "ann"}, {int64(-3), nil}} {
+		values := resultSet.Rows[i].Values
+		if values[0].Value != want[0] || values[1].Value != want[1] {
+			t.Errorf("row %d decoded as %v, want %v", i, resultSet.Rows[i], want)
+		}
+	}
+
+	encoded, err := EncodeResultSet(resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, usersResultSet) {
+		t.Errorf("encoded\n% x\nwant\n% x", encoded, usersResultSet)
+	}
+}
@@ -0,0 +1,272 @@
+//go:build linux
+
+package v1
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+//ref: https://www.postgresql.org/docs/current/protocol-message-formats.html#PROTOCOL-MESSAGE-FORMATS-DATAROW
+
+// OIDs of the built-in data types whose binary format is decoded into a Go value, the values
+// of other types in binary format are kept as raw bytes.
+const (
+	oidBool    uint32 = 16
+	oidName    uint32 = 19
+	oidInt8    uint32 = 20
+	oidInt2    uint32 = 21
+	oidInt4    uint32 = 23
+	oidText    uint32 = 25
+	oidOID     uint32 = 26
+	oidFloat4  uint32 = 700
+	oidFloat8  uint32 = 701
+	oidBpchar  uint32 = 1042
+	oidVarchar uint32 = 1043
+)
+
+// Format codes of the columns of a RowDescription.
+const (
+	formatText   int16 = 0
+	formatBinary int16 = 1
+)
+
+// DecodeDataRow decodes the DataRow message at the start of data, including its 'D' tag and
+// length, according to the fields of the RowDescription of the resultset. Values in text
+// format are decoded into strings, values in binary format into the Go type of their data
+// type (bool, int16, int32, int64, uint32, float32, float64 or string) or kept as []byte for
+// the other types. It returns the row and the number of bytes consumed.
+func DecodeDataRow(data []byte, desc *pgproto3.RowDescription) (*models.PostgresDataRow, int, error) {
+	if len(data) < 5 {
+		return nil, 0, fmt.Errorf("data row message too short: %d bytes", len(data))
+	}
+	if data[0] != 'D' {
+		return nil, 0, fmt.Errorf("unexpected message type %q, expected a data row", data[0])
+	}
+	// the length includes itself but not the tag
+	length := int(binary.BigEndian.Uint32(data[1:5]))
+	if length < 6 || len(data) < 1+length {
+		return nil, 0, fmt.Errorf("invalid data row length %d for %d bytes", length, len(data))
+	}
+	msg := data[5 : 1+length]
+
+	fieldCount := int(binary.BigEndian.Uint16(msg[:2]))
+	if fieldCount != len(desc.Fields) {
+		return nil, 0, fmt.Errorf("data row has %d values but the row description has %d fields", fieldCount, len(desc.Fields))
+	}
+	offset := 2
+
+	row := &models.PostgresDataRow{Values: make([]models.PostgresColumnEntry, 0, fieldCount)}
+	for i, field := range desc.Fields {
+		if len(msg)-offset < 4 {
+			return nil, 0, fmt.Errorf("truncated length of column %d", i)
+		}
+		valueLength := int(int32(binary.BigEndian.Uint32(msg[offset:])))
+		offset += 4
+
+		entry := models.PostgresColumnEntry{
+			Type:   field.DataTypeOID,
+			Name:   field.FieldName,
+			Format: field.Format,
+		}
+		if valueLength < 0 {
+			// -1 is NULL
+			row.Values = append(row.Values, entry)
+			continue
+		}
+		if len(msg)-offset < valueLength {
+			return nil, 0, fmt.Errorf("column %d needs %d bytes, got %d", i, valueLength, len(msg)-offset)
+		}
+		value, err := decodeColumnValue(msg[offset:offset+valueLength], field)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode column %s: %w", field.FieldName, err)
+		}
+		entry.Value = value
+		row.Values = append(row.Values, entry)
+		offset += valueLength
+	}
+
+	if offset != len(msg) {
+		return nil, 0, fmt.Errorf("data row has %d bytes left over after the last column", len(msg)-offset)
+	}
+	return row, 1 + length, nil
+}
+
+func decodeColumnValue(b []byte, field pgproto3.FieldDescription) (interface{}, error) {
+	if field.Format == formatText {
+		return string(b), nil
+	}
+	if field.Format != formatBinary {
+		return nil, fmt.Errorf("unknown format code %d", field.Format)
+	}
+
+	if width := fixedWidth(field.DataTypeOID); width > 0 && len(b) != width {
+		return nil, fmt.Errorf("binary value of type %d has %d bytes, expected %d", field.DataTypeOID, len(b), width)
+	}
+
+	switch field.DataTypeOID {
+	case oidBool:
+		return b[0] != 0, nil
+	case oidInt2:
+		return int16(binary.BigEndian.Uint16(b)), nil
+	case oidInt4:
+		return int32(binary.BigEndian.Uint32(b)), nil
+	case oidOID:
+		return binary.BigEndian.Uint32(b), nil
+	case oidInt8:
+		return int64(binary.BigEndian.Uint64(b)), nil
+	case oidFloat4:
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), nil
+	case oidFloat8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case oidText, oidVarchar, oidBpchar, oidName:
+		return string(b), nil
+	default:
+		raw := make([]byte, len(b))
+		copy(raw, b)
+		return raw, nil
+	}
+}
+
+// EncodeDataRow encodes the row as a DataRow message, including its 'D' tag and length. It
+// is the inverse of DecodeDataRow. Numbers may also be of the types they are read back
+// from a yaml mock as, e.g. int for an int4 column.
+func EncodeDataRow(row *models.PostgresDataRow) ([]byte, error) {
+	if len(row.Values) > math.MaxUint16 {
+		return nil, fmt.Errorf("data row has %d values, at most %d are allowed", len(row.Values), math.MaxUint16)
+	}
+
+	buf := make([]byte, 7, 64)
+	buf[0] = 'D'
+	binary.BigEndian.PutUint16(buf[5:7], uint16(len(row.Values)))
+
+	for _, entry := range row.Values {
+		if entry.Value == nil {
+			buf = binary.BigEndian.AppendUint32(buf, math.MaxUint32) // -1 is NULL
+			continue
+		}
+		value, err := encodeColumnValue(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode column %s: %w", entry.Name, err)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(value)))
+		buf = append(buf, value...)
+	}
+
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(buf)-1))
+	return buf, nil
+}
+
+func encodeColumnValue(entry models.PostgresColumnEntry) ([]byte, error) {
+	switch v := entry.Value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case []interface{}:
+		// raw bytes read back from a yaml mock
+		b := make([]byte, len(v))
+		for i, e := range v {
+			n, ok := e.(int)
+			if !ok || n < 0 || n > 0xff {
+				return nil, fmt.Errorf("invalid byte value %v at index %d", e, i)
+			}
+			b[i] = byte(n)
+		}
+		return b, nil
+	}
+	if entry.Format != formatBinary {
+		return nil, fmt.Errorf("unexpected type %T for a value in text format", entry.Value)
+	}
+
+	switch entry.Type {
+	case oidBool:
+		v, ok := entry.Value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T for a bool value", entry.Value)
+		}
+		if v {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case oidInt2, oidInt4, oidOID, oidInt8:
+		// values read back from a yaml mock are plain ints
+		i, err := intFromValue(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		bits := fixedWidth(entry.Type) * 8
+		if entry.Type == oidOID {
+			if i < 0 || i > math.MaxUint32 {
+				return nil, fmt.Errorf("value %d overflows oid", i)
+			}
+		} else if bits < 64 && (i < -(1<<(bits-1)) || i > 1<<(bits-1)-1) {
+			return nil, fmt.Errorf("value %d overflows %d-bit integer", i, bits)
+		}
+		b := binary.BigEndian.AppendUint64(nil, uint64(i))
+		return b[8-bits/8:], nil
+	case oidFloat4, oidFloat8:
+		var f float64
+		switch v := entry.Value.(type) {
+		case float32:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			i, err := intFromValue(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			f = float64(i)
+		}
+		if entry.Type == oidFloat4 {
+			return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))), nil
+		}
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), nil
+	default:
+		return nil, fmt.Errorf("unexpected type %T for a value of type %d", entry.Value, entry.Type)
+	}
+}
+
+// fixedWidth returns the size of the binary values of the fixed width data types, and 0
+// for the other types.
+func fixedWidth(oid uint32) int {
+	switch oid {
+	case oidBool:
+		return 1
+	case oidInt2:
+		return 2
+	case oidInt4, oidOID, oidFloat4:
+		return 4
+	case oidInt8, oidFloat8:
+		return 8
+	default:
+		return 0
+	}
+}
+
+func intFromValue(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int64", v)
+		}
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("unexpected type %T for an integer value", value)
+	}
+}
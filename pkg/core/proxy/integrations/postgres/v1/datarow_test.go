@@ -0,0 +1,76 @@
+//go:build linux
+
+package v1
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// usersDataRow is the DataRow sent for SELECT id, name, balance, note FROM users with the
+// id and balance requested in binary format: 7, 'ann', 12.5 and NULL.
+var usersDataRow = []byte{
+	'D', 0x00, 0x00, 0x00, 0x25, 0x00, 0x04,
+	0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x07,
+	0x00, 0x00, 0x00, 0x03, 'a', 'n', 'n',
+	0x00, 0x00, 0x00, 0x08, 0x40, 0x29, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0xff,
+}
+
+var usersRowDescription = &pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+	{FieldName: "id", DataTypeOID: oidInt4, DataTypeSize: 4, Format: formatBinary},
+	{FieldName: "name", DataTypeOID: oidText, DataTypeSize: -1, Format: formatText},
+	{FieldName: "balance", DataTypeOID: oidFloat8, DataTypeSize: 8, Format: formatBinary},
+	{FieldName: "note", DataTypeOID: oidText, DataTypeSize: -1, Format: formatText},
+}}
+
+func TestDataRowRoundTrip(t *testing.T) {
+	row, n, err := DecodeDataRow(usersDataRow, usersRowDescription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(usersDataRow) {
+		t.Errorf("decoded %d bytes of %d", n, len(usersDataRow))
+	}
+	for i, want := range []interface{}{int32(7), "ann", 12.5, nil} {
+		if got := row.Values[i]; got.Value != want || got.Name != usersRowDescription.Fields[i].FieldName {
+			t.Errorf("column %d decoded as %+v, want %#v", i, got, want)
+		}
+	}
+
+	encoded, err := EncodeDataRow(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, usersDataRow) {
+		t.Errorf("encoded % x, want % x", encoded, usersDataRow)
+	}
+
+	// and through the yaml form of a mock
+	data, err := yaml.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mock models.PostgresDataRow
+	if err := yaml.Unmarshal(data, &mock); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err = EncodeDataRow(&mock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, usersDataRow) {
+		t.Errorf("mock\n%s\nencoded % x, want % x", data, encoded, usersDataRow)
+	}
+}
+
+func TestDecodeDataRowFieldCountMismatch(t *testing.T) {
+	desc := &pgproto3.RowDescription{Fields: usersRowDescription.Fields[:3]}
+	if _, _, err := DecodeDataRow(usersDataRow, desc); err == nil {
+		t.Error("decoding a row with more values than fields succeeded")
+	}
+}
@@ -0,0 +1,347 @@
+//go:build linux
+
+package redis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+//ref: https://github.com/redis/redis-specifications/blob/master/protocol/RESP3.md
+
+// RESP type prefixes
+const (
+	respSimpleString   byte = '+'
+	respSimpleError    byte = '-'
+	respInteger        byte = ':'
+	respBulkString     byte = '$'
+	respArray          byte = '*'
+	respNull           byte = '_'
+	respBoolean        byte = '#'
+	respDouble         byte = ','
+	respBigNumber      byte = '('
+	respBulkError      byte = '!'
+	respVerbatimString byte = '='
+	respMap            byte = '%'
+	respAttribute      byte = '|'
+	respSet            byte = '~'
+	respPush           byte = '>'
+)
+
+var crlf = []byte("\r\n")
+
+// DecodeValue decodes the RESP value at the start of data, including the values nested in
+// aggregates, and returns it with the number of bytes it takes. io.ErrUnexpectedEOF is
+// returned when data ends before the value is complete. Streamed strings and aggregates
+// (with a '?' length) are not supported.
+func DecodeValue(data []byte) (*models.RedisValue, int, error) {
+	if len(data) == 0 {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	line, n, err := readLine(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	value := &models.RedisValue{Type: data[0]}
+	text := string(line[1:])
+
+	switch value.Type {
+	case respSimpleString, respSimpleError, respBigNumber:
+		value.Value = text
+		return value, n, nil
+
+	case respInteger:
+		i, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid RESP integer %q: %w", text, err)
+		}
+		value.Value = i
+		return value, n, nil
+
+	case respDouble:
+		f, err := parseDouble(text)
+		if err != nil {
+			return nil, 0, err
+		}
+		value.Value = f
+		if formatDouble(f) != text {
+			value.Raw = text
+		}
+		return value, n, nil
+
+	case respBoolean:
+		switch text {
+		case "t":
+			value.Value = true
+		case "f":
+			value.Value = false
+		default:
+			return nil, 0, fmt.Errorf("invalid RESP boolean %q", text)
+		}
+		return value, n, nil
+
+	case respNull:
+		if text != "" {
+			return nil, 0, fmt.Errorf("invalid RESP null %q", text)
+		}
+		return value, n, nil
+
+	case respBulkString, respBulkError, respVerbatimString:
+		length, err := parseLength(text)
+		if err != nil {
+			return nil, 0, err
+		}
+		if length < 0 {
+			if value.Type != respBulkString {
+				return nil, 0, fmt.Errorf("invalid RESP length %d for type %q", length, value.Type)
+			}
+			value.Null = true
+			return value, n, nil
+		}
+		if len(data)-n < length+2 {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		if !bytes.Equal(data[n+length:n+length+2], crlf) {
+			return nil, 0, fmt.Errorf("RESP string of %d bytes is not terminated by CRLF", length)
+		}
+		payload := data[n : n+length]
+		if value.Type == respBulkString && !utf8.Valid(payload) {
+			value.Value = append([]byte{}, payload...)
+		} else {
+			value.Value = string(payload)
+		}
+		return value, n + length + 2, nil
+
+	case respArray, respSet, respPush, respMap, respAttribute:
+		count, err := parseLength(text)
+		if err != nil {
+			return nil, 0, err
+		}
+		if count < 0 {
+			if value.Type != respArray {
+				return nil, 0, fmt.Errorf("invalid RESP length %d for type %q", count, value.Type)
+			}
+			value.Null = true
+			return value, n, nil
+		}
+		if value.Type == respMap || value.Type == respAttribute {
+			count *= 2
+		}
+		// every element takes at least 3 bytes, don't let a bogus count allocate more
+		value.Elements = make([]*models.RedisValue, 0, min(count, (len(data)-n)/3))
+		for i := 0; i < count; i++ {
+			element, m, err := DecodeValue(data[n:])
+			if err != nil {
+				return nil, 0, err
+			}
+			value.Elements = append(value.Elements, element)
+			n += m
+		}
+		return value, n, nil
+
+	default:
+		return nil, 0, fmt.Errorf("unknown RESP type %q", value.Type)
+	}
+}
+
+// EncodeValue encodes the value as RESP, it is the inverse of DecodeValue.
+func EncodeValue(value *models.RedisValue) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := writeValue(buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeValue(buf *bytes.Buffer, value *models.RedisValue) error {
+	if value == nil {
+		return errors.New("nil RESP value")
+	}
+	buf.WriteByte(value.Type)
+
+	switch value.Type {
+	case respSimpleString, respSimpleError, respBigNumber:
+		s, ok := value.Value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for a RESP %q value", value.Value, value.Type)
+		}
+		buf.WriteString(s)
+
+	case respInteger:
+		i, err := integerFromValue(value.Value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strconv.FormatInt(i, 10))
+
+	case respDouble:
+		if value.Raw != "" {
+			buf.WriteString(value.Raw)
+			break
+		}
+		f, err := doubleFromValue(value.Value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(formatDouble(f))
+
+	case respBoolean:
+		b, ok := value.Value.(bool)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for a RESP boolean", value.Value)
+		}
+		if b {
+			buf.WriteByte('t')
+		} else {
+			buf.WriteByte('f')
+		}
+
+	case respNull:
+
+	case respBulkString, respBulkError, respVerbatimString:
+		if value.Null {
+			buf.WriteString("-1")
+			break
+		}
+		var payload []byte
+		switch v := value.Value.(type) {
+		case string:
+			payload = []byte(v)
+		case []byte:
+			payload = v
+		case []interface{}:
+			// the bytes of a binary string read back from a yaml mock
+			payload = make([]byte, len(v))
+			for i, e := range v {
+				n, ok := e.(int)
+				if !ok || n < 0 || n > 0xff {
+					return fmt.Errorf("invalid byte value %v at index %d", e, i)
+				}
+				payload[i] = byte(n)
+			}
+		case nil:
+			// an empty string read back from a mock
+		default:
+			return fmt.Errorf("unexpected type %T for a RESP %q value", value.Value, value.Type)
+		}
+		buf.WriteString(strconv.Itoa(len(payload)))
+		buf.Write(crlf)
+		buf.Write(payload)
+
+	case respArray, respSet, respPush, respMap, respAttribute:
+		if value.Null {
+			buf.WriteString("-1")
+			break
+		}
+		count := len(value.Elements)
+		if value.Type == respMap || value.Type == respAttribute {
+			if count%2 != 0 {
+				return fmt.Errorf("RESP %q value has an odd number of elements %d", value.Type, count)
+			}
+			count /= 2
+		}
+		buf.WriteString(strconv.Itoa(count))
+		buf.Write(crlf)
+		for i, element := range value.Elements {
+			if err := writeValue(buf, element); err != nil {
+				return fmt.Errorf("failed to encode element %d: %w", i, err)
+			}
+		}
+		// the elements are terminated by their own CRLF
+		return nil
+
+	default:
+		return fmt.Errorf("unknown RESP type %q", value.Type)
+	}
+
+	buf.Write(crlf)
+	return nil
+}
+
+// readLine returns the line at the start of data, including the type prefix but not the
+// CRLF, and the number of bytes it takes with the CRLF.
+func readLine(data []byte) ([]byte, int, error) {
+	idx := bytes.Index(data, crlf)
+	if idx == -1 {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	return data[:idx], idx + 2, nil
+}
+
+func parseLength(text string) (int, error) {
+	if text == "?" {
+		return 0, errors.New("streamed RESP values are not supported")
+	}
+	length, err := strconv.Atoi(text)
+	if err != nil || length < -1 {
+		return 0, fmt.Errorf("invalid RESP length %q", text)
+	}
+	return length, nil
+}
+
+func parseDouble(text string) (float64, error) {
+	switch text {
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid RESP double %q: %w", text, err)
+	}
+	return f, nil
+}
+
+// formatDouble formats the double like the server does, the shortest representation that
+// reads back as the same value.
+func formatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// integerFromValue also accepts the int that numbers are read back from a yaml mock as.
+func integerFromValue(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows a RESP integer", v)
+		}
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("unexpected type %T for a RESP integer", value)
+	}
+}
+
+func doubleFromValue(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("unexpected type %T for a RESP double", value)
+	}
+}
@@ -0,0 +1,81 @@
+//go:build linux
+
+package redis
+
+import (
+	"bytes"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// nestedMapReply is a RESP3 map holding nested arrays, maps, doubles, booleans and nulls.
+var nestedMapReply = []byte("%4\r\n" +
+	"+server\r\n$5\r\nredis\r\n" +
+	"+version\r\n:7\r\n" +
+	"$7\r\nmodules\r\n*2\r\n" +
+	"%2\r\n+name\r\n$6\r\nsearch\r\n+score\r\n,1.10\r\n" +
+	"%2\r\n+name\r\n$4\r\njson\r\n+score\r\n,inf\r\n" +
+	"+flags\r\n*6\r\n#t\r\n#f\r\n_\r\n:0\r\n,0\r\n+\r\n")
+
+func TestNestedMapRoundTrip(t *testing.T) {
+	value, n, err := DecodeValue(nestedMapReply)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if n != len(nestedMapReply) {
+		t.Fatalf("decoded %d bytes of %d", n, len(nestedMapReply))
+	}
+	if value.Type != respMap || len(value.Elements) != 8 {
+		t.Fatalf("got type %q with %d elements, want a map of 4 entries", value.Type, len(value.Elements))
+	}
+	modules := value.Elements[5]
+	if modules.Type != respArray || len(modules.Elements) != 2 || modules.Elements[0].Type != respMap {
+		t.Fatalf("modules = %+v, want an array of 2 maps", modules)
+	}
+	if score := modules.Elements[0].Elements[3]; score.Value != 1.1 || score.Raw != "1.10" {
+		t.Errorf("score = %+v, want 1.1 kept as 1.10", score)
+	}
+
+	encoded, err := EncodeValue(value)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !bytes.Equal(encoded, nestedMapReply) {
+		t.Errorf("encoded %q, want %q", encoded, nestedMapReply)
+	}
+}
+
+// TestRoundTripThroughMock checks that values survive being stored in a yaml mock, which
+// reads binary strings back as lists of ints and leaves out zero values.
+func TestRoundTripThroughMock(t *testing.T) {
+	replies := map[string][]byte{
+		"nested map":    nestedMapReply,
+		"binary string": []byte("*2\r\n$3\r\n\xff\x00\xfe\r\n$0\r\n\r\n"),
+		"null bulk":     []byte("$-1\r\n"),
+	}
+	for name, reply := range replies {
+		t.Run(name, func(t *testing.T) {
+			value, _, err := DecodeValue(reply)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			doc, err := yaml.Marshal(value)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var mocked models.RedisValue
+			if err := yaml.Unmarshal(doc, &mocked); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			encoded, err := EncodeValue(&mocked)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !bytes.Equal(encoded, reply) {
+				t.Errorf("encoded %q, want %q", encoded, reply)
+			}
+		})
+	}
+}
@@ -37,6 +37,15 @@ type MongoOpMessage struct {
 	Checksum int      `json:"checksum" yaml:"checksum" bson:"checksum"`
 }
 
+// MongoOpMsgSection is a section of an OP_MSG message, a single body document (kind 0) or
+// a sequence of documents with an identifier (kind 1), e.g. the "documents" of an insert.
+// The documents are in canonical extended JSON, which keeps their field order and types.
+type MongoOpMsgSection struct {
+	Kind       int      `json:"kind" yaml:"kind" bson:"kind"`
+	Identifier string   `json:"identifier,omitempty" yaml:"identifier,omitempty" bson:"identifier,omitempty"`
+	Documents  []string `json:"documents" yaml:"documents" bson:"documents"`
+}
+
 type MongoOpQuery struct {
 	Flags                int32  `json:"flags" yaml:"flags" bson:"flags"`
 	FullCollectionName   string `json:"collection_name" yaml:"collection_name" bson:"collection_name"`
@@ -17,10 +17,20 @@ type QueryPacket struct {
 	Query   string `yaml:"query"`
 }
 
-// LocalInFileRequestPacket is used to send local file request to server, currently not supported
+// LocalInFileRequestPacket is sent by the server in response to a LOAD DATA LOCAL INFILE query to request the file from the client
 type LocalInFileRequestPacket struct {
-	PacketType byte `yaml:"command"`
-	Filename   string
+	PacketType byte   `yaml:"command"`
+	Filename   string `yaml:"filename"`
+}
+
+// LocalInFileResponse is used as a response packet for a LOAD DATA LOCAL INFILE query,
+// it holds the file request, the file contents streamed by the client (terminated by an empty packet)
+// and the final OK/ERR packet sent by the server.
+// refer: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_local_infile_request.html
+type LocalInFileResponse struct {
+	Request       *LocalInFileRequestPacket `yaml:"request"`
+	FileData      [][]byte                  `yaml:"fileData"`
+	FinalResponse *GenericResponse          `yaml:"FinalResponse"`
 }
 
 // TextResultSet is used as a response packet for COM_QUERY
@@ -30,6 +40,7 @@ type TextResultSet struct {
 	EOFAfterColumns []byte                `yaml:"eofAfterColumns"`
 	Rows            []*TextRow            `yaml:"rows"`
 	FinalResponse   *GenericResponse      `yaml:"FinalResponse"`
+	MoreResults     []*MoreResult         `yaml:"moreResults,omitempty"`
 }
 
 // BinaryProtocolResultSet is used as a response packet for COM_STMT_EXECUTE
@@ -39,6 +50,17 @@ type BinaryProtocolResultSet struct {
 	EOFAfterColumns []byte                `yaml:"eofAfterColumns"`
 	Rows            []*BinaryRow          `yaml:"rows"`
 	FinalResponse   *GenericResponse      `yaml:"FinalResponse"`
+	MoreResults     []*MoreResult         `yaml:"moreResults,omitempty"`
+}
+
+// MoreResult is one of the results following a resultset whose final EOF/OK packet has
+// SERVER_MORE_RESULTS_EXISTS set, like the further resultsets of a CALL statement and the
+// OK packet ending it. They are all kept in the MoreResults of the first resultset, in the
+// order they were sent. Exactly one of the fields is set.
+type MoreResult struct {
+	TextResultSet   *TextResultSet           `yaml:"textResultSet,omitempty"`
+	BinaryResultSet *BinaryProtocolResultSet `yaml:"binaryResultSet,omitempty"`
+	Response        *GenericResponse         `yaml:"response,omitempty"` // OK or ERR packet
 }
 
 type GenericResponse struct {
@@ -54,21 +76,22 @@ type ColumnCount struct {
 }
 
 type ColumnDefinition41 struct {
-	Header       Header `yaml:"header"`
-	Catalog      string `yaml:"catalog"`
-	Schema       string `yaml:"schema"`
-	Table        string `yaml:"table"`
-	OrgTable     string `yaml:"org_table"`
-	Name         string `yaml:"name"`
-	OrgName      string `yaml:"org_name"`
-	FixedLength  byte   `yaml:"fixed_length"`
-	CharacterSet uint16 `yaml:"character_set"`
-	ColumnLength uint32 `yaml:"column_length"`
-	Type         byte   `yaml:"type"`
-	Flags        uint16 `yaml:"flags"`
-	Decimals     byte   `yaml:"decimals"`
-	Filler       []byte `yaml:"filler"`
-	DefaultValue string `yaml:"defaultValue"`
+	Header          Header `yaml:"header"`
+	Catalog         string `yaml:"catalog"`
+	Schema          string `yaml:"schema"`
+	Table           string `yaml:"table"`
+	OrgTable        string `yaml:"org_table"`
+	Name            string `yaml:"name"`
+	OrgName         string `yaml:"org_name"`
+	FixedLength     byte   `yaml:"fixed_length"`
+	CharacterSet    uint16 `yaml:"character_set"`
+	ColumnLength    uint32 `yaml:"column_length"`
+	Type            byte   `yaml:"type"`
+	Flags           uint16 `yaml:"flags"`
+	Decimals        byte   `yaml:"decimals"`
+	Filler          []byte `yaml:"filler"`
+	DefaultValue    string `yaml:"defaultValue"`
+	HasDefaultValue bool   `yaml:"hasDefaultValue,omitempty"` // set when the default values trailer (COM_FIELD_LIST) is present
 }
 
 //Rows
@@ -90,6 +113,9 @@ type ColumnEntry struct {
 	Name     string      `yaml:"name"`
 	Value    interface{} `yaml:"value"`
 	Unsigned bool        `yaml:"unsigned"`
+	Opaque   bool        `yaml:"opaque,omitempty"`  // raw bytes of a value whose type isn't decoded
+	Raw      []byte      `yaml:"raw,omitempty"`     // exact wire bytes of a FLOAT/DOUBLE value, written instead of Value
+	Omitted  bool        `yaml:"omitted,omitempty"` // the value was left out when recorded, Value holds a hash of it
 }
 
 // COM_STMT_PREPARE packet
@@ -133,6 +159,7 @@ type Parameter struct {
 	Unsigned bool   `yaml:"unsigned"`
 	Name     string `yaml:"name,omitempty"`
 	Value    []byte `yaml:"value"`
+	LongData bool   `yaml:"longData,omitempty"` // value was sent with COM_STMT_SEND_LONG_DATA
 }
 
 // COM_STMT_FETCH packet is not currently supported because its response involves multi-resultset
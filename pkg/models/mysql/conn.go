@@ -7,15 +7,16 @@ package mysql
 
 // HandshakeV10Packet represents the initial handshake packet sent by the server to the client
 type HandshakeV10Packet struct {
-	ProtocolVersion uint8  `yaml:"protocol_version"`
-	ServerVersion   string `yaml:"server_version"`
-	ConnectionID    uint32 `yaml:"connection_id"`
-	AuthPluginData  []byte `yaml:"auth_plugin_data,omitempty,flow"`
-	Filler          byte   `yaml:"filler"`
-	CapabilityFlags uint32 `yaml:"capability_flags"`
-	CharacterSet    uint8  `yaml:"character_set"`
-	StatusFlags     uint16 `yaml:"status_flags"`
-	AuthPluginName  string `yaml:"auth_plugin_name"`
+	ProtocolVersion        uint8  `yaml:"protocol_version"`
+	ServerVersion          string `yaml:"server_version"`
+	ConnectionID           uint32 `yaml:"connection_id"`
+	AuthPluginData         []byte `yaml:"auth_plugin_data,omitempty,flow"`
+	Filler                 byte   `yaml:"filler"`
+	CapabilityFlags        uint32 `yaml:"capability_flags"`
+	CharacterSet           uint8  `yaml:"character_set"`
+	StatusFlags            uint16 `yaml:"status_flags"`
+	AuthPluginName         string `yaml:"auth_plugin_name"`
+	MariaDBCapabilityFlags uint32 `yaml:"mariadb_capability_flags,omitempty"` // extended capabilities of a MariaDB server
 }
 
 // HandshakeResponse41Packet represents the response packet sent by the client to the server after receiving the HandshakeV10Packet
@@ -36,6 +36,7 @@ const (
 	Native      AuthPluginName = "mysql_native_password"
 	CachingSha2 AuthPluginName = "caching_sha2_password"
 	Sha256      AuthPluginName = "sha256_password"
+	Ed25519     AuthPluginName = "client_ed25519" // MariaDB
 )
 
 // Some constants for MySQL
@@ -89,6 +90,35 @@ const (
 	CLIENT_REMEMBER_OPTIONS
 )
 
+// MariaDB extended capability flags, sent in the last 4 reserved bytes of the handshake packets
+// when the server doesn't set CLIENT_LONG_PASSWORD (CLIENT_MYSQL in MariaDB)
+// refer: https://mariadb.com/kb/en/connection/#capabilities
+const (
+	MARIADB_CLIENT_PROGRESS uint32 = 1 << iota
+	MARIADB_CLIENT_COM_MULTI
+	MARIADB_CLIENT_STMT_BULK_OPERATIONS
+	MARIADB_CLIENT_EXTENDED_METADATA
+	MARIADB_CLIENT_CACHE_METADATA
+	MARIADB_CLIENT_BULK_UNIT_RESULTS
+)
+
+// Server status flag set in the EOF/OK packet ending a result when more results follow
+const SERVER_MORE_RESULTS_EXISTS uint16 = 0x0008
+
+// Server status flag set in OK packets that carry session state changes
+const SERVER_SESSION_STATE_CHANGED uint16 = 0x4000
+
+// Session state change types
+// refer: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_ok_packet.html
+const (
+	SESSION_TRACK_SYSTEM_VARIABLES byte = iota
+	SESSION_TRACK_SCHEMA
+	SESSION_TRACK_STATE_CHANGE
+	SESSION_TRACK_GTIDS
+	SESSION_TRACK_TRANSACTION_CHARACTERISTICS
+	SESSION_TRACK_TRANSACTION_STATE
+)
+
 type FieldType byte
 
 // Field Types
@@ -132,6 +162,7 @@ const (
 	NOT_NULL_FLAG       = 1
 	PRI_KEY_FLAG        = 2
 	UNIQUE_KEY_FLAG     = 4
+	MULTIPLE_KEY_FLAG   = 8
 	BLOB_FLAG           = 16
 	UNSIGNED_FLAG       = 32
 	ZEROFILL_FLAG       = 64
@@ -140,6 +171,7 @@ const (
 	AUTO_INCREMENT_FLAG = 512
 	TIMESTAMP_FLAG      = 1024
 	SET_FLAG            = 2048
+	ON_UPDATE_NOW_FLAG  = 8192
 	NUM_FLAG            = 32768
 	PART_KEY_FLAG       = 16384
 	GROUP_FLAG          = 32768
@@ -11,6 +11,20 @@ type OKPacket struct {
 	StatusFlags  uint16 `json:"status_flags,omitempty" yaml:"status_flags"`
 	Warnings     uint16 `json:"warnings,omitempty" yaml:"warnings"`
 	Info         string `json:"info,omitempty" yaml:"info"`
+	// SessionTrack is set when the packet was sent with CLIENT_SESSION_TRACK, in which case
+	// Info is length-encoded and followed by the session state changes
+	SessionTrack        bool                 `json:"session_track,omitempty" yaml:"session_track,omitempty"`
+	SessionStateChanges []SessionStateChange `json:"session_state_changes,omitempty" yaml:"session_state_changes,omitempty"`
+}
+
+// SessionStateChange is one entry of the session state information of an OK packet.
+// System variable changes carry Name and Value, schema changes the schema in Value and
+// the other trackers their raw data in Data.
+type SessionStateChange struct {
+	Type  byte   `json:"type" yaml:"type"`
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	Data  []byte `json:"data,omitempty" yaml:"data,omitempty"`
 }
 
 // ERRPacket represents the ERR packet sent by the server to the client, it represents an error occurred during the execution of a command
@@ -0,0 +1,420 @@
+package mysql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Equal compares the row against other column by column, taking the column type into
+// account: integers and floats are compared numerically (honouring the unsigned flag),
+// decimals by value regardless of formatting, binary values byte by byte and temporal
+// values after normalising the fractional seconds. When the rows differ, the returned
+// string describes the first mismatch.
+func (r *BinaryRow) Equal(other *BinaryRow, columns []*ColumnDefinition41) (bool, string) {
+	return r.EqualWithConfig(other, columns, RowMatchConfig{})
+}
+
+// RowMatchConfig configures how rows are compared during replay.
+type RowMatchConfig struct {
+	// NoisyColumns are the names of the columns (ColumnDefinition41.Name, compared case
+	// insensitively like MySQL does) whose values change from run to run, e.g. auto-increment
+	// ids or CURRENT_TIMESTAMP defaults. Their values always match.
+	NoisyColumns []string `json:"noisyColumns,omitempty" yaml:"noisyColumns,omitempty"`
+	// ColumnPatterns maps column names to the pattern their live values must match instead
+	// of the recorded value: a template (see valueTemplates), e.g. <<uuid>>, or a regular
+	// expression, which has to match the whole value.
+	ColumnPatterns map[string]string `json:"columnPatterns,omitempty" yaml:"columnPatterns,omitempty"`
+}
+
+// valueTemplates are the patterns that can be used as a recorded value (or in
+// RowMatchConfig.ColumnPatterns) to match any live value of that shape, e.g. a recorded
+// <<uuid>> matches every UUID.
+var valueTemplates = map[string]*regexp.Regexp{
+	"<<uuid>>":     regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+	"<<date>>":     regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`),
+	"<<datetime>>": regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d{1,6})?$`),
+	"<<time>>":     regexp.MustCompile(`^-?(\d+ )?\d{2,3}:\d{2}:\d{2}(\.\d{1,6})?$`),
+	"<<int>>":      regexp.MustCompile(`^-?\d+$`),
+	"<<any>>":      regexp.MustCompile(`(?s)^.*$`),
+}
+
+// compiledPatterns caches the regular expressions of RowMatchConfig.ColumnPatterns, rows
+// are compared with the same config over and over during replay.
+var compiledPatterns sync.Map
+
+// patternFor returns the pattern the live values of the column must match, if any: the one
+// configured for the column or the template recorded as its value.
+func (c RowMatchConfig) patternFor(name string, recorded interface{}) (*regexp.Regexp, error) {
+	for column, pattern := range c.ColumnPatterns {
+		if strings.EqualFold(column, name) {
+			return compilePattern(pattern)
+		}
+	}
+	if s, ok := recorded.(string); ok {
+		if re, ok := valueTemplates[s]; ok {
+			return re, nil
+		}
+	}
+	return nil, nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := valueTemplates[pattern]; ok {
+		return re, nil
+	}
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	// the pattern has to match the whole value
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	compiledPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// matchesPattern reports whether the live value matches re, NULL matches no pattern.
+func matchesPattern(re *regexp.Regexp, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if b, ok := toBytes(value); ok {
+		return re.Match(b)
+	}
+	return re.MatchString(fmt.Sprint(value))
+}
+
+func (c RowMatchConfig) isNoisy(name string) bool {
+	for _, noisy := range c.NoisyColumns {
+		if strings.EqualFold(noisy, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// EqualWithConfig compares the rows like Equal, skipping the columns cfg marks as noisy and
+// matching the live values of the columns with a pattern, configured or recorded as a
+// template, against the pattern instead of the recorded value.
+func (r *BinaryRow) EqualWithConfig(other *BinaryRow, columns []*ColumnDefinition41, cfg RowMatchConfig) (bool, string) {
+	if r == nil || other == nil {
+		if r == other {
+			return true, ""
+		}
+		return false, fmt.Sprintf("expected row %v got %v", r, other)
+	}
+	if len(r.Values) != len(other.Values) {
+		return false, fmt.Sprintf("expected %d values got %d", len(r.Values), len(other.Values))
+	}
+
+	for i := range r.Values {
+		expected, actual := r.Values[i], other.Values[i]
+		name := expected.Name
+		fieldType := expected.Type
+		unsigned := expected.Unsigned || actual.Unsigned
+		if i < len(columns) && columns[i] != nil {
+			name = columns[i].Name
+			fieldType = FieldType(columns[i].Type)
+			unsigned = unsigned || columns[i].Flags&UNSIGNED_FLAG != 0
+		}
+		if cfg.isNoisy(name) {
+			continue
+		}
+		re, err := cfg.patternFor(name, expected.Value)
+		if err != nil {
+			return false, fmt.Sprintf("column `%s`: %v", name, err)
+		}
+		if re != nil {
+			if !matchesPattern(re, actual.Value) {
+				return false, fmt.Sprintf("column `%s`: expected a value matching %s got %v", name, re, actual.Value)
+			}
+			continue
+		}
+		if !valuesEqual(fieldType, unsigned, expected.Value, actual.Value) {
+			return false, fmt.Sprintf("column `%s`: expected %v got %v", name, expected.Value, actual.Value)
+		}
+	}
+	return true, ""
+}
+
+func valuesEqual(fieldType FieldType, unsigned bool, a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	switch fieldType {
+	case FieldTypeTiny, FieldTypeShort, FieldTypeInt24, FieldTypeLong, FieldTypeLongLong, FieldTypeYear:
+		x, okA := toBigInt(a)
+		y, okB := toBigInt(b)
+		if !okA || !okB {
+			break
+		}
+		if !unsigned {
+			return x.Cmp(y) == 0
+		}
+		// the same bits may have been stored as a signed value
+		return toUnsigned(x).Cmp(toUnsigned(y)) == 0
+
+	case FieldTypeFloat, FieldTypeDouble, FieldTypeDecimal, FieldTypeNewDecimal:
+		x, okA := toBigRat(a)
+		y, okB := toBigRat(b)
+		if okA && okB {
+			return x.Cmp(y) == 0
+		}
+
+	case FieldTypeBit, FieldTypeGeometry, FieldTypeBLOB, FieldTypeTinyBLOB, FieldTypeMediumBLOB, FieldTypeLongBLOB,
+		FieldTypeString, FieldTypeVarString, FieldTypeVarChar, FieldTypeEnum, FieldTypeSet, FieldTypeJSON:
+		// strings that aren't valid UTF-8 and binary JSON are kept as raw bytes
+		x, okA := toBytes(a)
+		y, okB := toBytes(b)
+		if okA && okB {
+			return bytes.Equal(x, y)
+		}
+
+	case FieldTypeDate, FieldTypeNewDate, FieldTypeDateTime, FieldTypeTimestamp, FieldTypeTime:
+		x, okA := a.(string)
+		y, okB := b.(string)
+		if okA && okB {
+			return normalizeTemporal(x) == normalizeTemporal(y)
+		}
+	}
+
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func toBigInt(v interface{}) (*big.Int, bool) {
+	switch n := v.(type) {
+	case int:
+		return big.NewInt(int64(n)), true
+	case int8:
+		return big.NewInt(int64(n)), true
+	case int16:
+		return big.NewInt(int64(n)), true
+	case int32:
+		return big.NewInt(int64(n)), true
+	case int64:
+		return big.NewInt(n), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint8:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint16:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case uint64:
+		return new(big.Int).SetUint64(n), true
+	case float64:
+		f := big.NewFloat(n)
+		if !f.IsInt() {
+			return nil, false
+		}
+		i, _ := f.Int(nil)
+		return i, true
+	case string:
+		return new(big.Int).SetString(strings.TrimSpace(n), 10)
+	}
+	return nil, false
+}
+
+// toUnsigned maps a negative value onto its two's complement 64 bit unsigned counterpart.
+func toUnsigned(i *big.Int) *big.Int {
+	if i.Sign() >= 0 {
+		return i
+	}
+	return new(big.Int).Add(i, new(big.Int).Lsh(big.NewInt(1), 64))
+}
+
+func toBigRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case float32:
+		if r := new(big.Rat).SetFloat64(float64(n)); r != nil {
+			return r, true
+		}
+		return nil, false
+	case float64:
+		if r := new(big.Rat).SetFloat64(n); r != nil {
+			return r, true
+		}
+		return nil, false
+	case string:
+		return new(big.Rat).SetString(strings.TrimSpace(n))
+	}
+	if i, ok := toBigInt(v); ok {
+		return new(big.Rat).SetInt(i), true
+	}
+	return nil, false
+}
+
+func toBytes(v interface{}) ([]byte, bool) {
+	switch b := v.(type) {
+	case []byte:
+		return b, true
+	case string:
+		return []byte(b), true
+	case []interface{}:
+		out := make([]byte, len(b))
+		for i, e := range b {
+			n, ok := e.(int)
+			if !ok || n < 0 || n > 0xff {
+				return nil, false
+			}
+			out[i] = byte(n)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// normalizeTemporal drops insignificant trailing zeros of the fractional seconds so
+// that e.g. "03:04:05.100" and "03:04:05.1" compare equal.
+func normalizeTemporal(s string) string {
+	s = strings.Replace(s, "T", " ", 1)
+	whole, frac, ok := strings.Cut(s, ".")
+	if !ok {
+		return s
+	}
+	frac = strings.TrimRight(frac, "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// AllNull reports whether every column of the row is NULL, a row without columns is not.
+func (r *BinaryRow) AllNull() bool {
+	if r == nil || len(r.Values) == 0 {
+		return false
+	}
+	for _, v := range r.Values {
+		if v.Value != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a deep copy of the row: the null bitmap, the raw bytes and the binary values
+// of the copy don't share memory with the row, which may alias the buffer it was decoded
+// from, so either can be changed without affecting the other.
+func (r *BinaryRow) Clone() *BinaryRow {
+	if r == nil {
+		return nil
+	}
+	clone := &BinaryRow{
+		Header:        r.Header,
+		OkAfterRow:    r.OkAfterRow,
+		RowNullBuffer: cloneBytes(r.RowNullBuffer),
+	}
+	if r.Values != nil {
+		clone.Values = make([]ColumnEntry, len(r.Values))
+		for i, v := range r.Values {
+			v.Raw = cloneBytes(v.Raw)
+			v.Value = cloneValue(v.Value)
+			clone.Values[i] = v
+		}
+	}
+	return clone
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(b)), b...)
+}
+
+// cloneValue copies the values that are references: binary values and the lists they are
+// read back from a yaml mock as.
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return cloneBytes(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = cloneValue(e)
+		}
+		return out
+	}
+	return value
+}
+
+// String renders the row as its column names and values, NULL values are shown as NULL,
+// strings quoted and binary values base64 encoded.
+func (r *BinaryRow) String() string {
+	if r == nil {
+		return "<nil>"
+	}
+	var sb strings.Builder
+	sb.WriteString("BinaryRow{")
+	for i, v := range r.Values {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(v.Name)
+		sb.WriteString(": ")
+		switch value := displayValue(v).(type) {
+		case nil:
+			sb.WriteString("NULL")
+		case string:
+			sb.WriteString(strconv.Quote(value))
+		case []byte:
+			sb.WriteString(base64.StdEncoding.EncodeToString(value))
+		default:
+			fmt.Fprint(&sb, value)
+		}
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// MarshalJSON implements json.Marshaler for BinaryRow so that rows are rendered as their
+// columns with typed values: numbers as numbers, binary values base64 encoded and temporal
+// values as strings. A NULL column has a null value and null set, unlike an empty string.
+func (r *BinaryRow) MarshalJSON() ([]byte, error) {
+	type jsonColumn struct {
+		Name  string      `json:"name"`
+		Type  byte        `json:"type"`
+		Null  bool        `json:"null"`
+		Value interface{} `json:"value"`
+	}
+	type jsonRow struct {
+		SequenceID byte         `json:"sequenceId"`
+		Columns    []jsonColumn `json:"columns"`
+	}
+
+	aux := jsonRow{
+		SequenceID: r.Header.SequenceID,
+		Columns:    make([]jsonColumn, len(r.Values)),
+	}
+	for i, v := range r.Values {
+		value := displayValue(v)
+		aux.Columns[i] = jsonColumn{
+			Name:  v.Name,
+			Type:  byte(v.Type),
+			Null:  value == nil,
+			Value: value,
+		}
+	}
+	return json.Marshal(aux)
+}
+
+// displayValue returns the value of the column entry, with binary values that went through
+// a yaml round trip ([]interface{} of ints) turned back into bytes.
+func displayValue(v ColumnEntry) interface{} {
+	if list, ok := v.Value.([]interface{}); ok {
+		if b, ok := toBytes(list); ok {
+			return b
+		}
+	}
+	return v.Value
+}
@@ -0,0 +1,167 @@
+package mysql
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEqualIgnoresFloatFormatting(t *testing.T) {
+	columns := []*ColumnDefinition41{
+		{Name: "price", Type: byte(FieldTypeDouble)},
+		{Name: "total", Type: byte(FieldTypeNewDecimal), Decimals: 2},
+		{Name: "ratio", Type: byte(FieldTypeFloat)},
+	}
+	row := func(price, total, ratio interface{}) *BinaryRow {
+		return &BinaryRow{Values: []ColumnEntry{
+			{Type: FieldTypeDouble, Name: "price", Value: price},
+			{Type: FieldTypeNewDecimal, Name: "total", Value: total},
+			{Type: FieldTypeFloat, Name: "ratio", Value: ratio},
+		}}
+	}
+	recorded := row(1.5, "10.50", float32(0.25))
+
+	// as read back from a yaml mock or formatted by another driver
+	for _, live := range []*BinaryRow{
+		row("1.50", "10.5", 0.25),
+		row(1.5, "010.500", "0.250"),
+		row("1.5e0", 10.5, float32(0.25)),
+	} {
+		if ok, diff := recorded.Equal(live, columns); !ok {
+			t.Errorf("rows %v and %v differ: %s", recorded, live, diff)
+		}
+	}
+
+	if ok, _ := recorded.Equal(row(1.25, "10.50", float32(0.25)), columns); ok {
+		t.Error("rows with different prices are equal")
+	}
+	if ok, _ := recorded.Equal(row(1.5, "10.51", float32(0.25)), columns); ok {
+		t.Error("rows with different totals are equal")
+	}
+}
+
+func TestNullAndEmptyStringRenderDifferently(t *testing.T) {
+	row := &BinaryRow{
+		Header: Header{SequenceID: 3},
+		Values: []ColumnEntry{
+			{Type: FieldTypeLong, Name: "id", Value: int32(7)},
+			{Type: FieldTypeVarString, Name: "empty", Value: ""},
+			{Type: FieldTypeVarString, Name: "missing", Value: nil},
+			{Type: FieldTypeBLOB, Name: "data", Value: []byte{0x00, 0xff}},
+		},
+	}
+
+	if got, want := row.String(), `BinaryRow{id: 7, empty: "", missing: NULL, data: AP8=}`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"sequenceId":3,"columns":[` +
+		`{"name":"id","type":3,"null":false,"value":7},` +
+		`{"name":"empty","type":253,"null":false,"value":""},` +
+		`{"name":"missing","type":253,"null":true,"value":null},` +
+		`{"name":"data","type":252,"null":false,"value":"AP8="}]}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON() = %s\nwant %s", data, want)
+	}
+}
+
+func TestNoisyColumnsAlwaysMatch(t *testing.T) {
+	columns := []*ColumnDefinition41{
+		{Name: "id", Type: byte(FieldTypeLong)},
+		{Name: "email", Type: byte(FieldTypeVarString)},
+	}
+	row := func(id int32, email string) *BinaryRow {
+		return &BinaryRow{Values: []ColumnEntry{
+			{Type: FieldTypeLong, Name: "id", Value: id},
+			{Type: FieldTypeVarString, Name: "email", Value: email},
+		}}
+	}
+	recorded, live := row(1, "ann@example.com"), row(57, "ann@example.com")
+
+	if ok, _ := recorded.Equal(live, columns); ok {
+		t.Fatal("rows with different ids are equal without a config")
+	}
+	cfg := RowMatchConfig{NoisyColumns: []string{"ID"}}
+	if ok, diff := recorded.EqualWithConfig(live, columns, cfg); !ok {
+		t.Errorf("rows differing only in the ignored id don't match: %s", diff)
+	}
+	if ok, _ := recorded.EqualWithConfig(row(57, "bob@example.com"), columns, cfg); ok {
+		t.Error("rows with different emails match")
+	}
+}
+
+func TestColumnPatternsMatchLiveValues(t *testing.T) {
+	columns := []*ColumnDefinition41{
+		{Name: "token", Type: byte(FieldTypeVarString)},
+		{Name: "order_ref", Type: byte(FieldTypeVarString)},
+	}
+	row := func(token, ref interface{}) *BinaryRow {
+		return &BinaryRow{Values: []ColumnEntry{
+			{Type: FieldTypeVarString, Name: "token", Value: token},
+			{Type: FieldTypeVarString, Name: "order_ref", Value: ref},
+		}}
+	}
+	// the token is recorded as a template, the order reference has a configured regex
+	recorded := row("<<uuid>>", "ORD-1")
+	cfg := RowMatchConfig{ColumnPatterns: map[string]string{"order_ref": `ORD-\d+`}}
+
+	for _, live := range []*BinaryRow{
+		row("5f0c6a8e-1b2d-4c3e-9f4a-0123456789ab", "ORD-1"),
+		row("00000000-0000-0000-0000-000000000000", "ORD-20240229"),
+		row("5F0C6A8E-1B2D-4C3E-9F4A-0123456789AB", []byte("ORD-7")),
+	} {
+		if ok, diff := recorded.EqualWithConfig(live, columns, cfg); !ok {
+			t.Errorf("%v doesn't match: %s", live, diff)
+		}
+	}
+
+	for _, live := range []*BinaryRow{
+		row("not-a-uuid", "ORD-1"),
+		row("5f0c6a8e-1b2d-4c3e-9f4a-0123456789ab", "ORD-"),
+		// the regex has to match the whole value
+		row("5f0c6a8e-1b2d-4c3e-9f4a-0123456789ab", "ORD-1 or more"),
+		row(nil, "ORD-1"),
+	} {
+		if ok, _ := recorded.EqualWithConfig(live, columns, cfg); ok {
+			t.Errorf("%v matches", live)
+		}
+	}
+
+	if ok, _ := recorded.EqualWithConfig(row("<<uuid>>", "ORD-1"), columns, RowMatchConfig{ColumnPatterns: map[string]string{"order_ref": "("}}); ok {
+		t.Error("an invalid regex matches")
+	}
+}
+
+func TestCloneIsolatesMutations(t *testing.T) {
+	// the bitmap and the blob alias the same buffer, like a row decoded with ZeroCopy
+	buffer := []byte{0x04, 0xde, 0xad, 0xbe, 0xef}
+	row := &BinaryRow{
+		Header:        Header{PayloadLength: 9, SequenceID: 3},
+		RowNullBuffer: buffer[:1],
+		Values: []ColumnEntry{
+			{Type: FieldTypeBLOB, Name: "data", Value: buffer[1:]},
+			{Type: FieldTypeDouble, Name: "price", Value: "0.1", Raw: []byte{0x9a, 0x99, 0x99, 0x99, 0x99, 0x99, 0xb9, 0x3f}},
+			{Type: FieldTypeVarString, Name: "note", Value: nil},
+		},
+	}
+
+	clone := row.Clone()
+	clone.RowNullBuffer[0] = 0x00
+	clone.Values[0].Value.([]byte)[0] = 0x00
+	clone.Values[1].Raw[0] = 0x00
+	clone.Values[2].Value = "set"
+	clone.Header.SequenceID = 4
+
+	if row.RowNullBuffer[0] != 0x04 || buffer[1] != 0xde || row.Values[1].Raw[0] != 0x9a {
+		t.Errorf("mutating the clone changed the bytes of the original: % x, % x", buffer, row.Values[1].Raw)
+	}
+	if row.Values[2].Value != nil || row.Header.SequenceID != 3 {
+		t.Errorf("mutating the clone changed the original: %+v", row)
+	}
+	if (*BinaryRow)(nil).Clone() != nil {
+		t.Error("the clone of a nil row isn't nil")
+	}
+}
@@ -102,6 +102,23 @@ type Frontend struct {
 	BodyLen int `json:"body_len,omitempty" yaml:"body_len,omitempty"`
 }
 
+// PostgresDataRow is a DataRow message decoded according to the RowDescription of its
+// resultset. It mirrors the MySQL BinaryRow so that rows of both databases can be compared
+// the same way.
+type PostgresDataRow struct {
+	Values []PostgresColumnEntry `json:"values" yaml:"values"`
+}
+
+// PostgresColumnEntry is a single column value of a PostgresDataRow. Type is the OID of
+// the column's data type and Format its format code (0 for text, 1 for binary). Value is
+// nil for NULL.
+type PostgresColumnEntry struct {
+	Type   uint32      `json:"type" yaml:"type"`
+	Name   string      `json:"name" yaml:"name"`
+	Format int16       `json:"format" yaml:"format"`
+	Value  interface{} `json:"value" yaml:"value"`
+}
+
 type StartupPacket struct {
 	Length          uint32
 	ProtocolVersion uint32
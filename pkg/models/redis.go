@@ -11,3 +11,23 @@ type RedisSchema struct {
 	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty"`
 	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty"`
 }
+
+// RedisValue is a RESP2/RESP3 value, the type is its RESP type prefix (e.g. '$' for a bulk
+// string or '%' for a map).
+// refer: https://github.com/redis/redis-specifications/blob/master/protocol/RESP3.md
+type RedisValue struct {
+	Type byte `json:"type" yaml:"type"`
+	// Value holds the value of the simple types: a string for simple strings, errors, big
+	// numbers and verbatim strings, a string or []byte (when not valid UTF-8) for bulk strings
+	// and blob errors, an int64 for integers, a float64 for doubles and a bool for booleans.
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	// Raw is the text of a double as it was received, written back instead of Value so that
+	// its formatting (e.g. "1.10" or "inf") is preserved.
+	Raw string `json:"raw,omitempty" yaml:"raw,omitempty"`
+	// Elements holds the elements of arrays, sets and pushes, and the keys and values of maps
+	// and attributes, alternately.
+	Elements []*RedisValue `json:"elements,omitempty" yaml:"elements,omitempty"`
+	// Null is set for the RESP2 null bulk string ($-1) and null array (*-1), the RESP3 null (_) is
+	// a type of its own.
+	Null bool `json:"null,omitempty" yaml:"null,omitempty"`
+}
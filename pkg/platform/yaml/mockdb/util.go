@@ -603,6 +603,15 @@ func decodeMySQLMessage(_ context.Context, logger *zap.Logger, yamlSpec *mysql.S
 				return nil, err
 			}
 			resp.Message = msg
+
+		case mysql.StatusToString(mysql.LocalInFile):
+			msg := &mysql.LocalInFileResponse{}
+			err := v.Message.Decode(msg)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal yml document into mysql LocalInFileResponse")
+				return nil, err
+			}
+			resp.Message = msg
 		}
 		responses = append(responses, resp)
 	}